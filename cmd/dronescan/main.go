@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
@@ -13,6 +14,7 @@ import (
 	"github.com/dronesec/droneriskscan/internal/auth"
 	"github.com/dronesec/droneriskscan/internal/browser"
 	"github.com/dronesec/droneriskscan/internal/engine"
+	"github.com/dronesec/droneriskscan/internal/oob"
 	"github.com/dronesec/droneriskscan/pkg/models"
 )
 
@@ -51,11 +53,24 @@ type Config struct {
 	Username         string
 	Password         string
 	AuthMethod       string
+	Token            string
+	IdentitiesFile   string
+	JWTWordlist      string
 	
 	// 爬虫配置
 	EnableCrawler    bool
 	MaxCrawlDepth    int
 	MaxCrawlPages    int
+	EnableGraphQL    bool
+	
+	// 带外交互配置
+	EnableOOB        bool
+	OOBListen        string
+	OOBHost          string
+	OOBDNSListen     string
+	OOBSMTPListen    string
+	OOBDomain        string
+	OOBWait          time.Duration
 	
 	// Stagehand配置
 	EnableStagehand  bool
@@ -63,6 +78,7 @@ type Config struct {
 	CrawlStrategy    string  
 	DetectionMode    string
 	AIAnalysis       bool
+	DOMXSS           bool
 	StagehandAPI     string
 }
 
@@ -194,7 +210,7 @@ func runHybridScan(ctx context.Context, hybridScanner *engine.HybridScanner, tar
 
 func runTraditionalScan(ctx context.Context, scanner *engine.Scanner, targets []string, config *Config) {
 	// 执行登录认证
-	if config.Username != "" && config.Password != "" {
+	if (config.Username != "" && config.Password != "") || config.Token != "" {
 		err := scanner.Login(ctx)
 		if err != nil {
 			log.Fatalf("认证失败: %v", err)
@@ -303,11 +319,24 @@ func parseFlags() *Config {
 	flag.StringVar(&config.Username, "username", "", "用户名")
 	flag.StringVar(&config.Password, "password", "", "密码")
 	flag.StringVar(&config.AuthMethod, "auth-method", "form", "认证方式 (form/basic/cookie/bearer)")
+	flag.StringVar(&config.Token, "token", "", "Bearer令牌，未指定用户名密码时使用Bearer认证")
+	flag.StringVar(&config.IdentitiesFile, "identities", "", "越权检测使用的其他身份 (JSON数组文件，字段同登录凭据)")
+	flag.StringVar(&config.JWTWordlist, "jwt-wordlist", "", "JWT弱密钥爆破的额外字典文件 (每行一个)")
 	
 	// 爬虫相关参数
 	flag.BoolVar(&config.EnableCrawler, "crawl", true, "启用爬虫功能")
 	flag.IntVar(&config.MaxCrawlDepth, "crawl-depth", 2, "最大爬取深度")
 	flag.IntVar(&config.MaxCrawlPages, "crawl-pages", 50, "最大爬取页面数")
+	flag.BoolVar(&config.EnableGraphQL, "graphql", true, "探测GraphQL端点并扫描结构中的查询和变更")
+	
+	// 带外交互参数
+	flag.BoolVar(&config.EnableOOB, "oob", false, "启用内置带外交互服务器（SSRF、命令盲注等回连确认）")
+	flag.StringVar(&config.OOBListen, "oob-listen", "127.0.0.1:0", "带外HTTP监听地址")
+	flag.StringVar(&config.OOBHost, "oob-host", "", "目标回连使用的主机地址 (host:port)，默认为监听地址")
+	flag.StringVar(&config.OOBDNSListen, "oob-dns-listen", "", "带外DNS监听地址 (如 0.0.0.0:53)，为空不启用")
+	flag.StringVar(&config.OOBSMTPListen, "oob-smtp-listen", "", "带外SMTP监听地址 (如 0.0.0.0:25)，为空不启用")
+	flag.StringVar(&config.OOBDomain, "oob-domain", "", "带外DNS/SMTP回连根域名")
+	flag.DurationVar(&config.OOBWait, "oob-wait", 10*time.Second, "扫描结束前等待延迟回连的最长时间")
 	
 	// Stagehand浏览器自动化参数
 	flag.BoolVar(&config.EnableStagehand, "enable-stagehand", false, "启用Stagehand浏览器自动化")
@@ -315,6 +344,7 @@ func parseFlags() *Config {
 	flag.StringVar(&config.CrawlStrategy, "crawl-strategy", "hybrid", "爬取策略 (traditional/stagehand/hybrid)")
 	flag.StringVar(&config.DetectionMode, "detection-mode", "hybrid", "检测模式 (passive/active/hybrid)")
 	flag.BoolVar(&config.AIAnalysis, "ai-analysis", false, "启用AI分析功能")
+	flag.BoolVar(&config.DOMXSS, "dom-xss", true, "启用DOM型XSS浏览器插桩检测")
 	flag.StringVar(&config.StagehandAPI, "stagehand-api", "http://localhost:8080/api/v1", "Stagehand API端点")
 
 	flag.Parse()
@@ -346,6 +376,8 @@ func createScannerConfig(config *Config) *engine.ScannerConfig {
 	scannerConfig.UserAgent = config.UserAgent
 	scannerConfig.Verbose = config.Verbose
 	scannerConfig.Debug = config.Debug
+	scannerConfig.OutputDir = config.OutputDir
+	scannerConfig.JWTWordlist = config.JWTWordlist
 
 	// 解析报告格式
 	if config.ReportFormat != "" {
@@ -406,12 +438,39 @@ func createScannerConfig(config *Config) *engine.ScannerConfig {
 			scannerConfig.AuthCredentials.SuccessText = "Choose your bug"
 			scannerConfig.AuthCredentials.FailureText = "Invalid credentials"
 		}
+	} else if config.Token != "" {
+		scannerConfig.AuthCredentials = &auth.Credentials{
+			Method: auth.AuthMethodBearer,
+			Token:  config.Token,
+		}
+	}
+
+	// 加载越权检测使用的其他身份
+	if config.IdentitiesFile != "" {
+		identities, err := loadIdentities(config.IdentitiesFile, scannerConfig.AuthCredentials)
+		if err != nil {
+			log.Fatalf("加载身份配置失败: %v", err)
+		}
+		scannerConfig.Identities = identities
 	}
 	
 	// 配置爬虫
 	scannerConfig.EnableCrawler = config.EnableCrawler
 	scannerConfig.MaxCrawlDepth = config.MaxCrawlDepth
 	scannerConfig.MaxCrawlPages = config.MaxCrawlPages
+	scannerConfig.EnableGraphQL = config.EnableGraphQL
+	
+	// 配置带外交互
+	scannerConfig.EnableOOB = config.EnableOOB
+	if config.EnableOOB {
+		scannerConfig.OOBConfig = oob.DefaultConfig()
+		scannerConfig.OOBConfig.HTTPAddr = config.OOBListen
+		scannerConfig.OOBConfig.PublicHost = config.OOBHost
+		scannerConfig.OOBConfig.DNSAddr = config.OOBDNSListen
+		scannerConfig.OOBConfig.SMTPAddr = config.OOBSMTPListen
+		scannerConfig.OOBConfig.Domain = config.OOBDomain
+		scannerConfig.OOBWaitTime = config.OOBWait
+	}
 
 	return scannerConfig
 }
@@ -435,6 +494,7 @@ func createHybridScannerConfig(config *Config, scannerConfig *engine.ScannerConf
 		AutoFallback:      true,
 		SmartRouting:      true,
 		AIAnalysis:        config.AIAnalysis,
+		DOMXSS:            config.DOMXSS,
 		BrowserPoolSize:   2,
 		MaxBrowserTime:    5 * time.Minute,
 		ConcurrentBrowser: 1,
@@ -468,6 +528,43 @@ func getTargets(config *Config) ([]string, error) {
 	return targets, nil
 }
 
+// loadIdentities 读取身份配置文件，未填写的登录方式、登录地址和成功/失败标志沿用主身份的配置
+func loadIdentities(path string, primary *auth.Credentials) ([]*auth.Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取身份配置文件失败: %w", err)
+	}
+
+	var identities []*auth.Credentials
+	if err := json.Unmarshal(data, &identities); err != nil {
+		return nil, fmt.Errorf("解析身份配置文件失败: %w", err)
+	}
+
+	for i, identity := range identities {
+		if identity.Name == "" && identity.Username == "" {
+			identity.Name = fmt.Sprintf("identity-%d", i+2)
+		}
+		if primary == nil {
+			continue
+		}
+		if identity.Method == "" {
+			identity.Method = primary.Method
+		}
+		if identity.LoginURL == "" {
+			identity.LoginURL = primary.LoginURL
+		}
+		if identity.LoginData == nil {
+			identity.LoginData = primary.LoginData
+		}
+		if identity.SuccessText == "" && identity.FailureText == "" {
+			identity.SuccessText = primary.SuccessText
+			identity.FailureText = primary.FailureText
+		}
+	}
+
+	return identities, nil
+}
+
 func printScanStats(result *models.ScanResult) {
 	stats := result.Statistics
 	
@@ -23,6 +23,7 @@ const (
 
 // Credentials 认证凭据
 type Credentials struct {
+	Name     string     `json:"name,omitempty"` // 身份名称，多身份越权检测时用于区分
 	Method   AuthMethod `json:"method"`
 	Username string     `json:"username"`
 	Password string     `json:"password"`
@@ -329,6 +330,43 @@ func (sm *SessionManager) GetCookies() []*http.Cookie {
 	return sm.cookies
 }
 
+// GetAuthHeaders 获取需要随请求发送的认证头（Basic/Bearer认证）
+func (sm *SessionManager) GetAuthHeaders() map[string]string {
+	headers := make(map[string]string)
+	if !sm.isLoggedIn {
+		return headers
+	}
+
+	switch sm.credentials.Method {
+	case AuthMethodBasic:
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(sm.credentials.Username, sm.credentials.Password)
+		headers["Authorization"] = req.Header.Get("Authorization")
+	case AuthMethodBearer:
+		headers["Authorization"] = "Bearer " + sm.credentials.Token
+	}
+	return headers
+}
+
+// GetIdentityName 获取身份名称，未设置时使用用户名
+func (sm *SessionManager) GetIdentityName() string {
+	if sm.credentials == nil {
+		return ""
+	}
+	if sm.credentials.Name != "" {
+		return sm.credentials.Name
+	}
+	return sm.credentials.Username
+}
+
+// GetUsername 获取用户名
+func (sm *SessionManager) GetUsername() string {
+	if sm.credentials == nil {
+		return ""
+	}
+	return sm.credentials.Username
+}
+
 // GetSessionID 获取会话ID
 func (sm *SessionManager) GetSessionID() string {
 	// 从Cookie中查找常见的会话ID
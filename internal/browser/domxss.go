@@ -0,0 +1,329 @@
+package browser
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// DOM XSS sources used to deliver canaries
+const (
+	DOMSourceFragment    = "fragment"
+	DOMSourceQuery       = "query"
+	DOMSourcePostMessage = "postMessage"
+)
+
+// domCanaryPrefix marks every canary so the init script only reports our own values
+const domCanaryPrefix = "drsdom"
+
+// domSinkBinding is the exposed binding the init script reports sink hits to
+const domSinkBinding = "__drsSinkReport"
+
+// domXSSInitScript hooks dangerous DOM sinks and reports any value carrying a canary
+// together with the JavaScript stack at the time of the call.
+const domXSSInitScript = `(() => {
+  if (window.__drsHooked) return;
+  window.__drsHooked = true;
+  const canaryRe = /drsdom[0-9a-f]{10}/;
+  const report = (sink, value) => {
+    try {
+      const str = String(value);
+      if (!canaryRe.test(str)) return;
+      const stack = (new Error()).stack || '';
+      window.__drsSinkReport(JSON.stringify({
+        sink: sink, value: str.slice(0, 2048), stack: stack, url: location.href
+      }));
+    } catch (e) {}
+  };
+  const hookSetter = (proto, prop, sink) => {
+    const desc = Object.getOwnPropertyDescriptor(proto, prop);
+    if (!desc || !desc.set) return;
+    Object.defineProperty(proto, prop, {
+      configurable: true,
+      enumerable: desc.enumerable,
+      get: desc.get,
+      set: function (v) { report(sink, v); return desc.set.call(this, v); }
+    });
+  };
+  hookSetter(Element.prototype, 'innerHTML', 'innerHTML');
+  hookSetter(Element.prototype, 'outerHTML', 'outerHTML');
+  const origInsert = Element.prototype.insertAdjacentHTML;
+  Element.prototype.insertAdjacentHTML = function (pos, html) {
+    report('insertAdjacentHTML', html);
+    return origInsert.call(this, pos, html);
+  };
+  const origWrite = document.write;
+  document.write = function (...args) { report('document.write', args.join('')); return origWrite.apply(this, args); };
+  const origWriteln = document.writeln;
+  document.writeln = function (...args) { report('document.writeln', args.join('')); return origWriteln.apply(this, args); };
+  const origEval = window.eval;
+  window.eval = function (code) { report('eval', code); return origEval(code); };
+  const origFunction = window.Function;
+  window.Function = function (...args) { report('Function', args.join(',')); return origFunction.apply(this, args); };
+  window.Function.prototype = origFunction.prototype;
+  const origSetTimeout = window.setTimeout;
+  window.setTimeout = function (handler, ...rest) {
+    if (typeof handler === 'string') report('setTimeout', handler);
+    return origSetTimeout.call(this, handler, ...rest);
+  };
+  const origSetInterval = window.setInterval;
+  window.setInterval = function (handler, ...rest) {
+    if (typeof handler === 'string') report('setInterval', handler);
+    return origSetInterval.call(this, handler, ...rest);
+  };
+  // Navigations are only a sink when they run script or leave the origin;
+  // same-document pushState/replaceState URLs carrying the canary are benign.
+  const reportNavigation = (sink, u) => {
+    try {
+      const dest = new URL(String(u), location.href);
+      if (dest.protocol !== 'javascript:' && dest.origin === location.origin) return;
+    } catch (e) { return; }
+    report(sink, u);
+  };
+  // Location properties are unforgeable, so assignments are observed through the
+  // Navigation API, which dispatches synchronously inside the assigning frame.
+  if (window.navigation && window.navigation.addEventListener) {
+    window.navigation.addEventListener('navigate', (e) => {
+      if (e.destination && e.destination.url) reportNavigation('location', e.destination.url);
+    });
+  }
+  const origAssign = Location.prototype.assign;
+  try { Location.prototype.assign = function (u) { reportNavigation('location.assign', u); return origAssign.call(this, u); }; } catch (e) {}
+  const origReplace = Location.prototype.replace;
+  try { Location.prototype.replace = function (u) { reportNavigation('location.replace', u); return origReplace.call(this, u); }; } catch (e) {}
+})();`
+
+// SinkReport is a raw sink hit reported by the init script
+type SinkReport struct {
+	Sink  string `json:"sink"`
+	Value string `json:"value"`
+	Stack string `json:"stack"`
+	URL   string `json:"url"`
+}
+
+// DOMXSSFinding is a confirmed flow from a controllable source into a DOM sink
+type DOMXSSFinding struct {
+	URL        string `json:"url"`         // page that was loaded
+	Source     string `json:"source"`      // fragment, query or postMessage
+	Parameter  string `json:"parameter"`   // query parameter name, if any
+	Canary     string `json:"canary"`      // unique marker inside the payload
+	Payload    string `json:"payload"`     // value delivered through the source
+	Sink       string `json:"sink"`        // sink that received the canary
+	SinkValue  string `json:"sink_value"`  // value passed to the sink
+	StackTrace string `json:"stack_trace"` // JavaScript stack at the sink call
+	Unencoded  bool   `json:"unencoded"`   // markup characters reached the sink verbatim
+}
+
+// EnableDOMXSSMode installs the sink instrumentation on the browser context
+func (pm *PlaywrightManager) EnableDOMXSSMode(ctx context.Context) error {
+	if pm.page == nil {
+		if err := pm.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start Playwright: %w", err)
+		}
+	}
+	if pm.domXSSEnabled {
+		return nil
+	}
+
+	err := pm.context.ExposeBinding(domSinkBinding, func(source *playwright.BindingSource, args ...interface{}) interface{} {
+		if len(args) == 0 {
+			return nil
+		}
+		raw, ok := args[0].(string)
+		if !ok {
+			return nil
+		}
+		report := &SinkReport{}
+		if err := json.Unmarshal([]byte(raw), report); err != nil {
+			return nil
+		}
+		pm.sinkMutex.Lock()
+		pm.sinkReports = append(pm.sinkReports, report)
+		pm.sinkMutex.Unlock()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to expose sink binding: %w", err)
+	}
+
+	script := domXSSInitScript
+	if err := pm.context.AddInitScript(playwright.Script{Content: &script}); err != nil {
+		return fmt.Errorf("failed to add init script: %w", err)
+	}
+
+	pm.domXSSEnabled = true
+	fmt.Println("[INFO] DOM XSS sink instrumentation enabled")
+	return nil
+}
+
+// ScanDOMXSS loads every URL with canaries in the fragment, the query string and
+// postMessage, and returns the sink hits observed by the instrumentation
+func (pm *PlaywrightManager) ScanDOMXSS(ctx context.Context, targetURLs []string) ([]*DOMXSSFinding, error) {
+	if err := pm.EnableDOMXSSMode(ctx); err != nil {
+		return nil, err
+	}
+
+	var findings []*DOMXSSFinding
+	seen := make(map[string]bool)
+
+	for _, targetURL := range targetURLs {
+		select {
+		case <-ctx.Done():
+			return findings, ctx.Err()
+		default:
+		}
+
+		parsedURL, err := url.Parse(targetURL)
+		if err != nil {
+			continue
+		}
+
+		fmt.Printf("[INFO] DOM XSS scanning: %s\n", targetURL)
+
+		// Keep the session for authenticated pages
+		for _, cookie := range pm.cookies {
+			pm.context.AddCookies([]playwright.OptionalCookie{{
+				Name:  cookie.Name,
+				Value: cookie.Value,
+				URL:   &targetURL,
+			}})
+		}
+
+		for _, probe := range buildDOMProbes(parsedURL) {
+			pm.resetSinkReports()
+
+			if _, err := pm.page.Goto(probe.url, playwright.PageGotoOptions{
+				WaitUntil: playwright.WaitUntilStateLoad,
+			}); err != nil {
+				fmt.Printf("[DEBUG] DOM XSS navigation failed: %v\n", err)
+				continue
+			}
+
+			if probe.source == DOMSourcePostMessage {
+				pm.page.Evaluate(`c => {
+  window.postMessage(c, '*');
+  window.postMessage({data: c, html: c, url: c, message: c}, '*');
+  window.postMessage(JSON.stringify({data: c, html: c, url: c}), '*');
+}`, probe.payload)
+			}
+
+			// Give timers and message handlers a chance to run
+			time.Sleep(1 * time.Second)
+
+			for _, report := range pm.takeSinkReports(probe.canary) {
+				key := probe.source + "|" + probe.parameter + "|" + report.Sink + "|" + parsedURL.Path
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				finding := &DOMXSSFinding{
+					URL:        targetURL,
+					Source:     probe.source,
+					Parameter:  probe.parameter,
+					Canary:     probe.canary,
+					Payload:    probe.payload,
+					Sink:       report.Sink,
+					SinkValue:  report.Value,
+					StackTrace: report.Stack,
+					Unencoded:  strings.Contains(report.Value, probe.canary+"'\"<x>"),
+				}
+				findings = append(findings, finding)
+				fmt.Printf("[FOUND] DOM XSS flow: %s -> %s (%s)\n", finding.Source, finding.Sink, targetURL)
+			}
+		}
+	}
+
+	return findings, nil
+}
+
+// domProbe is a single page load delivering a canary through one source
+type domProbe struct {
+	url       string
+	source    string
+	parameter string
+	canary    string
+	payload   string
+}
+
+// buildDOMProbes creates one probe per source for the given URL
+func buildDOMProbes(target *url.URL) []domProbe {
+	var probes []domProbe
+
+	newProbe := func(source, parameter string) domProbe {
+		canary := newDOMCanary()
+		return domProbe{source: source, parameter: parameter, canary: canary, payload: canary + "'\"<x>"}
+	}
+
+	// Fragment
+	probe := newProbe(DOMSourceFragment, "")
+	u := *target
+	u.Fragment = probe.payload
+	probe.url = u.String()
+	probes = append(probes, probe)
+
+	// Query string: every existing parameter plus an extra one
+	params := target.Query()
+	names := make([]string, 0, len(params)+1)
+	for name := range params {
+		names = append(names, name)
+	}
+	names = append(names, "drs_dom")
+	for _, name := range names {
+		probe := newProbe(DOMSourceQuery, name)
+		u := *target
+		query := target.Query()
+		query.Set(name, probe.payload)
+		u.RawQuery = query.Encode()
+		u.Fragment = ""
+		probe.url = u.String()
+		probes = append(probes, probe)
+	}
+
+	// postMessage is delivered after load on the clean URL
+	probe = newProbe(DOMSourcePostMessage, "")
+	u = *target
+	u.Fragment = ""
+	probe.url = u.String()
+	probes = append(probes, probe)
+
+	return probes
+}
+
+// resetSinkReports drops reports from previous page loads
+func (pm *PlaywrightManager) resetSinkReports() {
+	pm.sinkMutex.Lock()
+	pm.sinkReports = nil
+	pm.sinkMutex.Unlock()
+}
+
+// takeSinkReports returns and clears the reports carrying the given canary
+func (pm *PlaywrightManager) takeSinkReports(canary string) []*SinkReport {
+	pm.sinkMutex.Lock()
+	defer pm.sinkMutex.Unlock()
+
+	var matched []*SinkReport
+	for _, report := range pm.sinkReports {
+		if strings.Contains(report.Value, canary) {
+			matched = append(matched, report)
+		}
+	}
+	pm.sinkReports = nil
+	return matched
+}
+
+// newDOMCanary returns a unique canary matching the init script pattern
+func newDOMCanary() string {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return domCanaryPrefix + "0000000000"
+	}
+	return domCanaryPrefix + hex.EncodeToString(buf)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/playwright-community/playwright-go"
@@ -19,6 +20,11 @@ type PlaywrightManager struct {
 	config     *StagehandConfig
 	cookies    []*http.Cookie
 	sessionID  string
+	
+	// DOM XSS instrumentation state
+	domXSSEnabled bool
+	sinkReports   []*SinkReport
+	sinkMutex     sync.Mutex
 }
 
 // NewPlaywrightManager creates a new Playwright manager
@@ -380,6 +386,11 @@ func (pm *PlaywrightManager) GetCookies() []*http.Cookie {
 	return pm.cookies
 }
 
+// SetCookies replaces the session cookies applied before navigation
+func (pm *PlaywrightManager) SetCookies(cookies []*http.Cookie) {
+	pm.cookies = cookies
+}
+
 // Close closes the browser and cleans up resources
 func (pm *PlaywrightManager) Close() error {
 	if pm.page != nil {
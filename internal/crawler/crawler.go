@@ -302,7 +302,7 @@ func (c *Crawler) extractLinks(htmlContent string) []string {
 // extractForms 提取表单
 func (c *Crawler) extractForms(htmlContent string) []*FormInfo {
 	var forms []*FormInfo
-	formRe := regexp.MustCompile(`<form[^>]*>(.*?)</form>`)
+	formRe := regexp.MustCompile(`(?is)<form[^>]*>(.*?)</form>`)
 	matches := formRe.FindAllStringSubmatch(htmlContent, -1)
 	
 	for _, match := range matches {
@@ -383,7 +383,7 @@ func (c *Crawler) extractInputsFromHTML(htmlContent string) []*InputInfo {
 	}
 	
 	// 提取textarea标签
-	textareaRe := regexp.MustCompile(`<textarea[^>]*name=["']([^"']+)["'][^>]*>(.*?)</textarea>`)
+	textareaRe := regexp.MustCompile(`(?is)<textarea[^>]*name=["']([^"']+)["'][^>]*>(.*?)</textarea>`)
 	textareaMatches := textareaRe.FindAllStringSubmatch(htmlContent, -1)
 	
 	for _, match := range textareaMatches {
@@ -398,7 +398,7 @@ func (c *Crawler) extractInputsFromHTML(htmlContent string) []*InputInfo {
 	}
 	
 	// 提取select标签
-	selectRe := regexp.MustCompile(`<select[^>]*name=["']([^"']+)["'][^>]*>(.*?)</select>`)
+	selectRe := regexp.MustCompile(`(?is)<select[^>]*name=["']([^"']+)["'][^>]*>(.*?)</select>`)
 	selectMatches := selectRe.FindAllStringSubmatch(htmlContent, -1)
 	
 	for _, match := range selectMatches {
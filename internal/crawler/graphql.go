@@ -0,0 +1,610 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dronesec/droneriskscan/internal/auth"
+	"github.com/dronesec/droneriskscan/internal/transport"
+)
+
+// graphQLPaths 常见的GraphQL端点路径
+var graphQLPaths = []string{
+	"/graphql",
+	"/api/graphql",
+	"/graphql/v1",
+	"/v1/graphql",
+	"/api/v1/graphql",
+	"/graphql/api",
+	"/gql",
+	"/query",
+	"/graphiql",
+	"/playground",
+	"/api",
+}
+
+// graphQLErrorPattern GraphQL服务的典型错误信息
+var graphQLErrorPattern = regexp.MustCompile(`(?i)(graphql|syntax error|cannot query field|__typename|must provide (a )?query|query (is|not) |operation)`)
+
+// introspectionQuery 内省查询，类型引用与graphql-js一致展开8层（[[T!]!]! 需要6层），
+// 更深的包装在解析后OfType为nil，按String处理
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    types {
+      kind name
+      fields(includeDeprecated: true) { name args { name type { ...TypeRef } } type { ...TypeRef } }
+      inputFields { name type { ...TypeRef } }
+      enumValues(includeDeprecated: true) { name }
+    }
+  }
+}
+fragment TypeRef on __Type {
+  kind name ofType { kind name ofType { kind name ofType { kind name ofType {
+    kind name ofType { kind name ofType { kind name ofType { kind name } } }
+  } } } }
+}`
+
+const (
+	maxGraphQLOperations = 50 // 每个根类型最多生成的操作数
+	maxSelectionFields   = 12 // 返回对象最多选择的标量字段数
+	maxInputDepth        = 3  // 输入对象默认值的最大嵌套深度
+)
+
+// GraphQLEndpoint 发现的GraphQL端点
+type GraphQLEndpoint struct {
+	URL           string
+	Schema        *GraphQLSchema
+	Introspection bool   // 内省查询可用
+	Suggestions   bool   // 错误信息中包含字段建议（Did you mean）
+	SchemaSource  string // introspection / suggestions，未能恢复结构时为空
+}
+
+// GraphQLSchema GraphQL结构
+type GraphQLSchema struct {
+	QueryType    string
+	MutationType string
+	Types        map[string]*GraphQLType
+}
+
+// GraphQLType 命名类型
+type GraphQLType struct {
+	Kind        string
+	Name        string
+	Fields      []*GraphQLField
+	InputFields []*GraphQLInputValue
+	EnumValues  []string
+}
+
+// GraphQLField 对象类型的字段
+type GraphQLField struct {
+	Name string
+	Args []*GraphQLInputValue
+	Type *GraphQLTypeRef
+}
+
+// GraphQLInputValue 参数或输入对象字段
+type GraphQLInputValue struct {
+	Name string
+	Type *GraphQLTypeRef
+}
+
+// GraphQLTypeRef 类型引用，NON_NULL/LIST通过OfType包装
+type GraphQLTypeRef struct {
+	Kind   string
+	Name   string
+	OfType *GraphQLTypeRef
+}
+
+// GraphQLOperation 根据结构生成的查询或变更，参数全部通过variables传递
+type GraphQLOperation struct {
+	Endpoint  *GraphQLEndpoint
+	Kind      string // query / mutation
+	Field     string // 根字段名
+	Name      string // operationName
+	Selection string // 根字段的选择集，标量字段为空
+	Query     string
+	Variables map[string]interface{}
+}
+
+// graphQLResponse GraphQL响应
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// messages 全部错误信息
+func (r *graphQLResponse) messages() []string {
+	var messages []string
+	for _, e := range r.Errors {
+		messages = append(messages, e.Message)
+	}
+	return messages
+}
+
+// isGraphQL 响应是否来自GraphQL服务：返回了 __typename，或错误信息是GraphQL的解析/校验错误
+// （未认证等情况下服务端可能只返回错误）
+func (r *graphQLResponse) isGraphQL() bool {
+	if bytes.Contains(r.Data, []byte("__typename")) {
+		return true
+	}
+	for _, message := range r.messages() {
+		if graphQLErrorPattern.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
+
+// GraphQLDiscoverer GraphQL端点发现器：探测常见路径，通过内省或字段建议恢复结构
+type GraphQLDiscoverer struct {
+	httpClient     transport.HTTPClient
+	sessionManager *auth.SessionManager
+}
+
+// NewGraphQLDiscoverer 创建GraphQL端点发现器
+func NewGraphQLDiscoverer(httpClient transport.HTTPClient, sessionManager *auth.SessionManager) *GraphQLDiscoverer {
+	return &GraphQLDiscoverer{
+		httpClient:     httpClient,
+		sessionManager: sessionManager,
+	}
+}
+
+// Discover 在目标站点上发现GraphQL端点，candidates为额外的候选URL（如爬虫发现的链接），
+// 每个源只返回第一个响应GraphQL请求的端点
+func (g *GraphQLDiscoverer) Discover(ctx context.Context, targetURL string, candidates []string) (*GraphQLEndpoint, error) {
+	base, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析目标URL失败: %w", err)
+	}
+	origin := base.Scheme + "://" + base.Host
+
+	var urls []string
+	for _, candidate := range append([]string{targetURL}, candidates...) {
+		lower := strings.ToLower(candidate)
+		if strings.Contains(lower, "graphql") || strings.Contains(lower, "gql") {
+			urls = append(urls, candidate)
+		}
+	}
+	for _, path := range graphQLPaths {
+		urls = append(urls, origin+path)
+	}
+
+	seen := make(map[string]bool)
+	for _, endpointURL := range urls {
+		if seen[endpointURL] {
+			continue
+		}
+		seen[endpointURL] = true
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		resp, err := g.post(ctx, endpointURL, map[string]interface{}{"query": "query{__typename}"})
+		if err != nil || !resp.isGraphQL() {
+			continue
+		}
+
+		fmt.Printf("[INFO] 发现GraphQL端点: %s\n", endpointURL)
+		endpoint := &GraphQLEndpoint{URL: endpointURL}
+
+		if schema, err := g.introspect(ctx, endpointURL); err == nil {
+			endpoint.Schema = schema
+			endpoint.Introspection = true
+			endpoint.SchemaSource = "introspection"
+		} else if schema, suggestions := g.recoverSchema(ctx, endpointURL); schema != nil {
+			endpoint.Schema = schema
+			endpoint.Suggestions = suggestions
+			endpoint.SchemaSource = "suggestions"
+		}
+		return endpoint, nil
+	}
+
+	return nil, fmt.Errorf("未发现GraphQL端点")
+}
+
+// introspect 执行内省查询并解析结构
+func (g *GraphQLDiscoverer) introspect(ctx context.Context, endpointURL string) (*GraphQLSchema, error) {
+	resp, err := g.post(ctx, endpointURL, map[string]interface{}{"query": introspectionQuery})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 || string(resp.Data) == "null" {
+		return nil, fmt.Errorf("内省查询被拒绝: %s", strings.Join(resp.messages(), "; "))
+	}
+
+	var data struct {
+		Schema struct {
+			QueryType    *struct{ Name string } `json:"queryType"`
+			MutationType *struct{ Name string } `json:"mutationType"`
+			Types        []struct {
+				Kind   string `json:"kind"`
+				Name   string `json:"name"`
+				Fields []struct {
+					Name string `json:"name"`
+					Args []struct {
+						Name string          `json:"name"`
+						Type *GraphQLTypeRef `json:"type"`
+					} `json:"args"`
+					Type *GraphQLTypeRef `json:"type"`
+				} `json:"fields"`
+				InputFields []struct {
+					Name string          `json:"name"`
+					Type *GraphQLTypeRef `json:"type"`
+				} `json:"inputFields"`
+				EnumValues []struct {
+					Name string `json:"name"`
+				} `json:"enumValues"`
+			} `json:"types"`
+		} `json:"__schema"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return nil, fmt.Errorf("解析内省结果失败: %w", err)
+	}
+	if data.Schema.QueryType == nil {
+		return nil, fmt.Errorf("内省结果缺少查询根类型")
+	}
+
+	schema := &GraphQLSchema{
+		QueryType: data.Schema.QueryType.Name,
+		Types:     make(map[string]*GraphQLType),
+	}
+	if data.Schema.MutationType != nil {
+		schema.MutationType = data.Schema.MutationType.Name
+	}
+	for _, t := range data.Schema.Types {
+		gt := &GraphQLType{Kind: t.Kind, Name: t.Name}
+		for _, f := range t.Fields {
+			field := &GraphQLField{Name: f.Name, Type: f.Type}
+			for _, a := range f.Args {
+				field.Args = append(field.Args, &GraphQLInputValue{Name: a.Name, Type: a.Type})
+			}
+			gt.Fields = append(gt.Fields, field)
+		}
+		for _, f := range t.InputFields {
+			gt.InputFields = append(gt.InputFields, &GraphQLInputValue{Name: f.Name, Type: f.Type})
+		}
+		for _, e := range t.EnumValues {
+			gt.EnumValues = append(gt.EnumValues, e.Name)
+		}
+		schema.Types[t.Name] = gt
+	}
+	return schema, nil
+}
+
+// post 以JSON发送GraphQL请求，携带当前会话
+func (g *GraphQLDiscoverer) post(ctx context.Context, endpointURL string, payload interface{}) (*graphQLResponse, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpointURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	// 应用会话认证
+	if g.sessionManager != nil && g.sessionManager.IsLoggedIn() {
+		g.sessionManager.ApplyAuth(req)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var result graphQLResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("响应不是GraphQL JSON: %w", err)
+	}
+	if result.Data == nil && result.Errors == nil {
+		return nil, fmt.Errorf("响应缺少data和errors")
+	}
+	return &result, nil
+}
+
+// Operations 为查询和变更根类型的每个字段生成操作，每个参数声明为同名变量并填入类型对应的默认值
+func (e *GraphQLEndpoint) Operations() []*GraphQLOperation {
+	if e.Schema == nil {
+		return nil
+	}
+
+	var operations []*GraphQLOperation
+	for _, root := range []struct{ kind, typeName string }{
+		{"query", e.Schema.QueryType},
+		{"mutation", e.Schema.MutationType},
+	} {
+		rootType := e.Schema.Types[root.typeName]
+		if rootType == nil {
+			continue
+		}
+
+		count := 0
+		for _, field := range rootType.Fields {
+			if strings.HasPrefix(field.Name, "__") || count >= maxGraphQLOperations {
+				continue
+			}
+			count++
+			operations = append(operations, e.buildOperation(root.kind, field))
+		}
+	}
+	return operations
+}
+
+// buildOperation 生成单个根字段的操作
+func (e *GraphQLEndpoint) buildOperation(kind string, field *GraphQLField) *GraphQLOperation {
+	name := "Drs" + strings.ToUpper(kind[:1]) + kind[1:] + "_" + field.Name
+	variables := make(map[string]interface{})
+
+	var declarations, arguments []string
+	for _, arg := range field.Args {
+		declarations = append(declarations, fmt.Sprintf("$%s: %s", arg.Name, arg.Type.String()))
+		arguments = append(arguments, fmt.Sprintf("%s: $%s", arg.Name, arg.Name))
+		variables[arg.Name] = e.Schema.defaultValue(arg.Name, arg.Type, 0)
+	}
+
+	var query strings.Builder
+	query.WriteString(kind + " " + name)
+	if len(declarations) > 0 {
+		query.WriteString("(" + strings.Join(declarations, ", ") + ")")
+	}
+	query.WriteString(" { " + field.Name)
+	if len(arguments) > 0 {
+		query.WriteString("(" + strings.Join(arguments, ", ") + ")")
+	}
+	selection := e.Schema.selection(field.Type)
+	query.WriteString(selection + " }")
+
+	return &GraphQLOperation{
+		Endpoint:  e,
+		Kind:      kind,
+		Field:     field.Name,
+		Name:      name,
+		Selection: selection,
+		Query:     query.String(),
+		Variables: variables,
+	}
+}
+
+// RequestBody 操作的JSON请求体
+func (op *GraphQLOperation) RequestBody() string {
+	body, _ := json.Marshal(map[string]interface{}{
+		"query":         op.Query,
+		"variables":     op.Variables,
+		"operationName": op.Name,
+	})
+	return string(body)
+}
+
+// selection 返回对象的选择集：不需要必填参数的标量字段，没有时选择 __typename
+func (s *GraphQLSchema) selection(ref *GraphQLTypeRef) string {
+	named := s.Types[ref.NamedType()]
+	if named == nil {
+		if ref.Unwrap().Kind == "OBJECT" || ref.Unwrap().Kind == "INTERFACE" || ref.Unwrap().Kind == "UNION" {
+			return " { __typename }"
+		}
+		return ""
+	}
+
+	switch named.Kind {
+	case "OBJECT", "INTERFACE":
+		var fields []string
+		for _, f := range named.Fields {
+			if len(fields) >= maxSelectionFields {
+				break
+			}
+			if s.isLeaf(f.Type) && !hasRequiredArgs(f) {
+				fields = append(fields, f.Name)
+			}
+		}
+		if len(fields) == 0 {
+			return " { __typename }"
+		}
+		return " { " + strings.Join(fields, " ") + " }"
+	case "UNION":
+		return " { __typename }"
+	default:
+		return ""
+	}
+}
+
+// isLeaf 类型是否为标量或枚举
+func (s *GraphQLSchema) isLeaf(ref *GraphQLTypeRef) bool {
+	if named := s.Types[ref.NamedType()]; named != nil {
+		return named.Kind == "SCALAR" || named.Kind == "ENUM"
+	}
+	kind := ref.Unwrap().Kind
+	return kind == "SCALAR" || kind == "ENUM"
+}
+
+// hasRequiredArgs 字段是否有必填参数
+func hasRequiredArgs(field *GraphQLField) bool {
+	for _, arg := range field.Args {
+		if arg.Type != nil && arg.Type.Kind == "NON_NULL" {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultValue 参数类型对应的默认值，字符串类按参数名给出合适的测试值
+func (s *GraphQLSchema) defaultValue(name string, ref *GraphQLTypeRef, depth int) interface{} {
+	if ref == nil {
+		ref = &GraphQLTypeRef{Kind: "SCALAR", Name: "String"}
+	}
+	switch ref.Kind {
+	case "NON_NULL":
+		return s.defaultValue(name, ref.OfType, depth)
+	case "LIST":
+		return []interface{}{s.defaultValue(name, ref.OfType, depth)}
+	}
+
+	named := s.Types[ref.Name]
+	kind := ref.Kind
+	if named != nil {
+		kind = named.Kind
+	}
+
+	switch kind {
+	case "ENUM":
+		if named != nil && len(named.EnumValues) > 0 {
+			return named.EnumValues[0]
+		}
+		return nil
+	case "INPUT_OBJECT":
+		object := make(map[string]interface{})
+		if named == nil || depth >= maxInputDepth {
+			return object
+		}
+		for _, field := range named.InputFields {
+			object[field.Name] = s.defaultValue(field.Name, field.Type, depth+1)
+		}
+		return object
+	}
+
+	switch ref.Name {
+	case "Int":
+		return 1
+	case "Float":
+		return 1.5
+	case "Boolean":
+		return true
+	case "ID":
+		return "1"
+	}
+
+	lowerName := strings.ToLower(name)
+	switch {
+	case strings.Contains(lowerName, "email"):
+		return "test@example.com"
+	case strings.Contains(lowerName, "url") || strings.Contains(lowerName, "link"):
+		return "http://example.com"
+	case lowerName == "id" || strings.HasSuffix(lowerName, "id"):
+		return "1"
+	default:
+		return "test"
+	}
+}
+
+// String GraphQL类型表示，如 [ID!]!
+func (r *GraphQLTypeRef) String() string {
+	if r == nil {
+		return "String"
+	}
+	switch r.Kind {
+	case "NON_NULL":
+		return r.OfType.String() + "!"
+	case "LIST":
+		return "[" + r.OfType.String() + "]"
+	default:
+		return r.Name
+	}
+}
+
+// Unwrap 去掉NON_NULL/LIST包装后的命名类型引用
+func (r *GraphQLTypeRef) Unwrap() *GraphQLTypeRef {
+	for r != nil && (r.Kind == "NON_NULL" || r.Kind == "LIST") {
+		r = r.OfType
+	}
+	if r == nil {
+		return &GraphQLTypeRef{Kind: "SCALAR", Name: "String"}
+	}
+	return r
+}
+
+// NamedType 命名类型名
+func (r *GraphQLTypeRef) NamedType() string {
+	return r.Unwrap().Name
+}
+
+// parseTypeRef 解析错误信息中的类型表示（如 [ID!]!），未知命名类型按kind参数处理
+func parseTypeRef(s, kind string) *GraphQLTypeRef {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasSuffix(s, "!"):
+		return &GraphQLTypeRef{Kind: "NON_NULL", OfType: parseTypeRef(strings.TrimSuffix(s, "!"), kind)}
+	case strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]"):
+		return &GraphQLTypeRef{Kind: "LIST", OfType: parseTypeRef(s[1:len(s)-1], kind)}
+	}
+	switch s {
+	case "Int", "Float", "String", "Boolean", "ID":
+		return &GraphQLTypeRef{Kind: "SCALAR", Name: s}
+	}
+	return &GraphQLTypeRef{Kind: kind, Name: s}
+}
+
+// CyclicPath 在结构中寻找可以无限嵌套的字段路径（如 User.posts -> Post.author -> User），
+// 返回从查询根类型出发、不需要必填参数的字段名序列，第一个字段之后的部分可重复
+func (s *GraphQLSchema) CyclicPath() (string, []string) {
+	root := s.Types[s.QueryType]
+	if root == nil {
+		return "", nil
+	}
+
+	for _, entry := range root.Fields {
+		if hasRequiredArgs(entry) || s.isLeaf(entry.Type) {
+			continue
+		}
+		start := entry.Type.NamedType()
+
+		// 广度优先搜索回到start的最短路径
+		type node struct {
+			typeName string
+			path     []string
+		}
+		queue := []node{{typeName: start}}
+		visited := map[string]bool{start: true}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			t := s.Types[current.typeName]
+			if t == nil || len(current.path) >= 4 {
+				continue
+			}
+			names := make([]string, 0, len(t.Fields))
+			fields := make(map[string]*GraphQLField)
+			for _, f := range t.Fields {
+				names = append(names, f.Name)
+				fields[f.Name] = f
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				f := fields[name]
+				if hasRequiredArgs(f) || s.isLeaf(f.Type) {
+					continue
+				}
+				next := f.Type.NamedType()
+				path := append(append([]string{}, current.path...), f.Name)
+				if next == start {
+					return entry.Name, path
+				}
+				if !visited[next] {
+					visited[next] = true
+					queue = append(queue, node{typeName: next, path: path})
+				}
+			}
+		}
+	}
+	return "", nil
+}
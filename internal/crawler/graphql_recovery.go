@@ -0,0 +1,358 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 内省被禁用时，通过GraphQL校验错误中的字段建议（Did you mean）与类型提示逐步恢复结构。
+// 校验阶段会报告查询中的全部错误，因此一次请求可以同时判断一批候选名是否存在。
+
+const (
+	maxRecoveredFields = 30 // 每个根类型最多深入探测的字段数
+	namePattern        = `[_A-Za-z][_0-9A-Za-z]*`
+)
+
+var (
+	// Cannot query field "usr" on type "Query". Did you mean "user"?
+	cannotQueryPattern = regexp.MustCompile(`Cannot query field "(` + namePattern + `)" on type "(` + namePattern + `)"`)
+	// Field "user" of type "User" must have a selection of subfields.
+	subfieldsPattern = regexp.MustCompile(`Field "(` + namePattern + `)" of type "([^"]+)" must have a selection of subfields`)
+	// Field "user" argument "id" of type "ID!" is required, but it was not provided.
+	requiredArgPattern = regexp.MustCompile(`Field "(?:` + namePattern + `\.)?(` + namePattern + `)" argument "(` + namePattern + `)" of type "([^"]+)" is required`)
+	// Unknown argument "idd" on field "Query.user". Did you mean "id"?
+	unknownArgPattern = regexp.MustCompile(`Unknown argument "(` + namePattern + `)" on field "(?:` + namePattern + `\.)?(` + namePattern + `)"`)
+	// Variable "$id" of type "String" used in position expecting type "Int!".
+	variableTypePattern = regexp.MustCompile(`Variable "\$(` + namePattern + `)" of type "[^"]+" used in position expecting type "([^"]+)"`)
+
+	didYouMeanPattern = regexp.MustCompile(`Did you mean (.+?)\?`)
+	quotedNamePattern = regexp.MustCompile(`"(` + namePattern + `)"`)
+)
+
+// recoveryFieldWords 根字段候选名
+var recoveryFieldWords = []string{
+	"user", "users", "me", "viewer", "account", "accounts", "profile", "admin", "node", "nodes",
+	"search", "token", "session", "post", "posts", "comment", "comments", "product", "products",
+	"order", "orders", "item", "items", "file", "files", "message", "messages", "setting", "settings",
+	"config", "system", "debug", "health", "version", "flag", "customer", "customers", "payment",
+	"payments", "report", "reports", "role", "roles", "group", "groups", "project", "projects",
+	"task", "tasks", "event", "events", "device", "devices", "drone", "drones", "mission", "missions",
+	"login", "logout", "register", "signup", "createUser", "updateUser", "deleteUser", "createPost",
+	"updatePost", "deletePost", "changePassword", "resetPassword", "uploadFile", "addComment",
+}
+
+// recoveryArgWords 参数候选名
+var recoveryArgWords = []string{
+	"id", "ids", "name", "username", "email", "password", "token", "query", "q", "search", "filter",
+	"input", "data", "first", "last", "limit", "offset", "skip", "after", "before", "page", "size",
+	"orderBy", "sort", "where", "type", "status", "url", "path", "file", "code", "key", "value",
+	"text", "title", "content", "userId", "postId",
+}
+
+// recoverySubfieldWords 对象字段候选名
+var recoverySubfieldWords = []string{
+	"id", "name", "username", "email", "password", "role", "roles", "token", "title", "content",
+	"body", "text", "status", "type", "createdAt", "updatedAt", "description", "url", "path",
+	"value", "key", "isAdmin", "admin", "firstName", "lastName", "phone", "address", "price",
+	"total", "count", "message", "secret", "apiKey", "hash",
+}
+
+// recoveryResult 一次探测请求中提取到的信息
+type recoveryResult struct {
+	missing     map[string]bool              // 不存在的字段
+	suggested   map[string][]string          // 类型 -> 建议的字段名
+	objects     map[string]string            // 字段 -> 对象类型
+	required    map[string]map[string]string // 字段 -> 必填参数 -> 类型
+	unknownArgs map[string]bool              // 不存在的参数
+	argSuggests []string                     // 建议的参数名
+	hasHints    bool                         // 错误信息中出现过 Did you mean
+}
+
+// recoverSchema 通过字段建议恢复查询与变更根类型，返回nil表示端点没有泄露可用信息
+func (g *GraphQLDiscoverer) recoverSchema(ctx context.Context, endpointURL string) (*GraphQLSchema, bool) {
+	schema := &GraphQLSchema{Types: make(map[string]*GraphQLType)}
+	suggestions := false
+
+	for _, kind := range []string{"query", "mutation"} {
+		rootName := g.rootTypeName(ctx, endpointURL, kind)
+		if rootName == "" {
+			continue
+		}
+		root, hinted := g.recoverRoot(ctx, endpointURL, kind, rootName, schema)
+		suggestions = suggestions || hinted
+		if len(root.Fields) == 0 {
+			continue
+		}
+		schema.Types[rootName] = root
+		if kind == "query" {
+			schema.QueryType = rootName
+		} else {
+			schema.MutationType = rootName
+		}
+	}
+
+	if schema.QueryType == "" && schema.MutationType == "" {
+		return nil, suggestions
+	}
+	if schema.QueryType == "" {
+		// 生成操作时以查询根为入口，只恢复出变更时补一个空的查询根
+		schema.QueryType = "Query"
+		schema.Types["Query"] = &GraphQLType{Kind: "OBJECT", Name: "Query"}
+	}
+	return schema, suggestions
+}
+
+// rootTypeName 通过 __typename 获取根类型名，不支持该操作类型时返回空
+func (g *GraphQLDiscoverer) rootTypeName(ctx context.Context, endpointURL, kind string) string {
+	resp, err := g.post(ctx, endpointURL, map[string]interface{}{"query": kind + "{__typename}"})
+	if err != nil || len(resp.Data) == 0 {
+		return ""
+	}
+	var data struct {
+		Typename string `json:"__typename"`
+	}
+	if json.Unmarshal(resp.Data, &data) != nil {
+		return ""
+	}
+	return data.Typename
+}
+
+// recoverRoot 恢复根类型的字段、参数与返回类型
+func (g *GraphQLDiscoverer) recoverRoot(ctx context.Context, endpointURL, kind, rootName string, schema *GraphQLSchema) (*GraphQLType, bool) {
+	root := &GraphQLType{Kind: "OBJECT", Name: rootName}
+
+	result := g.probe(ctx, endpointURL, kind+" { "+strings.Join(recoveryFieldWords, " ")+" }")
+	if result == nil {
+		return root, false
+	}
+	hinted := result.hasHints
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range append(result.confirmed(recoveryFieldWords), result.suggested[rootName]...) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	for i, name := range names {
+		if i >= maxRecoveredFields {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return root, hinted
+		default:
+		}
+
+		field := &GraphQLField{Name: name, Type: &GraphQLTypeRef{Kind: "SCALAR", Name: "String"}}
+		fieldHinted := g.recoverField(ctx, endpointURL, kind, field, schema)
+		hinted = hinted || fieldHinted
+		root.Fields = append(root.Fields, field)
+	}
+	return root, hinted
+}
+
+// recoverField 恢复单个根字段：参数（名称与类型）和返回对象的标量字段
+func (g *GraphQLDiscoverer) recoverField(ctx context.Context, endpointURL, kind string, field *GraphQLField, schema *GraphQLSchema) bool {
+	args := make(map[string]string)
+
+	// 参数候选名，值统一用1，只关心参数是否存在
+	var probes []string
+	for _, arg := range recoveryArgWords {
+		probes = append(probes, arg+": 1")
+	}
+	result := g.probe(ctx, endpointURL, fmt.Sprintf("%s { %s(%s) }", kind, field.Name, strings.Join(probes, ", ")))
+	if result == nil {
+		return false
+	}
+	hinted := result.hasHints
+
+	// 服务端没有逐个报告未知参数时无法判断候选名，只采用建议名
+	var candidates []string
+	if len(result.unknownArgs) > 0 {
+		for _, arg := range recoveryArgWords {
+			if !result.unknownArgs[arg] {
+				candidates = append(candidates, arg)
+			}
+		}
+	}
+	for _, arg := range append(candidates, result.argSuggests...) {
+		if _, ok := args[arg]; !ok {
+			args[arg] = "String"
+		}
+	}
+	for arg, typ := range result.required[field.Name] {
+		args[arg] = typ
+	}
+
+	// 返回类型与对象字段
+	objectType := result.objects[field.Name]
+	if objectType == "" {
+		// 带参数的探测可能在参数错误处就没有报告选择集错误，单独确认
+		if single := g.probe(ctx, endpointURL, fmt.Sprintf("%s { %s }", kind, field.Name)); single != nil {
+			objectType = single.objects[field.Name]
+			for arg, typ := range single.required[field.Name] {
+				args[arg] = typ
+			}
+		}
+	}
+	if objectType != "" {
+		field.Type = parseTypeRef(objectType, "OBJECT")
+		named := field.Type.NamedType()
+		if schema.Types[named] == nil {
+			object, objectHinted := g.recoverObject(ctx, endpointURL, kind, field.Name, named)
+			hinted = hinted || objectHinted
+			schema.Types[named] = object
+		}
+	}
+
+	for name, typ := range args {
+		field.Args = append(field.Args, &GraphQLInputValue{Name: name, Type: parseTypeRef(typ, "INPUT_OBJECT")})
+	}
+	sort.Slice(field.Args, func(i, j int) bool { return field.Args[i].Name < field.Args[j].Name })
+	g.refineArgTypes(ctx, endpointURL, kind, field, schema)
+	return hinted
+}
+
+// recoverObject 恢复对象类型的标量字段
+func (g *GraphQLDiscoverer) recoverObject(ctx context.Context, endpointURL, kind, fieldName, typeName string) (*GraphQLType, bool) {
+	object := &GraphQLType{Kind: "OBJECT", Name: typeName}
+	result := g.probe(ctx, endpointURL, fmt.Sprintf("%s { %s { %s } }", kind, fieldName, strings.Join(recoverySubfieldWords, " ")))
+	if result == nil {
+		return object, false
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range append(result.confirmed(recoverySubfieldWords), result.suggested[typeName]...) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		ref := &GraphQLTypeRef{Kind: "SCALAR", Name: "String"}
+		if nested, ok := result.objects[name]; ok {
+			ref = parseTypeRef(nested, "OBJECT")
+		}
+		object.Fields = append(object.Fields, &GraphQLField{Name: name, Type: ref})
+	}
+	return object, result.hasHints
+}
+
+// refineArgTypes 以当前推测的类型发送一次操作，根据变量类型不匹配的错误修正参数类型
+func (g *GraphQLDiscoverer) refineArgTypes(ctx context.Context, endpointURL, kind string, field *GraphQLField, schema *GraphQLSchema) {
+	if len(field.Args) == 0 {
+		return
+	}
+
+	endpoint := &GraphQLEndpoint{URL: endpointURL, Schema: schema}
+	operation := endpoint.buildOperation(kind, field)
+	resp, err := g.post(ctx, endpointURL, map[string]interface{}{
+		"query":         operation.Query,
+		"variables":     operation.Variables,
+		"operationName": operation.Name,
+	})
+	if err != nil {
+		return
+	}
+
+	unknown := make(map[string]bool)
+	for _, message := range resp.messages() {
+		if m := variableTypePattern.FindStringSubmatch(message); m != nil {
+			for _, arg := range field.Args {
+				if arg.Name == m[1] {
+					arg.Type = parseTypeRef(m[2], "INPUT_OBJECT")
+				}
+			}
+		}
+		if m := unknownArgPattern.FindStringSubmatch(message); m != nil {
+			unknown[m[1]] = true
+		}
+	}
+
+	if len(unknown) > 0 {
+		var args []*GraphQLInputValue
+		for _, arg := range field.Args {
+			if !unknown[arg.Name] {
+				args = append(args, arg)
+			}
+		}
+		field.Args = args
+	}
+}
+
+// probe 发送探测查询并解析校验错误，请求失败时返回nil
+func (g *GraphQLDiscoverer) probe(ctx context.Context, endpointURL, query string) *recoveryResult {
+	resp, err := g.post(ctx, endpointURL, map[string]interface{}{"query": query})
+	if err != nil {
+		return nil
+	}
+
+	result := &recoveryResult{
+		missing:     make(map[string]bool),
+		suggested:   make(map[string][]string),
+		objects:     make(map[string]string),
+		required:    make(map[string]map[string]string),
+		unknownArgs: make(map[string]bool),
+	}
+
+	for _, message := range resp.messages() {
+		suggestions := suggestedNames(message)
+		if len(suggestions) > 0 {
+			result.hasHints = true
+		}
+
+		switch {
+		case cannotQueryPattern.MatchString(message):
+			m := cannotQueryPattern.FindStringSubmatch(message)
+			result.missing[m[1]] = true
+			result.suggested[m[2]] = append(result.suggested[m[2]], suggestions...)
+		case subfieldsPattern.MatchString(message):
+			m := subfieldsPattern.FindStringSubmatch(message)
+			result.objects[m[1]] = m[2]
+		case requiredArgPattern.MatchString(message):
+			m := requiredArgPattern.FindStringSubmatch(message)
+			if result.required[m[1]] == nil {
+				result.required[m[1]] = make(map[string]string)
+			}
+			result.required[m[1]][m[2]] = m[3]
+		case unknownArgPattern.MatchString(message):
+			m := unknownArgPattern.FindStringSubmatch(message)
+			result.unknownArgs[m[1]] = true
+			result.argSuggests = append(result.argSuggests, suggestions...)
+		}
+	}
+	return result
+}
+
+// confirmed 候选名中未被报告不存在的字段。服务端没有逐个报告不存在的字段时无法判断，返回空
+func (r *recoveryResult) confirmed(words []string) []string {
+	if len(r.missing) == 0 {
+		return nil
+	}
+	var names []string
+	for _, word := range words {
+		if !r.missing[word] {
+			names = append(names, word)
+		}
+	}
+	return names
+}
+
+// suggestedNames 提取 Did you mean 后的候选名
+func suggestedNames(message string) []string {
+	m := didYouMeanPattern.FindStringSubmatch(message)
+	if m == nil {
+		return nil
+	}
+	var names []string
+	for _, quoted := range quotedNamePattern.FindAllStringSubmatch(m[1], -1) {
+		names = append(names, quoted[1])
+	}
+	return names
+}
@@ -0,0 +1,501 @@
+package authz
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// sensitivePathPattern 通常需要登录才能访问的页面，匿名访问得到与登录后相同的内容时提示人工确认
+var sensitivePathPattern = regexp.MustCompile(`(?i)/(admin|administrator|manage|manager|management|dashboard|console|panel|backend|internal|settings|account|profile)(/|\.|$)`)
+
+// publicAuthPathPattern 按设计无需登录的登录、注册、找回密码页面
+var publicAuthPathPattern = regexp.MustCompile(`(?i)/(login|logon|signin|sign-in|sign_in|register|registration|signup|sign-up|sign_up|forgot|forgot-password|forgot_password|reset|reset-password|reset_password|password-reset|recover|recovery|logout)(/|\.|$)`)
+
+// passwordInputPattern 页面包含密码输入框，通常是登录或注册表单
+var passwordInputPattern = regexp.MustCompile(`(?i)<input[^>]+type\s*=\s*["']?password`)
+
+// AuthBypassDetector 认证绕过与强制浏览检测器 - 对登录后可访问的页面，不带会话、带失效会话、篡改请求方法
+// 和变形路径重新请求，响应仍包含只有登录后才能看到的内容时判定认证可被绕过
+type AuthBypassDetector struct {
+	*detector.BasePlugin
+
+	mutex      sync.Mutex
+	identities []detector.Identity
+	tested     map[string]bool
+}
+
+// bypassAttempt 一次绕过尝试
+type bypassAttempt struct {
+	Name    string            // 手法描述
+	Method  string            // 请求方法
+	URL     string            // 请求URL（可包含未规范化的路径）
+	Headers map[string]string // 额外请求头
+	Body    string
+}
+
+// bypassHit 成功的绕过尝试
+type bypassHit struct {
+	attempt  bypassAttempt
+	status   int
+	coverage float64
+}
+
+// NewAuthBypassDetector 创建认证绕过检测器
+func NewAuthBypassDetector(httpClient transport.HTTPClient) *AuthBypassDetector {
+	base := detector.NewBasePlugin(
+		"auth-bypass",
+		detector.PluginTypeActive,
+		models.CategoryAuth,
+		models.SeverityHigh,
+	)
+
+	base.SetDescription("检测登录后页面的认证绕过：匿名访问、失效会话、请求方法篡改（HEAD/OPTIONS/覆盖头）和路径变形（大小写、;/、%2e）")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &AuthBypassDetector{
+		BasePlugin: base,
+		tested:     make(map[string]bool),
+	}
+}
+
+// SetIdentities 设置登录身份，只使用主身份作为登录后的基准
+func (d *AuthBypassDetector) SetIdentities(identities []detector.Identity) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.identities = identities
+}
+
+// Execute 执行认证绕过检测
+func (d *AuthBypassDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	d.mutex.Lock()
+	var identity *detector.Identity
+	if len(d.identities) > 0 {
+		identity = &d.identities[0]
+	}
+	key := target.URL.Scheme + "://" + target.URL.Host + target.URL.Path
+	alreadyTested := d.tested[key]
+	d.tested[key] = true
+	d.mutex.Unlock()
+
+	if identity == nil {
+		result.Metadata["message"] = "认证绕过检测需要已登录会话"
+		return result, nil
+	}
+	// 只检测页面请求，避免以篡改后的方法重放表单提交
+	if target.Method != "GET" {
+		result.Metadata["message"] = "仅检测GET页面"
+		return result, nil
+	}
+	if alreadyTested {
+		result.Metadata["message"] = "页面已检测"
+		return result, nil
+	}
+
+	// 重定向通常是跳转登录页，按拒绝访问处理
+	ctx = transport.WithoutRedirects(ctx)
+	pageURL := target.URL.String()
+
+	authResp, err := d.send(ctx, target, bypassAttempt{Method: "GET", URL: pageURL}, identity.Cookies, identity.Headers)
+	if err != nil || !authResp.ok() {
+		result.Metadata["message"] = "登录后无法访问该页面"
+		return result, nil
+	}
+
+	anonResp, err := d.send(ctx, target, bypassAttempt{Method: "GET", URL: pageURL}, nil, nil)
+	if err != nil {
+		result.Metadata["message"] = "匿名请求失败"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] 认证绕过检测器正在测试: %s\n", pageURL)
+
+	// 匿名访问得到与登录后相同的内容：公开页面，或本应受保护的页面缺失认证
+	if anonResp.ok() && anonResp.similarity(authResp) >= publicThreshold {
+		// 内容相同首先说明页面是公开的，只有路径看起来需要登录时才提示人工确认；
+		// 登录、注册、找回密码页面按设计公开
+		if sensitivePathPattern.MatchString(target.URL.Path) &&
+			!publicAuthPathPattern.MatchString(target.URL.Path) &&
+			!passwordInputPattern.Match(anonResp.body) {
+			vuln := d.buildVulnerability(target, models.SeverityLow, "CWE-306",
+				"Possible Missing Authentication for Protected Page",
+				"管理/账户类路径的页面不带任何会话也能得到与登录后相同的内容，可能是公开页面，也可能是服务端未对该页面做认证，需人工确认",
+				"不携带会话Cookie和认证头",
+				fmt.Sprintf("匿名请求返回 HTTP %d，与登录后的响应相似度 %.2f", anonResp.status, anonResp.similarity(authResp)),
+				0.3, models.PositionCOOKIE, "")
+			vuln.Metadata["bypass_technique"] = "no-session"
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现可能缺失认证的页面: %s\n", pageURL)
+		} else {
+			result.Metadata["message"] = "无需认证即可访问，跳过"
+		}
+		d.finish(result, 1)
+		return result, nil
+	}
+
+	// 受保护内容 = 登录后的响应 - 匿名响应，绕过请求需要覆盖其中大部分才算读到了受保护页面
+	protected := authResp.difference(anonResp)
+	if len(protected) < minObjectShingles {
+		result.Metadata["message"] = "登录前后页面内容无明显差异"
+		d.finish(result, 1)
+		return result, nil
+	}
+
+	tested := 1
+	groups := []struct {
+		technique string
+		cwe       string
+		title     string
+		position  models.Position
+		attempts  []bypassAttempt
+		creds     bool // 是否携带失效会话
+	}{
+		{"expired-session", "CWE-287", "Authentication Bypass with Invalid Session", models.PositionCOOKIE, []bypassAttempt{{Name: "失效会话", Method: "GET", URL: pageURL}}, true},
+		{"verb-tampering", "CWE-650", "Authentication Bypass via HTTP Verb Tampering", models.PositionHEADER, d.verbAttempts(pageURL), false},
+		{"path-normalization", "CWE-288", "Authentication Bypass via Path Normalization", models.PositionPATH, d.pathAttempts(target.URL), false},
+	}
+
+	for _, group := range groups {
+		var cookies []*http.Cookie
+		var headers map[string]string
+		if group.creds {
+			cookies, headers = invalidCredentials(identity)
+			if len(cookies) == 0 && len(headers) == 0 {
+				continue
+			}
+		}
+
+		var hits []bypassHit
+		for _, attempt := range group.attempts {
+			tested++
+			fmt.Printf("[DEBUG] 测试认证绕过: %s %s %s\n", attempt.Name, attempt.Method, attempt.URL)
+
+			resp, err := d.send(ctx, target, attempt, cookies, headers)
+			if err != nil || !resp.ok() {
+				continue
+			}
+			if coverage := resp.coverage(protected); coverage >= coverageThreshold {
+				hits = append(hits, bypassHit{attempt: attempt, status: resp.status, coverage: coverage})
+			}
+		}
+		if len(hits) == 0 {
+			continue
+		}
+
+		var evidence []string
+		for _, hit := range hits {
+			evidence = append(evidence, fmt.Sprintf("%s: %s %s -> HTTP %d，覆盖受保护内容 %.0f%%",
+				hit.attempt.Name, hit.attempt.Method, hit.attempt.URL, hit.status, hit.coverage*100))
+		}
+		first := hits[0].attempt
+		vuln := d.buildVulnerability(target, models.SeverityHigh, group.cwe, group.title,
+			fmt.Sprintf("匿名请求被拒绝（HTTP %d），但以%s方式请求可以得到登录后才能看到的页面内容", anonResp.status, first.Name),
+			describeAttempt(first),
+			strings.Join(evidence, "\n"),
+			0.9, group.position, first.URL)
+		vuln.Metadata["bypass_technique"] = group.technique
+		vuln.Metadata["anonymous_status"] = fmt.Sprintf("%d", anonResp.status)
+		result.IsVulnerable = true
+		result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+		fmt.Printf("[SUCCESS] 发现认证绕过(%s): %s\n", first.Name, pageURL)
+	}
+
+	// HEAD响应没有正文，只能根据状态码判断：匿名GET被拒绝而匿名HEAD成功，且不存在的路径不会对HEAD返回成功
+	if !hasTechnique(result, "verb-tampering") && (anonResp.status == http.StatusUnauthorized || anonResp.status == http.StatusForbidden || anonResp.status/100 == 3) {
+		tested++
+		head, err := d.send(ctx, target, bypassAttempt{Method: "HEAD", URL: pageURL}, nil, nil)
+		if err == nil && head.ok() {
+			control, err := d.send(ctx, target, bypassAttempt{Method: "HEAD", URL: randomSibling(target.URL)}, nil, nil)
+			if err == nil && !control.ok() {
+				attempt := bypassAttempt{Name: "HEAD方法", Method: "HEAD", URL: pageURL}
+				vuln := d.buildVulnerability(target, models.SeverityMedium, "CWE-650",
+					"Authentication Bypass via HTTP Verb Tampering (HEAD)",
+					fmt.Sprintf("匿名GET请求被拒绝（HTTP %d），匿名HEAD请求却返回成功，访问控制可能只限制了部分请求方法，需确认HEAD请求是否会执行页面逻辑", anonResp.status),
+					describeAttempt(attempt),
+					fmt.Sprintf("HEAD %s -> HTTP %d；不存在路径的HEAD请求 -> HTTP %d", pageURL, head.status, control.status),
+					0.6, models.PositionHEADER, pageURL)
+				vuln.Metadata["bypass_technique"] = "verb-tampering"
+				vuln.Metadata["anonymous_status"] = fmt.Sprintf("%d", anonResp.status)
+				result.IsVulnerable = true
+				result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+				fmt.Printf("[SUCCESS] 发现认证绕过(HEAD方法): %s\n", pageURL)
+			}
+		}
+	}
+
+	d.finish(result, tested)
+	return result, nil
+}
+
+// verbAttempts 请求方法篡改：OPTIONS、未知方法，以及POST携带方法覆盖头/参数
+func (d *AuthBypassDetector) verbAttempts(pageURL string) []bypassAttempt {
+	attempts := []bypassAttempt{
+		{Name: "OPTIONS方法", Method: "OPTIONS", URL: pageURL},
+		{Name: "未知方法", Method: "DRSCAN", URL: pageURL},
+	}
+	for _, header := range []string{"X-HTTP-Method-Override", "X-Method-Override", "X-HTTP-Method"} {
+		attempts = append(attempts, bypassAttempt{Name: header + "覆盖头", Method: "POST", URL: pageURL, Headers: map[string]string{header: "GET"}})
+	}
+	attempts = append(attempts, bypassAttempt{
+		Name:    "_method参数",
+		Method:  "POST",
+		URL:     pageURL,
+		Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		Body:    "_method=GET",
+	})
+	return attempts
+}
+
+// pathAttempts 路径变形：服务端路由与访问控制规则对路径的规范化方式不一致时可绕过后者
+func (d *AuthBypassDetector) pathAttempts(u *url.URL) []bypassAttempt {
+	origin := u.Scheme + "://" + u.Host
+	rawPath := u.EscapedPath()
+	if rawPath == "" {
+		rawPath = "/"
+	}
+	query := ""
+	if u.RawQuery != "" {
+		query = "?" + u.RawQuery
+	}
+
+	trimmed := strings.TrimSuffix(rawPath, "/")
+	if trimmed == "" {
+		return nil
+	}
+	first := trimmed
+	rest := ""
+	if i := strings.Index(trimmed[1:], "/"); i >= 0 {
+		first, rest = trimmed[:i+1], trimmed[i+1:]
+	}
+	lastSlash := strings.LastIndex(trimmed, "/")
+
+	variants := []struct{ name, path string }{
+		{"末尾斜杠", trimmed + "/"},
+		{"去掉末尾斜杠", trimmed},
+		{"路径大写", strings.ToUpper(rawPath)},
+		{"首段大写", strings.ToUpper(first) + rest},
+		{"分号路径参数", trimmed + ";/"},
+		{"分号路径参数", first + ";" + rest},
+		{"分号路径参数", "/;" + rawPath},
+		{"%2e路径段", "/%2e" + rawPath},
+		{"%2e路径段", trimmed[:lastSlash+1] + "%2e/" + trimmed[lastSlash+1:]},
+		{"点路径段", "/." + rawPath},
+		{"双斜杠", "/" + rawPath},
+		{"扩展名", trimmed + ".json"},
+	}
+	// 路径以 // 开头时首段只有 "/"，没有可编码的字符
+	if len(first) > 1 && first[1] != '%' {
+		variants = append(variants, struct{ name, path string }{"URL编码", fmt.Sprintf("/%%%02x", first[1]) + first[2:] + rest})
+	}
+
+	seen := map[string]bool{rawPath: true}
+	var attempts []bypassAttempt
+	for _, v := range variants {
+		if len(v.path) < 2 || seen[v.path] {
+			continue
+		}
+		seen[v.path] = true
+		attempts = append(attempts, bypassAttempt{Name: v.name, Method: "GET", URL: origin + v.path + query})
+	}
+
+	// 反向代理按根路径做访问控制、后端按重写头路由
+	for _, header := range []string{"X-Original-URL", "X-Rewrite-URL"} {
+		attempts = append(attempts, bypassAttempt{
+			Name:    header + "请求头",
+			Method:  "GET",
+			URL:     origin + "/" + query,
+			Headers: map[string]string{header: rawPath},
+		})
+	}
+	return attempts
+}
+
+// invalidCredentials 与主身份同名、值随机的会话Cookie和认证头，模拟过期或伪造的会话
+func invalidCredentials(identity *detector.Identity) ([]*http.Cookie, map[string]string) {
+	var cookies []*http.Cookie
+	for _, cookie := range identity.Cookies {
+		cookies = append(cookies, &http.Cookie{Name: cookie.Name, Value: randomToken(len(cookie.Value))})
+	}
+
+	headers := make(map[string]string)
+	for name, value := range identity.Headers {
+		scheme, _, found := strings.Cut(value, " ")
+		switch {
+		case found && strings.EqualFold(scheme, "Basic"):
+			headers[name] = "Basic " + base64.StdEncoding.EncodeToString([]byte("drscan:"+randomToken(12)))
+		case found:
+			headers[name] = scheme + " " + randomToken(len(value)-len(scheme)-1)
+		default:
+			headers[name] = randomToken(len(value))
+		}
+	}
+	return cookies, headers
+}
+
+// randomToken 指定长度的随机十六进制串，长度过短时取16
+func randomToken(length int) string {
+	if length < 16 {
+		length = 16
+	}
+	const hexDigits = "0123456789abcdef"
+	token := make([]byte, length)
+	for i := range token {
+		token[i] = hexDigits[rand.Intn(len(hexDigits))]
+	}
+	return string(token)
+}
+
+// randomSibling 与页面同目录、几乎不可能存在的路径
+func randomSibling(u *url.URL) string {
+	dir := u.EscapedPath()
+	if i := strings.LastIndex(dir, "/"); i >= 0 {
+		dir = dir[:i+1]
+	} else {
+		dir = "/"
+	}
+	return u.Scheme + "://" + u.Host + dir + "drs" + randomToken(8)[:8]
+}
+
+// describeAttempt 用于漏洞载荷的请求描述
+func describeAttempt(attempt bypassAttempt) string {
+	desc := attempt.Method + " " + attempt.URL
+	for name, value := range attempt.Headers {
+		desc += fmt.Sprintf("\n%s: %s", name, value)
+	}
+	if attempt.Body != "" {
+		desc += "\n\n" + attempt.Body
+	}
+	return desc
+}
+
+// hasTechnique 结果中是否已有该手法的漏洞
+func hasTechnique(result *detector.DetectionResult, technique string) bool {
+	for _, vuln := range result.Vulnerabilities {
+		if vuln.Metadata["bypass_technique"] == technique {
+			return true
+		}
+	}
+	return false
+}
+
+// send 发送绕过请求，cookies/headers为空时不携带任何凭据；目标记录的认证Cookie和认证头一律去掉
+func (d *AuthBypassDetector) send(ctx context.Context, target *detector.ScanTarget, attempt bypassAttempt, cookies []*http.Cookie, headers map[string]string) (*responseSignature, error) {
+	req, err := http.NewRequestWithContext(ctx, attempt.Method, attempt.URL, strings.NewReader(attempt.Body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	for k, v := range target.Headers {
+		switch strings.ToLower(k) {
+		case "content-length", "content-type", "cookie", "authorization":
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	credentialCookies := make(map[string]bool)
+	d.mutex.Lock()
+	for _, known := range d.identities {
+		for _, cookie := range known.Cookies {
+			credentialCookies[cookie.Name] = true
+		}
+	}
+	d.mutex.Unlock()
+	for name, value := range target.Cookies {
+		if !credentialCookies[name] {
+			req.AddCookie(&http.Cookie{Name: name, Value: value})
+		}
+	}
+
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range attempt.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := d.GetHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := transport.NewResponseHelper().ReadBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	return newSignature(resp.StatusCode, resp.Header, respBody), nil
+}
+
+// finish 填写检测统计信息
+func (d *AuthBypassDetector) finish(result *detector.DetectionResult, tested int) {
+	result.Metadata["tested_parameters"] = tested
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+}
+
+// buildVulnerability 构建认证绕过漏洞对象
+func (d *AuthBypassDetector) buildVulnerability(
+	target *detector.ScanTarget,
+	severity models.Severity,
+	cwe, title, description, payload, evidence string,
+	confidence float64,
+	position models.Position,
+	bypassURL string,
+) *models.Vulnerability {
+	cvss := 8.2
+	if severity == models.SeverityMedium {
+		cvss = 5.3
+	}
+
+	vuln := models.NewVulnerabilityBuilder().
+		WithType(models.VulnAuthBypass).
+		WithCategory(models.CategoryAuth).
+		WithSeverity(severity).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithParameter("", position).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(d.Name()).
+		WithCWE(cwe).
+		WithCVSS(cvss).
+		WithSolution("在路由解析之后、按规范化后的路径统一做认证检查，对所有请求方法生效；拒绝未知方法和方法覆盖头，不信任X-Original-URL等重写头；会话无效时按未登录处理").
+		WithReferences([]string{
+			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/04-Authentication_Testing/04-Testing_for_Bypassing_Authentication_Schema",
+			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/02-Configuration_and_Deployment_Management_Testing/06-Test_HTTP_Methods",
+		}).
+		Build()
+
+	if bypassURL != "" {
+		vuln.Metadata["bypass_url"] = bypassURL
+	}
+	return vuln
+}
@@ -0,0 +1,522 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+const (
+	publicThreshold   = 0.9 // 匿名响应与主身份响应的相似度超过该值视为公开资源
+	coverageThreshold = 0.7 // 其他响应覆盖对象数据的比例超过该值视为读到了同一对象
+	minObjectShingles = 5   // 对象数据至少包含的词组数，少于该值认为ID不决定响应内容
+	maxHarvestedIDs   = 20  // 每个身份最多记录的对象ID
+)
+
+var (
+	numericIDPattern = regexp.MustCompile(`^[1-9]\d{0,11}$`)
+	uuidPattern      = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	uuidInBody       = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+)
+
+// IDORDetector 越权访问（IDOR/BOLA）检测器 - 以其他身份的会话重放主身份发现的请求，并变异请求中的对象ID，
+// 通过响应特征比较判断是否读到了其他账户的数据
+type IDORDetector struct {
+	*detector.BasePlugin
+	paramExtractor *detector.ParameterExtractor
+
+	mutex      sync.Mutex
+	identities []detector.Identity
+	tested     map[string]bool
+	harvested  map[string][]string // 身份名 -> 该身份响应中出现的UUID
+}
+
+// ObjectID 请求中的对象ID
+type ObjectID struct {
+	Name     string
+	Value    string
+	Position models.Position
+	UUID     bool
+	segment  int // 路径段序号，Position为PATH时有效
+}
+
+// NewIDORDetector 创建越权访问检测器
+func NewIDORDetector(httpClient transport.HTTPClient) *IDORDetector {
+	base := detector.NewBasePlugin(
+		"idor-detector",
+		detector.PluginTypeActive,
+		models.CategoryAuth,
+		models.SeverityHigh,
+	)
+
+	base.SetDescription("使用多个登录身份检测越权访问（IDOR/BOLA），以其他身份重放请求并变异数字/UUID对象ID")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &IDORDetector{
+		BasePlugin:     base,
+		paramExtractor: detector.NewParameterExtractor(),
+		tested:         make(map[string]bool),
+		harvested:      make(map[string][]string),
+	}
+}
+
+// SetIdentities 设置登录身份，第一个为主身份
+func (d *IDORDetector) SetIdentities(identities []detector.Identity) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.identities = identities
+}
+
+// Execute 执行越权检测
+func (d *IDORDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	d.mutex.Lock()
+	identities := d.identities
+	key := target.Method + " " + target.URL.String() + " " + target.Body
+	alreadyTested := d.tested[key]
+	d.tested[key] = true
+	d.mutex.Unlock()
+
+	if len(identities) < 2 {
+		result.Metadata["message"] = "越权检测需要至少两个已登录身份"
+		return result, nil
+	}
+	if alreadyTested {
+		result.Metadata["message"] = "请求已检测"
+		return result, nil
+	}
+
+	// 重定向通常是跳转登录页，按拒绝访问处理
+	ctx = transport.WithoutRedirects(ctx)
+	owner := identities[0]
+
+	ownerResp, err := d.send(ctx, target, &owner, nil, "")
+	if err != nil || !ownerResp.ok() {
+		result.Metadata["message"] = "主身份无法访问该请求"
+		return result, nil
+	}
+
+	anonResp, err := d.send(ctx, target, nil, nil, "")
+	if err == nil && anonResp.ok() && anonResp.similarity(ownerResp) >= publicThreshold {
+		result.Metadata["message"] = "无需认证即可访问，跳过"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] 越权检测器正在测试: %s %s\n", target.Method, target.URL)
+
+	// 将对象ID替换为不存在的值，主身份响应中随之消失的部分即该对象的数据
+	var selected *ObjectID
+	var objectData map[uint64]struct{}
+	var missingResp *responseSignature
+	ids := d.findObjectIDs(target)
+	for i := range ids {
+		missing, err := d.send(ctx, target, &owner, &ids[i], nonexistentID(ids[i]))
+		if err != nil {
+			continue
+		}
+		if data := ownerResp.difference(missing); len(data) >= minObjectShingles {
+			selected, objectData, missingResp = &ids[i], data, missing
+			break
+		}
+	}
+
+	for i := range identities[1:] {
+		other := identities[i+1]
+		otherResp, err := d.send(ctx, target, &other, nil, "")
+		if err != nil {
+			continue
+		}
+		d.harvest(other.Name, otherResp.body)
+
+		if vuln := d.checkReplay(target, owner, other, ownerResp, otherResp, selected, objectData); vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现越权访问: 身份 %s 可读取身份 %s 的数据 %s\n", other.Name, owner.Name, target.URL)
+		}
+	}
+
+	// 变异对象ID只针对GET请求，避免以他人对象ID提交修改、删除操作
+	if selected != nil && target.Method == "GET" && len(result.Vulnerabilities) == 0 {
+		if vuln := d.testMutations(ctx, target, identities, ownerResp, missingResp, selected, objectData); vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现越权访问: 对象ID %s 可被替换 %s\n", selected.Name, target.URL)
+		}
+	}
+
+	result.Metadata["tested_parameters"] = len(ids)
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// checkReplay 判断其他身份重放主身份的请求是否读到了主身份的数据
+func (d *IDORDetector) checkReplay(
+	target *detector.ScanTarget,
+	owner, other detector.Identity,
+	ownerResp, otherResp *responseSignature,
+	selected *ObjectID,
+	objectData map[uint64]struct{},
+) *models.Vulnerability {
+	if !otherResp.ok() {
+		return nil
+	}
+
+	similarity := ownerResp.similarity(otherResp)
+	markerLeaked := ownerResp.contains(owner.Marker) && !ownerResp.contains(other.Marker) && otherResp.contains(owner.Marker)
+
+	if selected != nil {
+		coverage := otherResp.coverage(objectData)
+		if coverage < coverageThreshold {
+			return nil
+		}
+
+		confidence := 0.85
+		evidence := fmt.Sprintf("身份 %s 重放请求得到 HTTP %d，响应覆盖了身份 %s 的对象数据的 %.0f%%（对象ID %s=%s）",
+			other.Name, otherResp.status, owner.Name, coverage*100, selected.Name, selected.Value)
+		if markerLeaked {
+			confidence = 0.95
+			evidence += fmt.Sprintf("，且响应中出现身份 %s 的标识 %q", owner.Name, owner.Marker)
+		}
+
+		vuln := d.buildVulnerability(target, selected, models.SeverityHigh,
+			"Broken Object Level Authorization (Cross-Account Access)",
+			fmt.Sprintf("身份 %s 使用自己的会话可以读取属于身份 %s 的对象 %s=%s，服务端未校验对象归属", other.Name, owner.Name, selected.Name, selected.Value),
+			fmt.Sprintf("以身份 %s 的会话重放", other.Name), evidence, confidence)
+		vuln.Metadata["object_coverage"] = fmt.Sprintf("%.2f", coverage)
+		d.addIdentityMetadata(vuln, owner, other, similarity)
+		return vuln
+	}
+
+	// 没有对象ID时无法区分对象数据，只在主身份的响应属于本人、对方响应中也出现主身份标识时判定
+	if !markerLeaked || similarity < 0.5 {
+		return nil
+	}
+
+	vuln := d.buildVulnerability(target, nil, models.SeverityHigh,
+		"Broken Access Control (Cross-Account Access)",
+		fmt.Sprintf("身份 %s 访问该请求时得到了身份 %s 的个人数据", other.Name, owner.Name),
+		fmt.Sprintf("以身份 %s 的会话重放", other.Name),
+		fmt.Sprintf("身份 %s 的响应（HTTP %d，与主身份响应相似度 %.2f）中出现身份 %s 的标识 %q", other.Name, otherResp.status, similarity, owner.Name, owner.Marker),
+		0.85)
+	d.addIdentityMetadata(vuln, owner, other, similarity)
+	return vuln
+}
+
+// testMutations 以主身份访问相邻的数字ID和其他身份响应中出现的UUID
+func (d *IDORDetector) testMutations(
+	ctx context.Context,
+	target *detector.ScanTarget,
+	identities []detector.Identity,
+	ownerResp, missingResp *responseSignature,
+	selected *ObjectID,
+	objectData map[uint64]struct{},
+) *models.Vulnerability {
+	owner := identities[0]
+
+	type candidate struct {
+		value  string
+		source string // UUID取自哪个身份的响应
+	}
+	var candidates []candidate
+	if selected.UUID {
+		d.mutex.Lock()
+		for _, other := range identities[1:] {
+			for i, uuid := range d.harvested[other.Name] {
+				if i >= 3 {
+					break
+				}
+				if !strings.EqualFold(uuid, selected.Value) {
+					candidates = append(candidates, candidate{value: uuid, source: other.Name})
+				}
+			}
+		}
+		d.mutex.Unlock()
+	} else {
+		value, _ := strconv.ParseInt(selected.Value, 10, 64)
+		if value > 1 {
+			candidates = append(candidates, candidate{value: strconv.FormatInt(value-1, 10)})
+		}
+		candidates = append(candidates, candidate{value: strconv.FormatInt(value+1, 10)})
+	}
+
+	var enumerable *models.Vulnerability
+	for _, c := range candidates {
+		fmt.Printf("[DEBUG] 测试对象ID: %s=%s\n", selected.Name, c.value)
+
+		resp, err := d.send(ctx, target, &owner, selected, c.value)
+		if err != nil || !resp.ok() {
+			continue
+		}
+		// 与不存在对象的响应相同：对象不存在或被拒绝
+		if len(resp.difference(missingResp)) < minObjectShingles {
+			continue
+		}
+		// 仍然是原对象：ID被忽略
+		if resp.coverage(objectData) >= coverageThreshold {
+			continue
+		}
+
+		payload := fmt.Sprintf("%s=%s", selected.Name, c.value)
+		for _, other := range identities[1:] {
+			if resp.contains(other.Marker) && !ownerResp.contains(other.Marker) {
+				vuln := d.buildVulnerability(target, selected, models.SeverityHigh,
+					"Insecure Direct Object Reference (Cross-Account Access)",
+					fmt.Sprintf("将对象ID %s 替换为 %s 后，身份 %s 读取到了身份 %s 的数据", selected.Name, c.value, owner.Name, other.Name),
+					payload,
+					fmt.Sprintf("HTTP %d，响应中出现身份 %s 的标识 %q", resp.status, other.Name, other.Marker),
+					0.9)
+				d.addIdentityMetadata(vuln, owner, other, ownerResp.similarity(resp))
+				vuln.Metadata["mutated_id"] = c.value
+				return vuln
+			}
+		}
+
+		if c.source != "" {
+			vuln := d.buildVulnerability(target, selected, models.SeverityHigh,
+				"Insecure Direct Object Reference (Cross-Account Access)",
+				fmt.Sprintf("对象ID %s 取自身份 %s 的响应，身份 %s 替换后可读取该对象", c.value, c.source, owner.Name),
+				payload,
+				fmt.Sprintf("HTTP %d，响应包含 %d 个不存在对象时没有的词组", resp.status, len(resp.difference(missingResp))),
+				0.85)
+			vuln.Metadata["mutated_id"] = c.value
+			vuln.Metadata["id_source_identity"] = c.source
+			return vuln
+		}
+
+		// 相邻对象的归属无法确认，可能是公开的目录或详情页，仅作为信息提示；
+		// 继续测试其余候选值以寻找跨账号证据
+		if enumerable == nil {
+			enumerable = d.buildVulnerability(target, selected, models.SeverityInfo,
+				"Enumerable Object ID",
+				fmt.Sprintf("将对象ID %s 替换为相邻值 %s 后，身份 %s 可读取另一个对象，未发现该对象属于其他身份的证据，需人工确认", selected.Name, c.value, owner.Name),
+				payload,
+				fmt.Sprintf("HTTP %d，响应与原对象不同且不同于不存在对象时的响应", resp.status),
+				0.4)
+			enumerable.Metadata["mutated_id"] = c.value
+		}
+	}
+
+	return enumerable
+}
+
+// findObjectIDs 查找路径段、查询参数、表单和JSON请求体中的数字ID和UUID
+func (d *IDORDetector) findObjectIDs(target *detector.ScanTarget) []ObjectID {
+	var ids []ObjectID
+
+	segments := strings.Split(target.URL.Path, "/")
+	for i, segment := range segments {
+		if numericIDPattern.MatchString(segment) || uuidPattern.MatchString(segment) {
+			ids = append(ids, ObjectID{
+				Name:     fmt.Sprintf("path[%d]", i),
+				Value:    segment,
+				Position: models.PositionPATH,
+				UUID:     uuidPattern.MatchString(segment),
+				segment:  i,
+			})
+		}
+	}
+
+	for _, point := range d.paramExtractor.ExtractParameters(target) {
+		switch point.Position {
+		case models.PositionGET, models.PositionPOST, models.PositionJSON:
+		default:
+			continue
+		}
+		if numericIDPattern.MatchString(point.Value) || uuidPattern.MatchString(point.Value) {
+			ids = append(ids, ObjectID{
+				Name:     point.Name,
+				Value:    point.Value,
+				Position: point.Position,
+				UUID:     uuidPattern.MatchString(point.Value),
+			})
+		}
+	}
+
+	return ids
+}
+
+// nonexistentID 与原ID同类型、几乎不可能存在的值
+func nonexistentID(id ObjectID) string {
+	if id.UUID {
+		return fmt.Sprintf("%08x-0000-4000-8000-%012x", rand.Uint32(), rand.Int63n(1<<48))
+	}
+	return strconv.Itoa(900000000 + rand.Intn(99999999))
+}
+
+// harvest 记录其他身份响应中出现的UUID，用于替换主身份请求中的对象ID
+func (d *IDORDetector) harvest(name string, body []byte) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	seen := make(map[string]bool)
+	for _, uuid := range d.harvested[name] {
+		seen[strings.ToLower(uuid)] = true
+	}
+	for _, uuid := range uuidInBody.FindAllString(string(body), -1) {
+		if len(d.harvested[name]) >= maxHarvestedIDs {
+			return
+		}
+		if !seen[strings.ToLower(uuid)] {
+			seen[strings.ToLower(uuid)] = true
+			d.harvested[name] = append(d.harvested[name], uuid)
+		}
+	}
+}
+
+// send 以指定身份发送目标请求，id不为空时将其替换为value；identity为空时不携带任何凭据
+func (d *IDORDetector) send(ctx context.Context, target *detector.ScanTarget, identity *detector.Identity, id *ObjectID, value string) (*responseSignature, error) {
+	u := *target.URL
+	body := target.Body
+
+	if id != nil {
+		switch id.Position {
+		case models.PositionPATH:
+			segments := strings.Split(u.Path, "/")
+			segments[id.segment] = value
+			u.Path = strings.Join(segments, "/")
+			u.RawPath = ""
+		case models.PositionGET:
+			query := u.Query()
+			query.Set(id.Name, value)
+			u.RawQuery = query.Encode()
+		case models.PositionPOST:
+			form, err := url.ParseQuery(body)
+			if err != nil {
+				return nil, err
+			}
+			form.Set(id.Name, value)
+			body = form.Encode()
+		case models.PositionJSON:
+			var replacement interface{} = value
+			if !id.UUID && !strings.Contains(body, `"`+id.Value+`"`) {
+				replacement = json.Number(value)
+			}
+			newBody, err := detector.SetJSONValue(body, id.Name, replacement)
+			if err != nil {
+				return nil, err
+			}
+			body = newBody
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, target.Method, u.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	for k, v := range target.Headers {
+		switch strings.ToLower(k) {
+		case "content-length", "cookie", "authorization":
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	// 目标记录的Cookie可能是主身份会话续期时下发的，只保留与认证无关的部分
+	credentialCookies := make(map[string]bool)
+	d.mutex.Lock()
+	for _, known := range d.identities {
+		for _, cookie := range known.Cookies {
+			credentialCookies[cookie.Name] = true
+		}
+	}
+	d.mutex.Unlock()
+	for name, value := range target.Cookies {
+		if !credentialCookies[name] {
+			req.AddCookie(&http.Cookie{Name: name, Value: value})
+		}
+	}
+
+	if identity != nil {
+		for _, cookie := range identity.Cookies {
+			req.AddCookie(cookie)
+		}
+		for k, v := range identity.Headers {
+			req.Header.Set(k, v)
+		}
+	}
+
+	resp, err := d.GetHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := transport.NewResponseHelper().ReadBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	return newSignature(resp.StatusCode, resp.Header, respBody), nil
+}
+
+// addIdentityMetadata 记录参与比较的身份
+func (d *IDORDetector) addIdentityMetadata(vuln *models.Vulnerability, owner, other detector.Identity, similarity float64) {
+	vuln.Metadata["owner_identity"] = owner.Name
+	vuln.Metadata["accessing_identity"] = other.Name
+	vuln.Metadata["similarity"] = fmt.Sprintf("%.2f", similarity)
+}
+
+// buildVulnerability 构建越权访问漏洞对象
+func (d *IDORDetector) buildVulnerability(
+	target *detector.ScanTarget,
+	id *ObjectID,
+	severity models.Severity,
+	title, description, payload, evidence string,
+	confidence float64,
+) *models.Vulnerability {
+	cvss := 8.1
+	if severity == models.SeverityMedium {
+		cvss = 6.5
+	}
+
+	builder := models.NewVulnerabilityBuilder().
+		WithType(models.VulnIDOR).
+		WithCategory(models.CategoryAuth).
+		WithSeverity(severity).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(d.Name()).
+		WithCWE("CWE-639").
+		WithCVSS(cvss).
+		WithSolution("在服务端按当前会话用户校验每个被访问对象的归属或访问权限，不要依赖对象ID不可猜测；对敏感对象使用间接引用").
+		WithReferences([]string{
+			"https://owasp.org/API-Security/editions/2023/en/0xa1-broken-object-level-authorization/",
+			"https://cheatsheetseries.owasp.org/cheatsheets/Insecure_Direct_Object_Reference_Prevention_Cheat_Sheet.html",
+		})
+	if id != nil {
+		builder = builder.WithParameter(id.Name, id.Position)
+	}
+
+	vuln := builder.Build()
+	if id != nil {
+		vuln.Metadata["object_id"] = id.Value
+	}
+	return vuln
+}
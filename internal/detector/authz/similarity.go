@@ -0,0 +1,120 @@
+package authz
+
+import (
+	"bytes"
+	"hash/fnv"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// 不同身份、不同对象的响应通常共用页面模板，按字节比较几乎总是相似。这里把响应拆成词组（连续3个词），
+// 用集合运算区分"模板"与"对象数据"：对象数据 = 该对象的响应 - 不存在对象的响应，再看其他身份的响应覆盖了多少对象数据
+
+// wordPattern 词的切分
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+
+// volatilePattern 随请求变化的值（令牌、时间戳），比较前统一替换
+var volatilePattern = regexp.MustCompile(`^([0-9a-f]{16,}|\d{9,})$`)
+
+// responseSignature 响应特征
+type responseSignature struct {
+	status      int
+	contentType string
+	body        []byte
+	shingles    map[uint64]struct{}
+}
+
+// newSignature 计算响应特征
+func newSignature(status int, header http.Header, body []byte) *responseSignature {
+	contentType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+
+	words := wordPattern.FindAllString(strings.ToLower(string(body)), -1)
+	for i, word := range words {
+		if volatilePattern.MatchString(word) {
+			words[i] = "#"
+		}
+	}
+
+	shingles := make(map[uint64]struct{})
+	for i := 0; i < len(words); i++ {
+		end := i + 3
+		if end > len(words) {
+			end = len(words)
+		}
+		hash := fnv.New64a()
+		hash.Write([]byte(strings.Join(words[i:end], " ")))
+		shingles[hash.Sum64()] = struct{}{}
+		if end == len(words) {
+			break
+		}
+	}
+
+	return &responseSignature{
+		status:      status,
+		contentType: contentType,
+		body:        body,
+		shingles:    shingles,
+	}
+}
+
+// ok 是否为成功响应
+func (s *responseSignature) ok() bool {
+	return s.status >= 200 && s.status < 300
+}
+
+// similarity 整体相似度：状态类别或媒体类型不同为0，否则为词组集合的Jaccard系数
+func (s *responseSignature) similarity(o *responseSignature) float64 {
+	if s.status/100 != o.status/100 || s.contentType != o.contentType {
+		return 0
+	}
+	if len(s.shingles) == 0 && len(o.shingles) == 0 {
+		if bytes.Equal(s.body, o.body) {
+			return 1
+		}
+		return 0
+	}
+
+	common := 0
+	for shingle := range s.shingles {
+		if _, ok := o.shingles[shingle]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(s.shingles)+len(o.shingles)-common)
+}
+
+// difference 只出现在s中、不出现在o中的词组
+func (s *responseSignature) difference(o *responseSignature) map[uint64]struct{} {
+	diff := make(map[uint64]struct{})
+	for shingle := range s.shingles {
+		if _, ok := o.shingles[shingle]; !ok {
+			diff[shingle] = struct{}{}
+		}
+	}
+	return diff
+}
+
+// coverage set中同样出现在s中的比例，set为空时为0
+func (s *responseSignature) coverage(set map[uint64]struct{}) float64 {
+	if len(set) == 0 {
+		return 0
+	}
+	covered := 0
+	for shingle := range set {
+		if _, ok := s.shingles[shingle]; ok {
+			covered++
+		}
+	}
+	return float64(covered) / float64(len(set))
+}
+
+// contains 响应中是否以完整单词出现身份标识，过短的标识容易误匹配，视为不出现
+func (s *responseSignature) contains(marker string) bool {
+	if len(marker) < 3 {
+		return false
+	}
+	pattern := regexp.MustCompile(`(?i)(^|[^\p{L}\p{N}_])` + regexp.QuoteMeta(marker) + `($|[^\p{L}\p{N}_])`)
+	return pattern.Match(s.body)
+}
@@ -8,6 +8,8 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dronesec/droneriskscan/internal/transport"
 	"github.com/dronesec/droneriskscan/pkg/models"
@@ -34,6 +36,77 @@ type Plugin interface {
 	SetEnabled(enabled bool)
 }
 
+// PostScanPlugin 需要在所有目标扫描完成后执行收尾检测的插件（如存储型漏洞的回访验证）
+type PostScanPlugin interface {
+	Plugin
+
+	// PostScan 在扫描结束前调用，pages为本次扫描访问过的全部页面
+	PostScan(ctx context.Context, pages []string) (*DetectionResult, error)
+}
+
+// SessionAware 需要携带会话Cookie发送请求的插件
+type SessionAware interface {
+	SetSessionCookies(cookies []*http.Cookie)
+}
+
+// OOBInteraction 带外交互载荷信息
+type OOBInteraction struct {
+	ID     string // 关联ID
+	URL    string // HTTP回连地址
+	Domain string // DNS回连域名（未启用DNS监听时为空）
+	Email  string // SMTP回连邮箱（未启用SMTP监听时为空）
+}
+
+// OOBProvider 带外交互提供者，用于确认无回显的盲注类漏洞
+type OOBProvider interface {
+	// NewInteraction 生成新的关联ID及目标可回连的地址
+	NewInteraction() *OOBInteraction
+	// WaitForInteraction 等待关联ID的回连，超时返回false
+	WaitForInteraction(ctx context.Context, id string, timeout time.Duration) bool
+	// ExpectInteraction 登记关联ID回连到达时上报的漏洞，用于插件返回后才到达的延迟回连；
+	// 同一漏洞可登记在多个关联ID上，只上报一次
+	ExpectInteraction(id string, vuln *models.Vulnerability)
+}
+
+// OOBContentHost 可为关联ID返回指定内容的带外交互提供者，用于托管攻击者控制的资源（如JWKS、证书）
+type OOBContentHost interface {
+	// ServeContent 目标回连该关联ID时返回指定内容，而不是默认的应答
+	ServeContent(id, contentType string, body []byte)
+}
+
+// OOBAware 支持带外交互确认的插件
+type OOBAware interface {
+	SetOOBProvider(provider OOBProvider)
+}
+
+// OutputAware 需要将扫描产物写入输出目录的插件
+type OutputAware interface {
+	SetOutputDir(dir string)
+}
+
+// Identity 越权检测使用的登录身份
+type Identity struct {
+	Name    string
+	Marker  string            // 该身份特有、会出现在其个人数据中的字符串（如用户名），可为空
+	Cookies []*http.Cookie
+	Headers map[string]string // Basic/Bearer等随请求发送的认证头
+}
+
+// IdentityAware 需要多个登录身份的插件，第一个为主身份（爬虫和其他插件使用的会话）
+type IdentityAware interface {
+	SetIdentities(identities []Identity)
+}
+
+// ResponseObserver 需要检查扫描过程中全部HTTP响应的被动插件
+type ResponseObserver interface {
+	ObserveResponse(resp *http.Response)
+}
+
+// ScopeAware 需要区分扫描范围内主机的插件，hosts为用户指定目标的主机名
+type ScopeAware interface {
+	SetScopeHosts(hosts []string)
+}
+
 // PluginType 插件类型
 type PluginType string
 
@@ -250,6 +323,9 @@ func (pe *ParameterExtractor) ExtractParameters(target *ScanTarget) []InjectPoin
 		}
 	}
 	
+	// 提取JSON/XML请求体参数
+	points = append(points, pe.extractBodyParameters(target)...)
+	
 	// 提取Cookie参数（排除认证相关Cookie）
 	authCookies := []string{"PHPSESSID", "JSESSIONID", "ASP.NET_SessionId", "security_level", "_token", "csrf_token"}
 	for name, value := range target.Cookies {
@@ -305,8 +381,12 @@ const (
 	ParamTypeBoolean ParamType = "boolean"
 	ParamTypeEmail   ParamType = "email"
 	ParamTypeURL     ParamType = "url"
+	ParamTypePath    ParamType = "path"
 )
 
+// filePathPattern 文件路径特征：包含目录分隔符或常见文件扩展名
+var filePathPattern = regexp.MustCompile(`(?i)^[\w\-./\\:]*([/\\][\w\-.]+|\.(php\d?|phtml|inc|html?|txt|xml|json|ini|conf|cfg|log|jsp|aspx?|tpl|tmpl|md|pdf|csv))$`)
+
 // inferParameterType 推断参数类型
 func (pe *ParameterExtractor) inferParameterType(value string) ParamType {
 	// 数字类型
@@ -332,6 +412,11 @@ func (pe *ParameterExtractor) inferParameterType(value string) ParamType {
 		return ParamTypeURL
 	}
 	
+	// 文件路径类型
+	if filePathPattern.MatchString(value) {
+		return ParamTypePath
+	}
+	
 	// 默认字符串类型
 	return ParamTypeString
 }
@@ -340,6 +425,7 @@ func (pe *ParameterExtractor) inferParameterType(value string) ParamType {
 type RequestModifier struct {
 	httpClient transport.HTTPClient
 	sessionCookies []*http.Cookie
+	cookieMutex    sync.RWMutex
 }
 
 // NewRequestModifier 创建请求修改器
@@ -352,9 +438,18 @@ func NewRequestModifier(httpClient transport.HTTPClient) *RequestModifier {
 
 // SetSessionCookies 设置会话Cookie
 func (rm *RequestModifier) SetSessionCookies(cookies []*http.Cookie) {
+	rm.cookieMutex.Lock()
+	defer rm.cookieMutex.Unlock()
 	rm.sessionCookies = cookies
 }
 
+// getSessionCookies 获取会话Cookie，扫描器会在插件执行期间并发更新
+func (rm *RequestModifier) getSessionCookies() []*http.Cookie {
+	rm.cookieMutex.RLock()
+	defer rm.cookieMutex.RUnlock()
+	return rm.sessionCookies
+}
+
 // ModifyParameter 修改参数并发送请求
 func (rm *RequestModifier) ModifyParameter(ctx context.Context, target *ScanTarget, point InjectPoint, payload string) (*http.Response, error) {
 	// 根据参数位置修改请求
@@ -367,11 +462,127 @@ func (rm *RequestModifier) ModifyParameter(ctx context.Context, target *ScanTarg
 		return rm.modifyHeaderParameter(ctx, target, point.Name, payload)
 	case models.PositionCOOKIE:
 		return rm.modifyCookieParameter(ctx, target, point.Name, payload)
+	case models.PositionJSON:
+		return rm.modifyJSONParameter(ctx, target, point.Name, payload)
+	case models.PositionXML:
+		return rm.modifyXMLParameter(ctx, target, point.Name, payload)
+	case models.PositionMultipart:
+		return rm.modifyMultipartParameter(ctx, target, point.Name, payload)
 	default:
 		return nil, fmt.Errorf("不支持的参数位置: %s", point.Position)
 	}
 }
 
+// ModifyParameterRaw 以原始形式写入payload并发送请求，GET/POST参数不再进行URL编码，
+// 用于发送已预先编码（如双重编码、%00截断）的payload；其他位置与ModifyParameter一致
+func (rm *RequestModifier) ModifyParameterRaw(ctx context.Context, target *ScanTarget, point InjectPoint, rawPayload string) (*http.Response, error) {
+	switch point.Position {
+	case models.PositionGET:
+		u := *target.URL
+		u.RawQuery = replaceRawQueryValue(u.Query(), point.Name, rawPayload)
+		
+		req, err := http.NewRequestWithContext(ctx, target.Method, u.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("创建请求失败: %w", err)
+		}
+		rm.applyTargetContext(req, target)
+		return rm.httpClient.Do(req)
+		
+	case models.PositionPOST:
+		formValues, err := url.ParseQuery(target.Body)
+		if err != nil {
+			return nil, fmt.Errorf("解析表单数据失败: %w", err)
+		}
+		newBody := replaceRawQueryValue(formValues, point.Name, rawPayload)
+		
+		req, err := http.NewRequestWithContext(ctx, target.Method, target.URL.String(), strings.NewReader(newBody))
+		if err != nil {
+			return nil, fmt.Errorf("创建请求失败: %w", err)
+		}
+		rm.applyTargetContext(req, target)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return rm.httpClient.Do(req)
+		
+	default:
+		return rm.ModifyParameter(ctx, target, point, rawPayload)
+	}
+}
+
+// ModifyParameterName 将参数替换为新的参数名后发送请求（如 name -> name[$ne]），仅支持GET/POST参数
+func (rm *RequestModifier) ModifyParameterName(ctx context.Context, target *ScanTarget, point InjectPoint, newName, value string) (*http.Response, error) {
+	switch point.Position {
+	case models.PositionGET:
+		u := *target.URL
+		query := u.Query()
+		query.Del(point.Name)
+		query.Set(newName, value)
+		u.RawQuery = query.Encode()
+		
+		req, err := http.NewRequestWithContext(ctx, target.Method, u.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("创建请求失败: %w", err)
+		}
+		rm.applyTargetContext(req, target)
+		return rm.httpClient.Do(req)
+		
+	case models.PositionPOST:
+		formValues, err := url.ParseQuery(target.Body)
+		if err != nil {
+			return nil, fmt.Errorf("解析表单数据失败: %w", err)
+		}
+		formValues.Del(point.Name)
+		formValues.Set(newName, value)
+		return rm.SendBody(ctx, target, "application/x-www-form-urlencoded", formValues.Encode())
+		
+	default:
+		return nil, fmt.Errorf("不支持修改参数名的位置: %s", point.Position)
+	}
+}
+
+// SendBody 使用指定的请求体和Content-Type发送目标请求
+func (rm *RequestModifier) SendBody(ctx context.Context, target *ScanTarget, contentType, body string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, target.Method, target.URL.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	rm.applyTargetContext(req, target)
+	req.Header.Set("Content-Type", contentType)
+	return rm.httpClient.Do(req)
+}
+
+// replaceRawQueryValue 编码其余参数，并以原始形式拼接指定参数
+func replaceRawQueryValue(values url.Values, name, rawValue string) string {
+	values.Del(name)
+	encoded := values.Encode()
+	pair := url.QueryEscape(name) + "=" + rawValue
+	if encoded == "" {
+		return pair
+	}
+	return encoded + "&" + pair
+}
+
+// applyTargetContext 添加目标头部与Cookie，会话Cookie优先
+func (rm *RequestModifier) applyTargetContext(req *http.Request, target *ScanTarget) {
+	for k, v := range target.Headers {
+		if k != "Content-Length" {
+			req.Header.Set(k, v)
+		}
+	}
+	
+	sessionCookieNames := make(map[string]bool)
+	for _, cookie := range rm.getSessionCookies() {
+		sessionCookieNames[cookie.Name] = true
+	}
+	for k, v := range target.Cookies {
+		if !sessionCookieNames[k] {
+			req.AddCookie(&http.Cookie{Name: k, Value: v})
+		}
+	}
+	for _, cookie := range rm.getSessionCookies() {
+		req.AddCookie(cookie)
+	}
+}
+
 // modifyGETParameter 修改GET参数
 func (rm *RequestModifier) modifyGETParameter(ctx context.Context, target *ScanTarget, paramName, payload string) (*http.Response, error) {
 	// 解析URL
@@ -397,7 +608,7 @@ func (rm *RequestModifier) modifyGETParameter(ctx context.Context, target *ScanT
 	
 	// 添加目标Cookie（但跳过会被会话Cookie覆盖的）
 	sessionCookieNames := make(map[string]bool)
-	for _, cookie := range rm.sessionCookies {
+	for _, cookie := range rm.getSessionCookies() {
 		sessionCookieNames[cookie.Name] = true
 	}
 	
@@ -409,7 +620,7 @@ func (rm *RequestModifier) modifyGETParameter(ctx context.Context, target *ScanT
 	}
 	
 	// 添加会话Cookie（这些优先级更高）
-	for _, cookie := range rm.sessionCookies {
+	for _, cookie := range rm.getSessionCookies() {
 		req.AddCookie(cookie)
 	}
 	
@@ -462,7 +673,7 @@ func (rm *RequestModifier) modifyPOSTParameter(ctx context.Context, target *Scan
 	}
 	
 	// 添加会话Cookie
-	for _, cookie := range rm.sessionCookies {
+	for _, cookie := range rm.getSessionCookies() {
 		req.AddCookie(cookie)
 	}
 	
@@ -495,7 +706,7 @@ func (rm *RequestModifier) modifyHeaderParameter(ctx context.Context, target *Sc
 	}
 	
 	// 添加会话Cookie
-	for _, cookie := range rm.sessionCookies {
+	for _, cookie := range rm.getSessionCookies() {
 		req.AddCookie(cookie)
 	}
 	
@@ -529,13 +740,50 @@ func (rm *RequestModifier) modifyCookieParameter(ctx context.Context, target *Sc
 	}
 	
 	// 添加会话Cookie
-	for _, cookie := range rm.sessionCookies {
+	for _, cookie := range rm.getSessionCookies() {
 		req.AddCookie(cookie)
 	}
 	
 	return rm.httpClient.Do(req)
 }
 
+// modifyJSONParameter 修改JSON请求体中指定路径的值
+func (rm *RequestModifier) modifyJSONParameter(ctx context.Context, target *ScanTarget, path, payload string) (*http.Response, error) {
+	newBody, err := SetJSONValue(target.Body, path, payload)
+	if err != nil {
+		return nil, err
+	}
+	
+	contentType := ContentTypeOf(target)
+	if !IsJSONContentType(contentType) {
+		contentType = "application/json"
+	}
+	return rm.SendBody(ctx, target, contentType, newBody)
+}
+
+// modifyXMLParameter 修改XML请求体中指定元素文本或属性的值
+func (rm *RequestModifier) modifyXMLParameter(ctx context.Context, target *ScanTarget, path, payload string) (*http.Response, error) {
+	newBody, err := SetXMLValue(target.Body, path, payload)
+	if err != nil {
+		return nil, err
+	}
+	
+	contentType := ContentTypeOf(target)
+	if !IsXMLContentType(contentType) {
+		contentType = "application/xml"
+	}
+	return rm.SendBody(ctx, target, contentType, newBody)
+}
+
+// modifyMultipartParameter 修改multipart/form-data请求体中的普通字段
+func (rm *RequestModifier) modifyMultipartParameter(ctx context.Context, target *ScanTarget, name, payload string) (*http.Response, error) {
+	newBody, contentType, err := SetMultipartValue(ContentTypeOf(target), target.Body, name, payload)
+	if err != nil {
+		return nil, err
+	}
+	return rm.SendBody(ctx, target, contentType, newBody)
+}
+
 // ResponseAnalyzer 响应分析器
 type ResponseAnalyzer struct{}
 
@@ -577,6 +825,84 @@ func (ra *ResponseAnalyzer) ContainsErrorPatterns(body []byte, patterns []string
 	return len(foundPatterns) > 0, foundPatterns
 }
 
+// AnalyzeBooleanDifference 分析布尔条件对的响应差异：True条件应与基准相似，False条件应与之不同
+func (ra *ResponseAnalyzer) AnalyzeBooleanDifference(baseline, trueResp, falseResp []byte) bool {
+	baselineLen := len(baseline)
+	trueLen := len(trueResp)
+	falseLen := len(falseResp)
+	
+	// 1. 基本长度差异检查
+	trueDiff := abs(trueLen - baselineLen)
+	falseDiff := abs(falseLen - baselineLen)
+	
+	// True应该与基准相似，False应该有差异
+	if trueDiff < 100 && falseDiff > 500 {
+		return true
+	}
+	
+	// 2. True和False之间的差异
+	tfDiff := abs(trueLen - falseLen)
+	if tfDiff > 200 {
+		return true
+	}
+	
+	// 3. 内容相似度分析
+	trueSim, _ := ra.AnalyzeDifference(baseline, trueResp)
+	falseSim, _ := ra.AnalyzeDifference(baseline, falseResp)
+	
+	// True应该与基准更相似
+	if trueSim > 0.95 && falseSim < 0.85 {
+		return true
+	}
+	
+	// 4. 文本内容差异分析
+	if ra.analyzeTextDifferences(string(trueResp), string(falseResp)) {
+		return true
+	}
+	
+	return false
+}
+
+// analyzeTextDifferences 比较两个响应的词频差异
+func (ra *ResponseAnalyzer) analyzeTextDifferences(trueBody, falseBody string) bool {
+	// 检查关键词差异
+	trueWords := strings.Fields(strings.ToLower(trueBody))
+	falseWords := strings.Fields(strings.ToLower(falseBody))
+	
+	trueWordCount := make(map[string]int)
+	falseWordCount := make(map[string]int)
+	
+	for _, word := range trueWords {
+		trueWordCount[word]++
+	}
+	
+	for _, word := range falseWords {
+		falseWordCount[word]++
+	}
+	
+	// 计算词汇差异
+	diffCount := 0
+	for word := range trueWordCount {
+		if trueWordCount[word] != falseWordCount[word] {
+			diffCount++
+		}
+	}
+	
+	for word := range falseWordCount {
+		if falseWordCount[word] != trueWordCount[word] {
+			diffCount++
+		}
+	}
+	
+	// 如果词汇差异超过10%，认为存在差异
+	totalWords := len(trueWords) + len(falseWords)
+	if totalWords > 0 && float64(diffCount)/float64(totalWords) > 0.1 {
+		return true
+	}
+	
+	return false
+}
+
 // 辅助函数
 func abs(x int) int {
 	if x < 0 {
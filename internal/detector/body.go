@@ -0,0 +1,556 @@
+package detector
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// 请求体注入点：JSON请求体按路径展开（如 user.address[0].city），
+// XML请求体映射为元素文本（如 order/items/item[1]/name）和属性（如 order/@id），
+// multipart/form-data请求体的普通字段映射为MULTIPART注入点，文件字段在重建时原样保留。
+// GraphQL请求体只取 variables 下的参数，改写查询文本本身只会得到语法错误
+
+// ContentTypeOf 获取目标请求的Content-Type（不区分头部大小写）
+func ContentTypeOf(target *ScanTarget) string {
+	for k, v := range target.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			return v
+		}
+	}
+	return ""
+}
+
+// IsJSONContentType 判断是否为JSON内容类型
+func IsJSONContentType(contentType string) bool {
+	ct := strings.ToLower(contentType)
+	return strings.Contains(ct, "application/json") || strings.Contains(ct, "+json")
+}
+
+// IsMultipartContentType 判断是否为multipart/form-data内容类型
+func IsMultipartContentType(contentType string) bool {
+	return strings.Contains(strings.ToLower(contentType), "multipart/form-data")
+}
+
+// IsXMLContentType 判断是否为XML内容类型（含SOAP）
+func IsXMLContentType(contentType string) bool {
+	ct := strings.ToLower(contentType)
+	return strings.Contains(ct, "/xml") || strings.Contains(ct, "+xml")
+}
+
+// IsGraphQLBody 判断JSON请求体是否为带变量的GraphQL请求（{"query": "...", "variables": {...}}）
+func IsGraphQLBody(body string) bool {
+	root, err := decodeJSON(body)
+	if err != nil {
+		return false
+	}
+	object, ok := root.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, hasQuery := object["query"].(string)
+	_, hasVariables := object["variables"].(map[string]interface{})
+	return hasQuery && hasVariables
+}
+
+// extractBodyParameters 从JSON/XML请求体中提取注入点
+func (pe *ParameterExtractor) extractBodyParameters(target *ScanTarget) []InjectPoint {
+	if target.Body == "" {
+		return nil
+	}
+
+	contentType := ContentTypeOf(target)
+	var points []InjectPoint
+
+	switch {
+	case IsJSONContentType(contentType):
+		graphQL := IsGraphQLBody(target.Body)
+		for _, leaf := range FlattenJSON(target.Body) {
+			if graphQL && !strings.HasPrefix(leaf.Path, "variables.") {
+				continue
+			}
+			points = append(points, InjectPoint{
+				Name:     leaf.Path,
+				Value:    leaf.Value,
+				Position: models.PositionJSON,
+				Type:     pe.inferParameterType(leaf.Value),
+			})
+		}
+
+	case IsMultipartContentType(contentType):
+		parts, err := ParseMultipartBody(contentType, target.Body)
+		if err != nil {
+			return nil
+		}
+		for _, part := range parts {
+			if part.IsFile {
+				continue
+			}
+			points = append(points, InjectPoint{
+				Name:     part.Name,
+				Value:    string(part.Data),
+				Position: models.PositionMultipart,
+				Type:     pe.inferParameterType(string(part.Data)),
+			})
+		}
+
+	case IsXMLContentType(contentType):
+		spans, err := xmlInjectSpans(target.Body)
+		if err != nil {
+			return nil
+		}
+		for _, span := range spans {
+			points = append(points, InjectPoint{
+				Name:     span.Path,
+				Value:    span.Value,
+				Position: models.PositionXML,
+				Type:     pe.inferParameterType(span.Value),
+			})
+		}
+	}
+
+	return points
+}
+
+// JSONLeaf JSON叶子节点
+type JSONLeaf struct {
+	Path  string
+	Value string
+}
+
+// FlattenJSON 将JSON请求体展开为叶子节点路径，解析失败返回nil
+func FlattenJSON(body string) []JSONLeaf {
+	root, err := decodeJSON(body)
+	if err != nil {
+		return nil
+	}
+
+	var leaves []JSONLeaf
+	var walk func(path string, node interface{})
+	walk = func(path string, node interface{}) {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				childPath := key
+				if path != "" {
+					childPath = path + "." + key
+				}
+				walk(childPath, v[key])
+			}
+		case []interface{}:
+			for i, item := range v {
+				walk(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		case nil:
+			leaves = append(leaves, JSONLeaf{Path: path, Value: ""})
+		case string:
+			leaves = append(leaves, JSONLeaf{Path: path, Value: v})
+		default:
+			leaves = append(leaves, JSONLeaf{Path: path, Value: fmt.Sprint(v)})
+		}
+	}
+	walk("", root)
+
+	return leaves
+}
+
+// SetJSONValue 将JSON请求体中指定路径的值替换为value并重新序列化，
+// value可以是字符串，也可以是对象/数组（如NoSQL操作符）；路径不存在的对象键会被创建
+func SetJSONValue(body, path string, value interface{}) (string, error) {
+	root, err := decodeJSON(body)
+	if err != nil {
+		return "", fmt.Errorf("解析JSON请求体失败: %w", err)
+	}
+
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	root, err = setJSONPath(root, segments, value)
+	if err != nil {
+		return "", fmt.Errorf("设置JSON路径 %s 失败: %w", path, err)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(root); err != nil {
+		return "", fmt.Errorf("序列化JSON失败: %w", err)
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// decodeJSON 解析JSON并保留数字的原始形式
+func decodeJSON(body string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+
+	var root interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// jsonPathSegment JSON路径片段
+type jsonPathSegment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// parseJSONPath 解析 a.b[0].c 形式的路径
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	var segments []jsonPathSegment
+
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var indexes []int
+		if bracket := strings.Index(part, "["); bracket >= 0 {
+			key = part[:bracket]
+			rest := part[bracket:]
+			for rest != "" {
+				end := strings.Index(rest, "]")
+				if !strings.HasPrefix(rest, "[") || end < 0 {
+					return nil, fmt.Errorf("无效的JSON路径: %s", path)
+				}
+				index, err := strconv.Atoi(rest[1:end])
+				if err != nil {
+					return nil, fmt.Errorf("无效的JSON数组下标: %s", path)
+				}
+				indexes = append(indexes, index)
+				rest = rest[end+1:]
+			}
+		}
+
+		if key != "" {
+			segments = append(segments, jsonPathSegment{Key: key})
+		}
+		for _, index := range indexes {
+			segments = append(segments, jsonPathSegment{Index: index, IsIndex: true})
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("空的JSON路径")
+	}
+	return segments, nil
+}
+
+// setJSONPath 递归设置路径上的值，返回替换后的节点
+func setJSONPath(node interface{}, segments []jsonPathSegment, value interface{}) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+
+	segment := segments[0]
+	if segment.IsIndex {
+		array, ok := node.([]interface{})
+		if !ok || segment.Index < 0 || segment.Index >= len(array) {
+			return nil, fmt.Errorf("数组下标 %d 不存在", segment.Index)
+		}
+		child, err := setJSONPath(array[segment.Index], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		array[segment.Index] = child
+		return array, nil
+	}
+
+	object, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("键 %s 的父节点不是对象", segment.Key)
+	}
+	child, err := setJSONPath(object[segment.Key], segments[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	object[segment.Key] = child
+	return object, nil
+}
+
+// xmlSpan XML注入点在原始请求体中的字节范围
+type xmlSpan struct {
+	Path  string
+	Value string
+	Start int
+	End   int
+}
+
+// xmlFrame 解析XML时的元素栈帧
+type xmlFrame struct {
+	path       string
+	contentPos int // 开始标签结束位置
+	selfClose  bool
+	textStart  int
+	textEnd    int
+	text       strings.Builder
+	hasChild   bool
+	childCount map[string]int
+}
+
+// xmlInjectSpans 解析XML请求体，返回叶子元素文本和属性值的位置
+func xmlInjectSpans(body string) ([]xmlSpan, error) {
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	decoder.Strict = false
+
+	var spans []xmlSpan
+	var stack []*xmlFrame
+	rootCount := make(map[string]int)
+
+	for {
+		before := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析XML请求体失败: %w", err)
+		}
+		after := int(decoder.InputOffset())
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			counter := rootCount
+			parentPath := ""
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.hasChild = true
+				counter = parent.childCount
+				parentPath = parent.path + "/"
+			}
+
+			name := t.Name.Local
+			if n := counter[name]; n > 0 {
+				name = fmt.Sprintf("%s[%d]", name, n)
+			}
+			counter[t.Name.Local]++
+
+			frame := &xmlFrame{
+				path:       parentPath + name,
+				contentPos: after,
+				selfClose:  strings.HasSuffix(body[before:after], "/>"),
+				textStart:  -1,
+				childCount: make(map[string]int),
+			}
+			stack = append(stack, frame)
+
+			spans = append(spans, xmlAttributeSpans(body, before, after, frame.path, t.Attr)...)
+
+		case xml.CharData:
+			if len(stack) == 0 {
+				continue
+			}
+			frame := stack[len(stack)-1]
+			if frame.textStart < 0 {
+				frame.textStart = before
+			}
+			frame.textEnd = after
+			frame.text.Write(t)
+
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+			frame := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if frame.hasChild {
+				continue
+			}
+			if frame.textStart >= 0 {
+				spans = append(spans, xmlSpan{
+					Path:  frame.path,
+					Value: frame.text.String(),
+					Start: frame.textStart,
+					End:   frame.textEnd,
+				})
+			} else if !frame.selfClose {
+				// <a></a> 空元素，在开始标签后插入；自闭合元素 <a/> 无法直接插入文本，跳过
+				spans = append(spans, xmlSpan{Path: frame.path, Start: frame.contentPos, End: frame.contentPos})
+			}
+		}
+	}
+
+	return spans, nil
+}
+
+// xmlAttributeSpans 在开始标签的原始字节中定位各属性值
+func xmlAttributeSpans(body string, tagStart, tagEnd int, elementPath string, attrs []xml.Attr) []xmlSpan {
+	var spans []xmlSpan
+	raw := body[tagStart:tagEnd]
+
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+
+		pattern := regexp.MustCompile(`\s(?:[\w.\-]+:)?` + regexp.QuoteMeta(attr.Name.Local) + `\s*=\s*("[^"]*"|'[^']*')`)
+		loc := pattern.FindStringSubmatchIndex(raw)
+		if loc == nil {
+			continue
+		}
+
+		// 去掉引号
+		start := tagStart + loc[2] + 1
+		end := tagStart + loc[3] - 1
+		spans = append(spans, xmlSpan{
+			Path:  elementPath + "/@" + attr.Name.Local,
+			Value: attr.Value,
+			Start: start,
+			End:   end,
+		})
+	}
+
+	return spans
+}
+
+// SetXMLValue 将XML请求体中指定元素文本或属性的值替换为payload（经XML转义），保留其余结构
+func SetXMLValue(body, path, payload string) (string, error) {
+	var escaped bytes.Buffer
+	if err := xml.EscapeText(&escaped, []byte(payload)); err != nil {
+		return "", fmt.Errorf("转义XML内容失败: %w", err)
+	}
+	return SetXMLRawValue(body, path, escaped.String())
+}
+
+// SetXMLRawValue 将XML请求体中指定元素文本或属性的值替换为原始XML片段（不转义，如实体引用 &xxe;）
+func SetXMLRawValue(body, path, raw string) (string, error) {
+	spans, err := xmlInjectSpans(body)
+	if err != nil {
+		return "", err
+	}
+
+	for _, span := range spans {
+		if span.Path == path {
+			return body[:span.Start] + raw + body[span.End:], nil
+		}
+	}
+
+	return "", fmt.Errorf("XML路径不存在: %s", path)
+}
+
+// MultipartPart multipart/form-data中的一个字段
+type MultipartPart struct {
+	Name        string
+	FileName    string
+	ContentType string
+	Data        []byte
+	IsFile      bool // Content-Disposition中带有filename参数
+}
+
+// ParseMultipartBody 解析multipart/form-data请求体
+func ParseMultipartBody(contentType, body string) ([]*MultipartPart, error) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("解析Content-Type失败: %w", err)
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, fmt.Errorf("multipart请求缺少boundary")
+	}
+
+	reader := multipart.NewReader(strings.NewReader(body), boundary)
+	var parts []*MultipartPart
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析multipart请求体失败: %w", err)
+		}
+
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("读取multipart字段失败: %w", err)
+		}
+
+		_, dispParams, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+		fileName, isFile := dispParams["filename"]
+		parts = append(parts, &MultipartPart{
+			Name:        dispParams["name"],
+			FileName:    fileName,
+			ContentType: part.Header.Get("Content-Type"),
+			Data:        data,
+			IsFile:      isFile,
+		})
+	}
+
+	return parts, nil
+}
+
+// multipartQuoteEscaper 转义Content-Disposition中的引号
+var multipartQuoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// BuildMultipartBody 重建multipart/form-data请求体，返回请求体和带boundary的Content-Type
+func BuildMultipartBody(parts []*MultipartPart) (string, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	for _, part := range parts {
+		disposition := fmt.Sprintf(`form-data; name="%s"`, multipartQuoteEscaper.Replace(part.Name))
+		if part.IsFile {
+			disposition += fmt.Sprintf(`; filename="%s"`, multipartQuoteEscaper.Replace(part.FileName))
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", disposition)
+		if part.ContentType != "" {
+			header.Set("Content-Type", part.ContentType)
+		} else if part.IsFile {
+			header.Set("Content-Type", "application/octet-stream")
+		}
+
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return "", "", fmt.Errorf("创建multipart字段失败: %w", err)
+		}
+		if _, err := w.Write(part.Data); err != nil {
+			return "", "", fmt.Errorf("写入multipart字段失败: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", "", fmt.Errorf("结束multipart请求体失败: %w", err)
+	}
+	return buf.String(), writer.FormDataContentType(), nil
+}
+
+// SetMultipartValue 替换multipart请求体中指定普通字段的值
+func SetMultipartValue(contentType, body, name, payload string) (string, string, error) {
+	parts, err := ParseMultipartBody(contentType, body)
+	if err != nil {
+		return "", "", err
+	}
+
+	found := false
+	for _, part := range parts {
+		if part.Name == name && !part.IsFile {
+			part.Data = []byte(payload)
+			found = true
+		}
+	}
+	if !found {
+		return "", "", fmt.Errorf("multipart字段不存在: %s", name)
+	}
+
+	return BuildMultipartBody(parts)
+}
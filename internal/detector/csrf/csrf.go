@@ -0,0 +1,632 @@
+package csrf
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/crawler"
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// csrfAttackerOrigin 跨站请求使用的攻击者源（保留域名）
+const csrfAttackerOrigin = "https://drs-csrf.example.com"
+
+var (
+	// tokenFieldPattern 反CSRF令牌字段名
+	tokenFieldPattern = regexp.MustCompile(`(?i)(csrf|xsrf|token|nonce|authenticity|verification|formkey|form_key|__viewstategenerator)`)
+	// rejectionPattern 令牌校验失败页面的常见提示，仅在正常提交的响应中不存在时作为拒绝依据
+	rejectionPattern = regexp.MustCompile(`(?i)(csrf|xsrf|forgery|invalid[\s_-]*token|token[\s_-]*(is\s+)?(mismatch|missing|invalid|expired)|request\s+verification|page\s+expired)`)
+	inputTagPattern  = regexp.MustCompile(`(?is)<input[^>]*>`)
+	inputNamePattern = regexp.MustCompile(`(?i)\bname\s*=\s*["']([^"']+)["']`)
+	inputValPattern  = regexp.MustCompile(`(?i)\bvalue\s*=\s*["']([^"']*)["']`)
+)
+
+// CSRFDetector 跨站请求伪造检测器 - 对爬虫发现的POST表单，去掉、置空、伪造或换用其他会话的反CSRF令牌，
+// 并以跨站或缺失的Origin/Referer重新提交，服务端仍按正常提交处理时判定存在CSRF
+type CSRFDetector struct {
+	*detector.BasePlugin
+
+	mutex      sync.Mutex
+	identities []detector.Identity
+	tested     map[string]bool
+}
+
+// csrfVariant 一种令牌篡改方式
+type csrfVariant struct {
+	Name        string
+	Technique   string
+	Description string
+	Token       *string // 令牌字段的新值，为nil时去掉该字段
+}
+
+// csrfHit 被服务端接受的伪造请求
+type csrfHit struct {
+	variant     csrfVariant
+	crossSite   bool // 以跨站Origin/Referer提交被接受；为false时只在不带Origin/Referer时被接受
+	status      int
+	parts       []*detector.MultipartPart
+	contentType string
+}
+
+// csrfResponse 提交表单的响应摘要
+type csrfResponse struct {
+	status   int
+	location string
+	body     string
+}
+
+// NewCSRFDetector 创建CSRF检测器
+func NewCSRFDetector(httpClient transport.HTTPClient) *CSRFDetector {
+	base := detector.NewBasePlugin(
+		"csrf-detector",
+		detector.PluginTypeActive,
+		models.CategoryCSRF,
+		models.SeverityMedium,
+	)
+
+	base.SetDescription("检测POST表单的CSRF防护：令牌去掉/置空/伪造/跨会话复用、跨站或缺失的Origin/Referer，以及会话Cookie的SameSite属性")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &CSRFDetector{
+		BasePlugin: base,
+		tested:     make(map[string]bool),
+	}
+}
+
+// SetIdentities 设置登录身份，第一个为提交表单的受害者会话，第二个（如有）提供其他会话的令牌
+func (d *CSRFDetector) SetIdentities(identities []detector.Identity) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.identities = identities
+}
+
+// Execute 执行CSRF检测
+func (d *CSRFDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	form, _ := target.Metadata["form"].(*crawler.FormInfo)
+	pageURL, _ := target.Metadata["page_url"].(string)
+	if form == nil || !strings.EqualFold(target.Method, "POST") {
+		result.Metadata["message"] = "仅检测爬虫发现的POST表单"
+		return result, nil
+	}
+	if form.IsSearch {
+		result.Metadata["message"] = "搜索表单不改变状态，跳过"
+		return result, nil
+	}
+
+	contentType := detector.ContentTypeOf(target)
+	parts, err := parseFormBody(contentType, target.Body)
+	if err != nil || len(parts) == 0 {
+		result.Metadata["message"] = "无法解析表单请求体"
+		return result, nil
+	}
+
+	d.mutex.Lock()
+	var victim, other *detector.Identity
+	if len(d.identities) > 0 {
+		victim = &d.identities[0]
+	}
+	if len(d.identities) > 1 {
+		other = &d.identities[1]
+	}
+	key := formKey(target, parts)
+	alreadyTested := d.tested[key]
+	d.tested[key] = true
+	d.mutex.Unlock()
+
+	if alreadyTested {
+		result.Metadata["message"] = "表单已检测"
+		return result, nil
+	}
+
+	// 只通过认证头携带凭据时浏览器不会在跨站请求中自动附带，不存在CSRF
+	var cookies []*http.Cookie
+	if victim != nil {
+		if len(victim.Cookies) == 0 {
+			result.Metadata["message"] = "会话不使用Cookie，跨站请求无法携带凭据"
+			return result, nil
+		}
+		cookies = victim.Cookies
+	} else {
+		for name, value := range target.Cookies {
+			cookies = append(cookies, &http.Cookie{Name: name, Value: value})
+		}
+	}
+
+	// 重定向通常是提交成功后的跳转，与正常提交比较状态码和跳转地址
+	ctx = transport.WithoutRedirects(ctx)
+	if pageURL == "" {
+		pageURL = target.URL.String()
+	}
+
+	fmt.Printf("[INFO] CSRF检测器正在测试表单: %s %s\n", target.Method, target.URL.String())
+
+	tokenField := findTokenField(form, parts)
+	if tokenField != "" {
+		if fresh, ok := d.fetchToken(ctx, pageURL, tokenField, cookies); ok {
+			setField(parts, tokenField, fresh)
+		}
+	}
+
+	// 正常提交：同源Origin/Referer、有效令牌
+	valid, err := d.submit(ctx, target, parts, contentType, cookies, pageURL, originOf(target.URL))
+	if err != nil {
+		result.Metadata["message"] = "正常提交表单失败"
+		return result, nil
+	}
+	if valid.status >= 400 || rejectionPattern.MatchString(valid.body) {
+		result.Metadata["message"] = "正常提交表单被拒绝，无法作为比较基准"
+		d.finish(result, 1)
+		return result, nil
+	}
+
+	variants := []csrfVariant{{
+		Name:        "无令牌",
+		Technique:   "missing-token",
+		Description: "表单不包含反CSRF令牌",
+	}}
+	if tokenField != "" {
+		variants = d.tokenVariants(ctx, pageURL, tokenField, other)
+	}
+
+	tested := 1
+	var hits []csrfHit
+	for _, variant := range variants {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		forged := cloneParts(parts)
+		if tokenField != "" {
+			if variant.Token == nil {
+				forged = removeField(forged, tokenField)
+			} else {
+				setField(forged, tokenField, *variant.Token)
+			}
+		}
+		body, forgedType, err := buildFormBody(contentType, forged)
+		if err != nil {
+			continue
+		}
+
+		// 先以跨站Origin/Referer提交；被拒绝时再去掉这两个头部，检查服务端是否只在头部存在时才校验
+		for _, crossSite := range []bool{true, false} {
+			tested++
+			fmt.Printf("[DEBUG] 测试CSRF: %s (跨站头部: %v)\n", variant.Name, crossSite)
+
+			referer, origin := "", ""
+			if crossSite {
+				referer, origin = csrfAttackerOrigin+"/csrf.html", csrfAttackerOrigin
+			}
+			resp, err := d.submitRaw(ctx, target, body, forgedType, cookies, referer, origin)
+			if err != nil {
+				continue
+			}
+			if accepted(valid, resp) {
+				hits = append(hits, csrfHit{variant: variant, crossSite: crossSite, status: resp.status, parts: forged, contentType: forgedType})
+				break
+			}
+		}
+	}
+
+	if len(hits) > 0 {
+		vuln := d.buildVulnerability(target, form, hits, tokenField, cookies)
+		result.IsVulnerable = true
+		result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+		fmt.Printf("[SUCCESS] 发现CSRF: %s (%s)\n", target.URL.String(), hits[0].variant.Name)
+	}
+
+	d.finish(result, tested)
+	return result, nil
+}
+
+// tokenVariants 令牌篡改方式：去掉、置空、随机值、其他会话的令牌
+func (d *CSRFDetector) tokenVariants(ctx context.Context, pageURL, tokenField string, other *detector.Identity) []csrfVariant {
+	blank := ""
+	random := randomToken(32)
+	variants := []csrfVariant{
+		{Name: "去掉令牌字段", Technique: "token-removed", Description: "去掉令牌字段后服务端不再校验"},
+		{Name: "空令牌", Technique: "token-blank", Description: "令牌为空时服务端不再校验"},
+		{Name: "随机令牌", Technique: "token-forged", Description: "服务端接受任意令牌值"},
+	}
+	variants[1].Token = &blank
+	variants[2].Token = &random
+
+	// 其他会话的令牌：优先使用第二个登录身份，否则使用匿名会话
+	var otherCookies []*http.Cookie
+	source := "匿名会话"
+	if other != nil {
+		otherCookies = other.Cookies
+		source = "身份 " + other.Name
+	}
+	if foreign, ok := d.fetchToken(ctx, pageURL, tokenField, otherCookies); ok && foreign != "" {
+		variants = append(variants, csrfVariant{
+			Name:        source + "的令牌",
+			Technique:   "token-cross-session",
+			Description: "令牌未与会话绑定，攻击者可以用自己会话中取得的令牌伪造请求",
+			Token:       &foreign,
+		})
+	}
+	return variants
+}
+
+// fetchToken 以指定会话访问表单所在页面，取得令牌字段的当前值
+func (d *CSRFDetector) fetchToken(ctx context.Context, pageURL, tokenField string, cookies []*http.Cookie) (string, bool) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", false
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := d.GetHTTPClient().Do(req)
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+	body, err := transport.NewResponseHelper().ReadBody(resp)
+	if err != nil {
+		return "", false
+	}
+
+	for _, tag := range inputTagPattern.FindAllString(string(body), -1) {
+		name := inputNamePattern.FindStringSubmatch(tag)
+		if len(name) < 2 || name[1] != tokenField {
+			continue
+		}
+		if value := inputValPattern.FindStringSubmatch(tag); len(value) > 1 {
+			return html.UnescapeString(value[1]), true
+		}
+	}
+	return "", false
+}
+
+// submit 编码表单字段并提交
+func (d *CSRFDetector) submit(ctx context.Context, target *detector.ScanTarget, parts []*detector.MultipartPart, contentType string, cookies []*http.Cookie, referer, origin string) (*csrfResponse, error) {
+	body, bodyType, err := buildFormBody(contentType, parts)
+	if err != nil {
+		return nil, err
+	}
+	return d.submitRaw(ctx, target, body, bodyType, cookies, referer, origin)
+}
+
+// submitRaw 以受害者会话提交表单，referer/origin为空时不发送对应头部
+func (d *CSRFDetector) submitRaw(ctx context.Context, target *detector.ScanTarget, body, contentType string, cookies []*http.Cookie, referer, origin string) (*csrfResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, target.Method, target.URL.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := d.GetHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	return &csrfResponse{
+		status:   resp.StatusCode,
+		location: resp.Header.Get("Location"),
+		body:     string(respBody),
+	}, nil
+}
+
+// accepted 伪造请求的响应是否与正常提交一致：状态类别、跳转地址相同，没有新出现的拒绝提示，且正文长度接近
+func accepted(valid, resp *csrfResponse) bool {
+	if resp.status/100 != valid.status/100 || resp.status >= 400 {
+		return false
+	}
+	if valid.status/100 == 3 {
+		return sameLocation(valid.location, resp.location)
+	}
+	if rejectionPattern.MatchString(resp.body) {
+		return false
+	}
+
+	tolerance := len(valid.body) / 10
+	if tolerance < 100 {
+		tolerance = 100
+	}
+	diff := len(resp.body) - len(valid.body)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= tolerance
+}
+
+// sameLocation 跳转地址的路径是否相同，忽略查询参数中的随机值
+func sameLocation(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return ua.Host == ub.Host && ua.Path == ub.Path
+}
+
+// findTokenField 表单中的反CSRF令牌字段：名称匹配且为隐藏字段，并出现在请求体中
+func findTokenField(form *crawler.FormInfo, parts []*detector.MultipartPart) string {
+	inBody := make(map[string]bool)
+	for _, part := range parts {
+		inBody[part.Name] = true
+	}
+	for _, input := range form.Inputs {
+		if input.Type == "hidden" && inBody[input.Name] && tokenFieldPattern.MatchString(input.Name) {
+			return input.Name
+		}
+	}
+	return ""
+}
+
+// parseFormBody 把urlencoded或multipart请求体解析为有序的字段列表
+func parseFormBody(contentType, body string) ([]*detector.MultipartPart, error) {
+	if detector.IsMultipartContentType(contentType) {
+		return detector.ParseMultipartBody(contentType, body)
+	}
+
+	var parts []*detector.MultipartPart
+	for _, pair := range strings.Split(body, "&") {
+		if pair == "" {
+			continue
+		}
+		rawName, rawValue, _ := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(rawName)
+		if err != nil {
+			return nil, fmt.Errorf("解析表单数据失败: %w", err)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("解析表单数据失败: %w", err)
+		}
+		parts = append(parts, &detector.MultipartPart{Name: name, Data: []byte(value)})
+	}
+	return parts, nil
+}
+
+// buildFormBody 按原编码方式重建请求体，返回请求体和Content-Type
+func buildFormBody(contentType string, parts []*detector.MultipartPart) (string, string, error) {
+	if detector.IsMultipartContentType(contentType) {
+		return detector.BuildMultipartBody(parts)
+	}
+
+	pairs := make([]string, 0, len(parts))
+	for _, part := range parts {
+		pairs = append(pairs, url.QueryEscape(part.Name)+"="+url.QueryEscape(string(part.Data)))
+	}
+	return strings.Join(pairs, "&"), "application/x-www-form-urlencoded", nil
+}
+
+// cloneParts 复制字段列表，避免修改原表单
+func cloneParts(parts []*detector.MultipartPart) []*detector.MultipartPart {
+	cloned := make([]*detector.MultipartPart, 0, len(parts))
+	for _, part := range parts {
+		copied := *part
+		cloned = append(cloned, &copied)
+	}
+	return cloned
+}
+
+// setField 设置普通字段的值
+func setField(parts []*detector.MultipartPart, name, value string) {
+	for _, part := range parts {
+		if part.Name == name && !part.IsFile {
+			part.Data = []byte(value)
+		}
+	}
+}
+
+// removeField 去掉指定字段
+func removeField(parts []*detector.MultipartPart, name string) []*detector.MultipartPart {
+	kept := parts[:0]
+	for _, part := range parts {
+		if part.Name != name {
+			kept = append(kept, part)
+		}
+	}
+	return kept
+}
+
+// formKey 表单去重键：提交地址加字段名
+func formKey(target *detector.ScanTarget, parts []*detector.MultipartPart) string {
+	names := make([]string, 0, len(parts))
+	for _, part := range parts {
+		names = append(names, part.Name)
+	}
+	sort.Strings(names)
+	return target.Method + " " + target.URL.Scheme + "://" + target.URL.Host + target.URL.Path + " " + strings.Join(names, ",")
+}
+
+// originOf URL所在的源
+func originOf(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// randomToken 指定长度的随机十六进制串
+func randomToken(length int) string {
+	const hexDigits = "0123456789abcdef"
+	token := make([]byte, length)
+	for i := range token {
+		token[i] = hexDigits[rand.Intn(len(hexDigits))]
+	}
+	return string(token)
+}
+
+// sameSiteProtection 会话Cookie的SameSite属性：全部为Lax/Strict时跨站POST不会携带会话，返回true
+func sameSiteProtection(cookies []*http.Cookie) (string, bool) {
+	if len(cookies) == 0 {
+		return "none", false
+	}
+
+	var modes []string
+	protected := true
+	for _, cookie := range cookies {
+		mode := "unset"
+		switch cookie.SameSite {
+		case http.SameSiteStrictMode:
+			mode = "Strict"
+		case http.SameSiteLaxMode:
+			mode = "Lax"
+		case http.SameSiteNoneMode:
+			mode = "None"
+			protected = false
+		default:
+			protected = false
+		}
+		modes = append(modes, cookie.Name+"="+mode)
+	}
+	return strings.Join(modes, ", "), protected
+}
+
+// buildPoC 自动提交的攻击页面；只在缺失Referer时被接受的请求加上no-referrer策略
+func buildPoC(target *detector.ScanTarget, hit csrfHit) string {
+	var b strings.Builder
+	b.WriteString("<html>\n")
+	if !hit.crossSite {
+		b.WriteString("  <head><meta name=\"referrer\" content=\"no-referrer\"></head>\n")
+	}
+	b.WriteString("  <body>\n")
+
+	enctype := ""
+	if detector.IsMultipartContentType(hit.contentType) {
+		enctype = ` enctype="multipart/form-data"`
+	}
+	fmt.Fprintf(&b, "    <form action=\"%s\" method=\"%s\"%s>\n", html.EscapeString(target.URL.String()), html.EscapeString(target.Method), enctype)
+	for _, part := range hit.parts {
+		if part.IsFile {
+			continue
+		}
+		fmt.Fprintf(&b, "      <input type=\"hidden\" name=\"%s\" value=\"%s\" />\n", html.EscapeString(part.Name), html.EscapeString(string(part.Data)))
+	}
+	b.WriteString("    </form>\n")
+	b.WriteString("    <script>document.forms[0].submit();</script>\n")
+	b.WriteString("  </body>\n")
+	b.WriteString("</html>")
+	return b.String()
+}
+
+// finish 填写检测统计信息
+func (d *CSRFDetector) finish(result *detector.DetectionResult, tested int) {
+	result.Metadata["tested_parameters"] = tested
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+}
+
+// buildVulnerability 构建CSRF漏洞对象，优先以跨站头部被接受的请求生成PoC
+func (d *CSRFDetector) buildVulnerability(target *detector.ScanTarget, form *crawler.FormInfo, hits []csrfHit, tokenField string, cookies []*http.Cookie) *models.Vulnerability {
+	best := hits[0]
+	for _, hit := range hits {
+		if hit.crossSite {
+			best = hit
+			break
+		}
+	}
+
+	sameSite, protected := sameSiteProtection(cookies)
+
+	severity, cvss := models.SeverityMedium, 6.5
+	if protected || form.IsLogin {
+		severity, cvss = models.SeverityLow, 4.3
+	}
+
+	title := "Cross-Site Request Forgery"
+	if tokenField == "" {
+		title += " (Missing Token)"
+	} else {
+		title += " (Token Not Validated)"
+	}
+
+	description := best.variant.Description + "，攻击者页面可以受害者身份提交该表单"
+	if !best.crossSite {
+		description = best.variant.Description + "；跨站Origin/Referer会被拒绝，但不发送这两个头部时服务端放行，攻击者页面可通过no-referrer策略省略Referer"
+	}
+	if protected {
+		description += "。会话Cookie设置了SameSite=Lax/Strict，现代浏览器的跨站POST不会携带会话，可利用性较低"
+	}
+	if form.IsLogin {
+		description += "。该表单为登录表单，可用于登录CSRF（让受害者登录到攻击者账户）"
+	}
+
+	var evidence []string
+	for _, hit := range hits {
+		headers := "跨站Origin/Referer"
+		if !hit.crossSite {
+			headers = "无Origin/Referer"
+		}
+		evidence = append(evidence, fmt.Sprintf("%s + %s: HTTP %d，与正常提交一致", hit.variant.Name, headers, hit.status))
+	}
+	evidence = append(evidence, "会话Cookie SameSite: "+sameSite)
+	poc := buildPoC(target, best)
+	evidence = append(evidence, "", "PoC:", poc)
+
+	payload := best.variant.Name
+	if best.crossSite {
+		payload += "\nOrigin: " + csrfAttackerOrigin + "\nReferer: " + csrfAttackerOrigin + "/csrf.html"
+	} else {
+		payload += "\n(不发送Origin/Referer)"
+	}
+
+	vuln := models.NewVulnerabilityBuilder().
+		WithType(models.VulnCSRF).
+		WithCategory(models.CategoryCSRF).
+		WithSeverity(severity).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithParameter(tokenField, models.PositionPOST).
+		WithPayload(payload).
+		WithEvidence(strings.Join(evidence, "\n")).
+		WithConfidence(0.85).
+		WithPlugin(d.Name()).
+		WithCWE("CWE-352").
+		WithCVSS(cvss).
+		WithSolution("为所有改变状态的请求使用与会话绑定、不可预测的反CSRF令牌并在服务端严格校验（字段缺失或为空同样拒绝）；校验Origin/Referer且在两者缺失时拒绝；会话Cookie设置SameSite=Lax或Strict").
+		WithReferences([]string{
+			"https://owasp.org/www-community/attacks/csrf",
+			"https://cheatsheetseries.owasp.org/cheatsheets/Cross-Site_Request_Forgery_Prevention_Cheat_Sheet.html",
+			"https://portswigger.net/web-security/csrf",
+		}).
+		Build()
+
+	vuln.Metadata["csrf_technique"] = best.variant.Technique
+	vuln.Metadata["cross_site_headers"] = fmt.Sprint(best.crossSite)
+	vuln.Metadata["samesite"] = sameSite
+	vuln.Metadata["poc_html"] = poc
+	return vuln
+}
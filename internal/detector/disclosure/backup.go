@@ -0,0 +1,472 @@
+package disclosure
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// maxProbeBody 探测响应最多读取的字节数，备份压缩包可能很大
+const maxProbeBody = 64 * 1024
+
+// BackupFileDetector 备份与敏感文件发现插件 - 由页面URL推导备份文件名并检查常见敏感文件，
+// 对每个目录和扩展名做soft-404指纹，避免把统一返回200的兜底页面误判为文件存在
+type BackupFileDetector struct {
+	*detector.BasePlugin
+	responseAnalyzer *detector.ResponseAnalyzer
+	sessionCookies   []*http.Cookie
+	outputDir        string
+
+	mutex        sync.Mutex
+	tested       map[string]bool     // 已探测的候选URL
+	hostsChecked map[string]bool     // 已检查敏感文件清单的主机
+	fingerprints map[string]*soft404 // 目录+扩展名 -> 不存在文件的响应指纹
+}
+
+// FileCandidate 候选文件
+type FileCandidate struct {
+	Path        string
+	Description string
+	VulnType    models.VulnType
+	Severity    models.Severity
+	Validator   func(body []byte) bool // 为空时只依据soft-404指纹判断
+	Repository  string                 // 版本控制元数据（git/svn），确认后还原仓库
+}
+
+// soft404 请求不存在文件时的响应指纹
+type soft404 struct {
+	status int
+	body   []byte
+}
+
+// NewBackupFileDetector 创建备份文件发现插件
+func NewBackupFileDetector(httpClient transport.HTTPClient) *BackupFileDetector {
+	base := detector.NewBasePlugin(
+		"backup-files",
+		detector.PluginTypeActive,
+		models.CategoryDisclosure,
+		models.SeverityMedium,
+	)
+
+	base.SetDescription("发现遗留的备份文件（.bak、~、.swp、.old、目录压缩包）和敏感文件（.git、.env、.DS_Store、web.config、phpinfo）")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &BackupFileDetector{
+		BasePlugin:       base,
+		responseAnalyzer: detector.NewResponseAnalyzer(),
+		tested:           make(map[string]bool),
+		hostsChecked:     make(map[string]bool),
+		fingerprints:     make(map[string]*soft404),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (b *BackupFileDetector) SetSessionCookies(cookies []*http.Cookie) {
+	b.sessionCookies = cookies
+}
+
+// SetOutputDir 设置还原仓库的写入目录
+func (b *BackupFileDetector) SetOutputDir(dir string) {
+	b.outputDir = dir
+}
+
+// Execute 探测由目标URL推导的候选文件，每个主机首次出现时检查敏感文件清单
+func (b *BackupFileDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	origin := &url.URL{Scheme: target.URL.Scheme, Host: target.URL.Host}
+
+	candidates := b.deriveCandidates(target.URL.Path, target.URL.Hostname())
+	b.mutex.Lock()
+	if !b.hostsChecked[origin.String()] {
+		b.hostsChecked[origin.String()] = true
+		candidates = append(candidates, sensitiveFiles()...)
+	}
+	b.mutex.Unlock()
+
+	// 跟随重定向会把跳转到登录页、首页的响应当作文件内容
+	ctx = transport.WithoutRedirects(ctx)
+
+	tested := 0
+	for _, candidate := range candidates {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		candidateURL := origin.ResolveReference(&url.URL{Path: candidate.Path})
+		if !b.markTested(candidateURL.String()) {
+			continue
+		}
+		tested++
+
+		if vuln := b.probe(ctx, target, candidateURL, candidate); vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现%s: %s\n", candidate.Description, candidateURL)
+		}
+	}
+
+	result.Metadata["tested_parameters"] = tested
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// probe 请求候选文件，排除soft-404后确认存在
+func (b *BackupFileDetector) probe(ctx context.Context, target *detector.ScanTarget, candidateURL *url.URL, candidate FileCandidate) *models.Vulnerability {
+	fmt.Printf("[DEBUG] 探测文件: %s\n", candidateURL)
+
+	status, body, err := b.fetch(ctx, candidateURL.String())
+	if err != nil || (status != http.StatusOK && status != http.StatusPartialContent) {
+		return nil
+	}
+
+	dir, name := path.Split(candidateURL.Path)
+	fp := b.fingerprint(ctx, candidateURL, dir, name)
+
+	if candidate.Validator != nil {
+		if !candidate.Validator(body) {
+			return nil
+		}
+		// 兜底页面本身就满足特征时无法区分
+		if fp != nil && fp.status == status && candidate.Validator(fp.body) {
+			return nil
+		}
+	} else {
+		if fp != nil && b.isSoft404(fp, status, body, name) {
+			return nil
+		}
+		// 服务器忽略后缀、把请求交给原页面处理（如URL重写）时，响应与原页面相同
+		if len(target.BaselineBody) > 0 && b.similar(target.BaselineBody, body) {
+			return nil
+		}
+	}
+
+	vuln := b.buildVulnerability(target, candidateURL.String(), candidate, status, body)
+	if candidate.Repository != "" {
+		b.dumpRepository(ctx, candidateURL, candidate.Repository, vuln)
+	}
+	return vuln
+}
+
+// dumpRepository 还原暴露的仓库，将文件清单和敏感信息附加到漏洞证据
+func (b *BackupFileDetector) dumpRepository(ctx context.Context, metaURL *url.URL, kind string, vuln *models.Vulnerability) {
+	dumper := NewRepositoryDumper(b.GetHTTPClient(), b.sessionCookies, b.outputDir)
+	baseURL := metaURL.ResolveReference(&url.URL{Path: "./"})
+
+	fmt.Printf("[INFO] 正在还原暴露的%s仓库: %s\n", kind, baseURL)
+
+	var dump *RepositoryDump
+	var err error
+	switch kind {
+	case "git":
+		dump, err = dumper.DumpGit(ctx, baseURL)
+	case "svn":
+		dump, err = dumper.DumpSVN(ctx, baseURL)
+	}
+	if err != nil {
+		fmt.Printf("[DEBUG] 还原%s仓库失败: %v\n", kind, err)
+		return
+	}
+
+	fmt.Printf("[INFO] %s仓库还原完成: %d 个文件, %d 处疑似敏感信息\n", kind, len(dump.Files), len(dump.Secrets))
+
+	vuln.Evidence += "\n" + dump.Summary(100)
+	vuln.Metadata["repository_type"] = kind
+	vuln.Metadata["reconstructed_files"] = fmt.Sprint(len(dump.Files))
+	vuln.Metadata["secrets_found"] = fmt.Sprint(len(dump.Secrets))
+	if dump.OutputPath != "" && len(dump.Files) > 0 {
+		vuln.Metadata["output_path"] = dump.OutputPath
+	}
+	for key, value := range dump.Info {
+		vuln.Metadata["repository_"+key] = value
+	}
+	if len(dump.Secrets) > 0 {
+		vuln.Severity = models.SeverityCritical
+		vuln.Description += "，还原出的源码中包含疑似凭据"
+	}
+}
+
+// fingerprint 获取目录+扩展名对应的soft-404指纹，首次使用时请求一个随机文件名
+func (b *BackupFileDetector) fingerprint(ctx context.Context, candidateURL *url.URL, dir, name string) *soft404 {
+	suffix := path.Ext(name)
+	if strings.HasSuffix(name, "~") {
+		suffix = "~"
+	}
+	hidden := strings.HasPrefix(name, ".")
+	key := fmt.Sprintf("%s://%s%s|%s|%v", candidateURL.Scheme, candidateURL.Host, dir, suffix, hidden)
+
+	b.mutex.Lock()
+	fp, ok := b.fingerprints[key]
+	b.mutex.Unlock()
+	if ok {
+		return fp
+	}
+
+	probeName := fmt.Sprintf("drs%08x", rand.Uint32())
+	if hidden {
+		probeName = "." + probeName
+	}
+	if suffix != "~" && suffix != "" && !strings.HasSuffix(probeName, suffix) {
+		probeName += suffix
+	} else if suffix == "~" {
+		probeName += "~"
+	}
+
+	probeURL := *candidateURL
+	probeURL.Path = dir + probeName
+	status, body, err := b.fetch(ctx, probeURL.String())
+	if err == nil {
+		// 响应中可能回显请求的文件名，去掉后再比较
+		fp = &soft404{status: status, body: bytes.ReplaceAll(body, []byte(probeName), nil)}
+	}
+
+	b.mutex.Lock()
+	b.fingerprints[key] = fp
+	b.mutex.Unlock()
+	return fp
+}
+
+// isSoft404 响应与不存在文件的指纹一致
+func (b *BackupFileDetector) isSoft404(fp *soft404, status int, body []byte, name string) bool {
+	if fp.status != status {
+		return false
+	}
+	return b.similar(fp.body, bytes.ReplaceAll(body, []byte(name), nil))
+}
+
+// similar 长度接近且内容相似
+func (b *BackupFileDetector) similar(a, c []byte) bool {
+	lenDiff := len(a) - len(c)
+	if lenDiff < 0 {
+		lenDiff = -lenDiff
+	}
+	if lenDiff > 50 && float64(lenDiff) > 0.1*float64(len(a)) {
+		return false
+	}
+	similarity, _ := b.responseAnalyzer.AnalyzeDifference(a, c)
+	return similarity > 0.8 || lenDiff <= 10
+}
+
+// fetch 携带会话发送GET请求，响应体最多读取maxProbeBody字节
+func (b *BackupFileDetector) fetch(ctx context.Context, targetURL string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	for _, cookie := range b.sessionCookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := b.GetHTTPClient().Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	var reader io.Reader = resp.Body
+	if strings.Contains(strings.ToLower(resp.Header.Get("Content-Encoding")), "gzip") {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return resp.StatusCode, nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	body, err := io.ReadAll(io.LimitReader(reader, maxProbeBody))
+	if err != nil && len(body) == 0 {
+		return resp.StatusCode, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+// markTested 标记候选URL已探测，已探测过返回false
+func (b *BackupFileDetector) markTested(candidateURL string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.tested[candidateURL] {
+		return false
+	}
+	b.tested[candidateURL] = true
+	return true
+}
+
+// deriveCandidates 由页面路径推导备份文件名：文件本身的编辑器/手工备份，以及各级目录的压缩包
+func (b *BackupFileDetector) deriveCandidates(pagePath, hostname string) []FileCandidate {
+	var candidates []FileCandidate
+
+	dir, name := path.Split(pagePath)
+	if dir == "" {
+		dir = "/"
+	}
+
+	if name != "" {
+		backup := func(p, description string, validator func([]byte) bool) {
+			candidates = append(candidates, FileCandidate{
+				Path: dir + p, Description: description, VulnType: models.VulnBackupFiles,
+				Severity: models.SeverityHigh, Validator: validator,
+			})
+		}
+		backup(name+".bak", "源文件备份", nil)
+		backup(name+"~", "编辑器备份文件", nil)
+		backup(name+".old", "源文件备份", nil)
+		backup(name+".orig", "源文件备份", nil)
+		backup(name+".save", "编辑器备份文件", nil)
+		backup("."+name+".swp", "Vim交换文件", hasPrefix("b0VIM"))
+		if ext := path.Ext(name); ext != "" {
+			backup(strings.TrimSuffix(name, ext)+".bak", "源文件备份", nil)
+		}
+	}
+
+	// 逐级目录的压缩包：/a/b/ -> /a/b.zip、/a.zip
+	for current := strings.TrimSuffix(dir, "/"); current != ""; current = path.Dir(current) {
+		if current == "/" || current == "." {
+			break
+		}
+		candidates = append(candidates, archiveCandidates(current)...)
+	}
+
+	// 站点根目录常见的整站备份
+	for _, name := range []string{hostname, "www", "backup", "site", "web", "wwwroot"} {
+		if name != "" {
+			candidates = append(candidates, archiveCandidates("/"+name)...)
+		}
+	}
+
+	return candidates
+}
+
+// archiveCandidates 目录对应的压缩包候选，按文件头确认
+func archiveCandidates(base string) []FileCandidate {
+	archive := func(ext string, validator func([]byte) bool) FileCandidate {
+		return FileCandidate{
+			Path: base + ext, Description: "目录备份压缩包", VulnType: models.VulnBackupFiles,
+			Severity: models.SeverityHigh, Validator: validator,
+		}
+	}
+	return []FileCandidate{
+		archive(".zip", hasPrefix("PK\x03\x04")),
+		archive(".tar.gz", hasPrefix("\x1f\x8b")),
+		archive(".tgz", hasPrefix("\x1f\x8b")),
+		archive(".rar", hasPrefix("Rar!\x1a\x07")),
+		archive(".7z", hasPrefix("7z\xbc\xaf\x27\x1c")),
+	}
+}
+
+// sensitiveFiles 站点根目录的敏感文件清单
+func sensitiveFiles() []FileCandidate {
+	sqlDump := matches(`(?i)(CREATE TABLE|INSERT INTO|-- MySQL dump|-- PostgreSQL database dump)`)
+
+	return []FileCandidate{
+		{Path: "/.git/HEAD", Description: "Git仓库元数据", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityHigh,
+			Validator: matches(`^(ref: refs/[\w./-]+|[0-9a-f]{40})\s*$`), Repository: "git"},
+		{Path: "/.git/config", Description: "Git仓库配置", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityHigh,
+			Validator: matches(`(?m)^\[core\]`)},
+		{Path: "/.svn/entries", Description: "SVN工作副本元数据", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityHigh,
+			Validator: matches(`^(\d+\s*\n|<\?xml[^>]*>\s*<wc-entries)`)},
+		{Path: "/.svn/wc.db", Description: "SVN工作副本数据库", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityHigh,
+			Validator: hasPrefix("SQLite format 3\x00"), Repository: "svn"},
+		{Path: "/.env", Description: "环境变量配置文件", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityHigh,
+			Validator: matches(`(?m)^[A-Z][A-Z0-9_]*=\S*`)},
+		{Path: "/.DS_Store", Description: "macOS目录元数据文件", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityLow,
+			Validator: hasPrefix("\x00\x00\x00\x01Bud1")},
+		{Path: "/web.config", Description: "IIS配置文件", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityMedium,
+			Validator: matches(`(?is)<configuration[\s>].*</configuration>`)},
+		{Path: "/.htaccess", Description: "Apache目录配置文件", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityLow,
+			Validator: matches(`(?mi)^\s*(RewriteEngine|RewriteRule|Options|AuthType|Require|Deny from|Allow from)\b`)},
+		{Path: "/phpinfo.php", Description: "phpinfo页面", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityMedium,
+			Validator: matches(`(?i)<title>phpinfo\(\)</title>|PHP Version \d`)},
+		{Path: "/info.php", Description: "phpinfo页面", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityMedium,
+			Validator: matches(`(?i)<title>phpinfo\(\)</title>|PHP Version \d`)},
+		{Path: "/server-status", Description: "Apache状态页", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityLow,
+			Validator: matches(`Apache Server Status for`)},
+		{Path: "/config.php.bak", Description: "配置文件备份", VulnType: models.VulnBackupFiles, Severity: models.SeverityHigh,
+			Validator: matches(`<\?php`)},
+		{Path: "/wp-config.php.bak", Description: "WordPress配置文件备份", VulnType: models.VulnBackupFiles, Severity: models.SeverityHigh,
+			Validator: matches(`DB_PASSWORD`)},
+		{Path: "/backup.sql", Description: "数据库导出文件", VulnType: models.VulnBackupFiles, Severity: models.SeverityHigh, Validator: sqlDump},
+		{Path: "/dump.sql", Description: "数据库导出文件", VulnType: models.VulnBackupFiles, Severity: models.SeverityHigh, Validator: sqlDump},
+		{Path: "/.idea/workspace.xml", Description: "IDE项目配置", VulnType: models.VulnInfoDisclosure, Severity: models.SeverityLow,
+			Validator: matches(`<project version=`)},
+	}
+}
+
+// hasPrefix 按文件头确认
+func hasPrefix(magic string) func([]byte) bool {
+	return func(body []byte) bool {
+		return bytes.HasPrefix(body, []byte(magic))
+	}
+}
+
+// matches 按内容特征确认
+func matches(pattern string) func([]byte) bool {
+	re := regexp.MustCompile(pattern)
+	return func(body []byte) bool {
+		return re.Match(body)
+	}
+}
+
+// buildVulnerability 构建文件泄露漏洞对象
+func (b *BackupFileDetector) buildVulnerability(target *detector.ScanTarget, fileURL string, candidate FileCandidate, status int, body []byte) *models.Vulnerability {
+	confidence := 0.95
+	if candidate.Validator == nil {
+		// 无内容特征，仅凭与soft-404指纹不同判断
+		confidence = 0.75
+	}
+
+	vuln := models.NewVulnerabilityBuilder().
+		WithType(candidate.VulnType).
+		WithCategory(models.CategoryDisclosure).
+		WithSeverity(candidate.Severity).
+		WithTitle(fmt.Sprintf("Sensitive File Exposed (%s)", path.Base(candidate.Path))).
+		WithDescription(fmt.Sprintf("可直接下载%s %s，可能泄露源代码、配置或凭据", candidate.Description, fileURL)).
+		WithURL(fileURL).
+		WithMethod("GET").
+		WithPayload(candidate.Path).
+		WithEvidence(fmt.Sprintf("HTTP %d, 内容前缀: %q", status, preview(body))).
+		WithConfidence(confidence).
+		WithPlugin(b.Name()).
+		WithCWE("CWE-530").
+		WithSolution("从Web目录中删除备份文件、版本控制目录和调试页面，并在服务器配置中禁止访问以点开头的文件和备份扩展名").
+		WithReferences([]string{
+			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/02-Configuration_and_Deployment_Management_Testing/04-Review_Old_Backup_and_Unreferenced_Files_for_Sensitive_Information",
+		}).
+		Build()
+
+	vuln.Metadata["file_type"] = candidate.Description
+	vuln.Metadata["source_page"] = target.URL.String()
+	return vuln
+}
+
+// preview 截取响应开头作为证据
+func preview(body []byte) string {
+	if len(body) > 80 {
+		body = body[:80]
+	}
+	return string(body)
+}
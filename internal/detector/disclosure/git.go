@@ -0,0 +1,630 @@
+package disclosure
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 通过HTTP读取暴露的.git目录：解析HEAD、refs、packed-refs、logs/HEAD得到提交，
+// 从松散对象和pack（按.idx定位）中读取commit/tree/blob，结合.git/index还原工作区文件
+
+var shaPattern = regexp.MustCompile(`\b[0-9a-f]{40}\b`)
+
+// gitObject 解析后的git对象
+type gitObject struct {
+	kind string // commit / tree / blob / tag
+	data []byte
+}
+
+// gitPack 已下载的pack文件及其索引
+type gitPack struct {
+	name    string
+	data    []byte
+	offsets map[string]int64
+}
+
+// gitRepo 一次git仓库还原的状态
+type gitRepo struct {
+	dumper  *RepositoryDumper
+	baseURL *url.URL
+	packs   []*gitPack
+	cache   map[string]*gitObject
+	failed  map[string]bool
+}
+
+// DumpGit 还原baseURL（以/.git/结尾）指向的git仓库
+func (d *RepositoryDumper) DumpGit(ctx context.Context, baseURL *url.URL) (*RepositoryDump, error) {
+	repo := &gitRepo{
+		dumper:  d,
+		baseURL: baseURL,
+		cache:   make(map[string]*gitObject),
+		failed:  make(map[string]bool),
+	}
+	dump := d.newDump("git", baseURL)
+
+	head, _, err := d.fetch(ctx, repo.url("HEAD"), 4096)
+	if err != nil {
+		return nil, fmt.Errorf("读取HEAD失败: %w", err)
+	}
+
+	commits := repo.collectCommits(ctx, strings.TrimSpace(string(head)), dump)
+	if config, _, err := d.fetch(ctx, repo.url("config"), 64*1024); err == nil {
+		if match := regexp.MustCompile(`(?m)^\s*url\s*=\s*(\S+)`).FindSubmatch(config); match != nil {
+			remote := string(match[1])
+			if cred := credentialURLPattern.FindStringSubmatch(remote); cred != nil {
+				remote = maskSecret(remote, cred[1])
+			}
+			dump.Info["remote_url"] = remote
+		}
+		dump.Secrets = append(dump.Secrets, scanSecrets(".git/config", config, maxSecrets-len(dump.Secrets))...)
+	}
+
+	repo.loadPacks(ctx)
+	dump.Info["packs"] = strconv.Itoa(len(repo.packs))
+
+	// 工作区文件：优先使用.git/index，再用各提交的tree补充index中没有的路径
+	files := make(map[string]string)
+	var order []string
+	addEntry := func(filePath, sha string) {
+		if _, ok := files[filePath]; !ok && len(files) < maxRepoFiles {
+			files[filePath] = sha
+			order = append(order, filePath)
+		}
+	}
+
+	if index, truncated, err := d.fetch(ctx, repo.url("index"), maxRepoPackSize); err == nil && !truncated {
+		entries, err := parseGitIndex(index)
+		if err != nil {
+			fmt.Printf("[DEBUG] 解析.git/index失败: %v\n", err)
+		}
+		for _, entry := range entries {
+			addEntry(entry.path, entry.sha)
+		}
+		dump.Info["index_entries"] = strconv.Itoa(len(entries))
+	}
+
+	for _, commit := range commits {
+		if ctx.Err() != nil {
+			break
+		}
+		obj := repo.object(ctx, commit)
+		if obj == nil || obj.kind != "commit" {
+			continue
+		}
+		if tree := commitField(obj.data, "tree"); tree != "" {
+			repo.walkTree(ctx, tree, "", addEntry, 0)
+		}
+	}
+	dump.Info["commits"] = strconv.Itoa(len(commits))
+
+	for _, filePath := range order {
+		if ctx.Err() != nil {
+			break
+		}
+		obj := repo.object(ctx, files[filePath])
+		if obj == nil || obj.kind != "blob" {
+			dump.Missing++
+			continue
+		}
+		d.addFile(dump, filePath, obj.data)
+	}
+
+	return d.finish(dump), nil
+}
+
+// url .git目录下文件的完整URL
+func (r *gitRepo) url(name string) string {
+	return r.baseURL.ResolveReference(&url.URL{Path: name}).String()
+}
+
+// collectCommits 收集HEAD、分支、packed-refs和reflog中出现的提交，HEAD指向的提交排在最前
+func (r *gitRepo) collectCommits(ctx context.Context, head string, dump *RepositoryDump) []string {
+	var commits []string
+	seen := make(map[string]bool)
+	add := func(sha string) {
+		if sha != "" && sha != strings.Repeat("0", 40) && !seen[sha] {
+			seen[sha] = true
+			commits = append(commits, sha)
+		}
+	}
+
+	packedRefs := make(map[string]string)
+	if data, _, err := r.dumper.fetch(ctx, r.url("packed-refs"), 1024*1024); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && shaPattern.MatchString(fields[0]) {
+				packedRefs[fields[1]] = fields[0]
+			}
+		}
+	}
+
+	resolve := func(ref string) string {
+		if data, _, err := r.dumper.fetch(ctx, r.url(ref), 4096); err == nil {
+			if sha := shaPattern.FindString(string(data)); sha != "" {
+				return sha
+			}
+		}
+		return packedRefs[ref]
+	}
+
+	if strings.HasPrefix(head, "ref: ") {
+		ref := strings.TrimSpace(strings.TrimPrefix(head, "ref: "))
+		dump.Info["branch"] = strings.TrimPrefix(ref, "refs/heads/")
+		add(resolve(ref))
+	} else {
+		add(shaPattern.FindString(head))
+	}
+
+	for _, ref := range []string{"refs/heads/master", "refs/heads/main", "refs/heads/develop", "refs/heads/dev", "ORIG_HEAD", "FETCH_HEAD"} {
+		add(resolve(ref))
+	}
+	for _, sha := range packedRefs {
+		add(sha)
+	}
+
+	// reflog每行为 旧提交 新提交 ...，倒序加入使较新的提交优先
+	if data, _, err := r.dumper.fetch(ctx, r.url("logs/HEAD"), 1024*1024); err == nil {
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		for i := len(lines) - 1; i >= 0; i-- {
+			fields := strings.Fields(lines[i])
+			if len(fields) >= 2 {
+				add(shaPattern.FindString(fields[1]))
+				add(shaPattern.FindString(fields[0]))
+			}
+		}
+	}
+
+	return commits
+}
+
+// loadPacks 按objects/info/packs下载pack及其.idx
+func (r *gitRepo) loadPacks(ctx context.Context) {
+	data, _, err := r.dumper.fetch(ctx, r.url("objects/info/packs"), 64*1024)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "P" || !strings.HasSuffix(fields[1], ".pack") {
+			continue
+		}
+		name := strings.TrimSuffix(fields[1], ".pack")
+		if strings.ContainsAny(name, "/\\") {
+			continue
+		}
+
+		idx, _, err := r.dumper.fetch(ctx, r.url("objects/pack/"+name+".idx"), maxRepoPackSize)
+		if err != nil {
+			continue
+		}
+		offsets, err := parsePackIndex(idx)
+		if err != nil {
+			fmt.Printf("[DEBUG] 解析%s.idx失败: %v\n", name, err)
+			continue
+		}
+
+		// 超出大小限制的pack只能读取前部的对象
+		packData, _, err := r.dumper.fetch(ctx, r.url("objects/pack/"+name+".pack"), maxRepoPackSize)
+		if err != nil || !bytes.HasPrefix(packData, []byte("PACK")) {
+			continue
+		}
+
+		fmt.Printf("[DEBUG] 加载pack: %s (%d个对象)\n", name, len(offsets))
+		r.packs = append(r.packs, &gitPack{name: name, data: packData, offsets: offsets})
+	}
+}
+
+// object 读取对象，依次查找缓存、pack和松散对象
+func (r *gitRepo) object(ctx context.Context, sha string) *gitObject {
+	return r.resolveObject(ctx, sha, 0)
+}
+
+// resolveObject 读取对象，depth为REF_DELTA链的当前深度
+func (r *gitRepo) resolveObject(ctx context.Context, sha string, depth int) *gitObject {
+	if obj, ok := r.cache[sha]; ok {
+		return obj
+	}
+	if r.failed[sha] || len(r.cache) >= maxRepoFiles*4 {
+		return nil
+	}
+
+	// 解析期间先标记为失败，REF_DELTA以自身或循环引用作为基对象时直接失败，避免无限递归
+	r.failed[sha] = true
+
+	var obj *gitObject
+	for _, pack := range r.packs {
+		if offset, ok := pack.offsets[sha]; ok {
+			kind, data, err := r.readPackObject(ctx, pack, offset, depth)
+			if err == nil {
+				obj = &gitObject{kind: kind, data: data}
+				break
+			}
+		}
+	}
+
+	if obj == nil {
+		obj = r.looseObject(ctx, sha)
+	}
+	if obj == nil {
+		return nil
+	}
+
+	delete(r.failed, sha)
+	r.cache[sha] = obj
+	return obj
+}
+
+// looseObject 下载并解压objects/xx/yyyy
+func (r *gitRepo) looseObject(ctx context.Context, sha string) *gitObject {
+	data, truncated, err := r.dumper.fetch(ctx, r.url("objects/"+sha[:2]+"/"+sha[2:]), maxRepoFileSize)
+	if err != nil || truncated {
+		return nil
+	}
+
+	raw, err := inflate(data, maxRepoFileSize+64)
+	if err != nil {
+		return nil
+	}
+	nul := bytes.IndexByte(raw, 0)
+	if nul < 0 {
+		return nil
+	}
+	header := strings.Fields(string(raw[:nul]))
+	if len(header) != 2 {
+		return nil
+	}
+	return &gitObject{kind: header[0], data: raw[nul+1:]}
+}
+
+// walkTree 递归遍历tree，跳过子模块和符号链接
+func (r *gitRepo) walkTree(ctx context.Context, sha, prefix string, add func(filePath, sha string), depth int) {
+	if depth > 32 || ctx.Err() != nil {
+		return
+	}
+	obj := r.object(ctx, sha)
+	if obj == nil || obj.kind != "tree" {
+		return
+	}
+
+	data := obj.data
+	for len(data) > 0 {
+		space := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if space < 0 || nul < space || nul+21 > len(data) {
+			return
+		}
+		mode := string(data[:space])
+		name := string(data[space+1 : nul])
+		entrySHA := hex.EncodeToString(data[nul+1 : nul+21])
+		data = data[nul+21:]
+
+		switch mode {
+		case "40000", "040000":
+			r.walkTree(ctx, entrySHA, prefix+name+"/", add, depth+1)
+		case "160000", "120000":
+		default:
+			add(prefix+name, entrySHA)
+		}
+	}
+}
+
+// readPackObject 读取pack中offset处的对象，解析OFS_DELTA/REF_DELTA
+func (r *gitRepo) readPackObject(ctx context.Context, pack *gitPack, offset int64, depth int) (string, []byte, error) {
+	if depth > 50 {
+		return "", nil, fmt.Errorf("delta链过长")
+	}
+	data := pack.data
+	if offset < 12 || offset >= int64(len(data)) {
+		return "", nil, fmt.Errorf("偏移超出已下载范围")
+	}
+
+	pos := offset
+	c := data[pos]
+	pos++
+	objType := (c >> 4) & 7
+	size := int64(c & 0x0f)
+	for shift := uint(4); c&0x80 != 0; shift += 7 {
+		if pos >= int64(len(data)) {
+			return "", nil, fmt.Errorf("对象头不完整")
+		}
+		if shift >= 63 {
+			return "", nil, fmt.Errorf("对象长度无效")
+		}
+		c = data[pos]
+		pos++
+		size |= int64(c&0x7f) << shift
+	}
+	if size < 0 || size > maxRepoPackSize {
+		return "", nil, fmt.Errorf("对象过大")
+	}
+
+	var baseKind string
+	var baseData []byte
+	switch objType {
+	case 1, 2, 3, 4:
+	case 6: // OFS_DELTA：基对象位于当前对象之前的相对偏移
+		if pos >= int64(len(data)) {
+			return "", nil, fmt.Errorf("对象头不完整")
+		}
+		rel, n := readOffsetVarint(data[pos:])
+		if n == 0 || int64(rel) > offset {
+			return "", nil, fmt.Errorf("对象头不完整")
+		}
+		pos += int64(n)
+		var err error
+		baseKind, baseData, err = r.readPackObject(ctx, pack, offset-int64(rel), depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+	case 7: // REF_DELTA：基对象以SHA-1引用
+		if pos+20 > int64(len(data)) {
+			return "", nil, fmt.Errorf("对象头不完整")
+		}
+		base := r.resolveObject(ctx, hex.EncodeToString(data[pos:pos+20]), depth+1)
+		pos += 20
+		if base == nil {
+			return "", nil, fmt.Errorf("基对象不可用")
+		}
+		baseKind, baseData = base.kind, base.data
+	default:
+		return "", nil, fmt.Errorf("未知对象类型 %d", objType)
+	}
+
+	content, err := inflate(data[pos:], size)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if baseData == nil {
+		kinds := map[byte]string{1: "commit", 2: "tree", 3: "blob", 4: "tag"}
+		return kinds[objType], content, nil
+	}
+
+	patched, err := applyDelta(baseData, content)
+	if err != nil {
+		return "", nil, err
+	}
+	return baseKind, patched, nil
+}
+
+// inflate 解压zlib数据，最多读取limit字节
+func inflate(data []byte, limit int64) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(io.LimitReader(reader, limit))
+}
+
+// applyDelta 将git delta应用到基对象
+func applyDelta(base, delta []byte) ([]byte, error) {
+	pos := 0
+	// readSize 小端变长整数，超过63位（恶意构造的头部）时返回-1
+	readSize := func() int {
+		size, shift := 0, uint(0)
+		for pos < len(delta) {
+			if shift >= 63 {
+				return -1
+			}
+			c := delta[pos]
+			pos++
+			size |= int(c&0x7f) << shift
+			shift += 7
+			if c&0x80 == 0 {
+				break
+			}
+		}
+		return size
+	}
+
+	if readSize() != len(base) {
+		return nil, fmt.Errorf("delta基对象长度不符")
+	}
+	targetSize := readSize()
+	if targetSize < 0 || targetSize > maxRepoPackSize {
+		return nil, fmt.Errorf("delta结果长度无效")
+	}
+
+	out := make([]byte, 0, targetSize)
+	for pos < len(delta) {
+		cmd := delta[pos]
+		pos++
+
+		if cmd&0x80 != 0 {
+			// 复制：低4位标记偏移字节，5-7位标记长度字节
+			var offset, length int
+			for i := uint(0); i < 4; i++ {
+				if cmd&(1<<i) != 0 {
+					if pos >= len(delta) {
+						return nil, fmt.Errorf("delta截断")
+					}
+					offset |= int(delta[pos]) << (8 * i)
+					pos++
+				}
+			}
+			for i := uint(0); i < 3; i++ {
+				if cmd&(0x10<<i) != 0 {
+					if pos >= len(delta) {
+						return nil, fmt.Errorf("delta截断")
+					}
+					length |= int(delta[pos]) << (8 * i)
+					pos++
+				}
+			}
+			if length == 0 {
+				length = 0x10000
+			}
+			if offset+length > len(base) {
+				return nil, fmt.Errorf("delta复制越界")
+			}
+			out = append(out, base[offset:offset+length]...)
+		} else if cmd != 0 {
+			// 插入：cmd为随后字面量的长度
+			if pos+int(cmd) > len(delta) {
+				return nil, fmt.Errorf("delta截断")
+			}
+			out = append(out, delta[pos:pos+int(cmd)]...)
+			pos += int(cmd)
+		} else {
+			return nil, fmt.Errorf("delta指令无效")
+		}
+	}
+
+	if len(out) != targetSize {
+		return nil, fmt.Errorf("delta结果长度不符")
+	}
+	return out, nil
+}
+
+// parsePackIndex 解析v2格式的pack索引，返回对象SHA-1到pack内偏移的映射
+func parsePackIndex(idx []byte) (map[string]int64, error) {
+	if len(idx) < 8+256*4 || !bytes.Equal(idx[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(idx[4:8]) != 2 {
+		return nil, fmt.Errorf("不支持的索引格式")
+	}
+
+	count := int(binary.BigEndian.Uint32(idx[8+255*4 : 8+256*4]))
+	shaStart := 8 + 256*4
+	offsetStart := shaStart + count*20 + count*4
+	largeStart := offsetStart + count*4
+	if count > maxRepoPackSize/20 || largeStart > len(idx) {
+		return nil, fmt.Errorf("索引不完整")
+	}
+
+	offsets := make(map[string]int64, count)
+	for i := 0; i < count; i++ {
+		sha := hex.EncodeToString(idx[shaStart+i*20 : shaStart+i*20+20])
+		offset := int64(binary.BigEndian.Uint32(idx[offsetStart+i*4:]))
+		if offset&0x80000000 != 0 {
+			large := largeStart + int(offset&0x7fffffff)*8
+			if large+8 > len(idx) {
+				continue
+			}
+			offset = int64(binary.BigEndian.Uint64(idx[large:]))
+		}
+		offsets[sha] = offset
+	}
+	return offsets, nil
+}
+
+// gitIndexEntry .git/index中的文件条目
+type gitIndexEntry struct {
+	path string
+	sha  string
+}
+
+// parseGitIndex 解析.git/index（v2-v4），跳过子模块和符号链接
+func parseGitIndex(data []byte) ([]gitIndexEntry, error) {
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("不是git索引文件")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("不支持的索引版本 %d", version)
+	}
+	count := int(binary.BigEndian.Uint32(data[8:12]))
+
+	var entries []gitIndexEntry
+	pos := 12
+	previous := ""
+	for i := 0; i < count && i < maxRepoFiles*4; i++ {
+		start := pos
+		if pos+62 > len(data) {
+			return entries, fmt.Errorf("索引截断")
+		}
+		mode := binary.BigEndian.Uint32(data[pos+24 : pos+28])
+		sha := hex.EncodeToString(data[pos+40 : pos+60])
+		flags := binary.BigEndian.Uint16(data[pos+60 : pos+62])
+		pos += 62
+		if version >= 3 && flags&0x4000 != 0 {
+			pos += 2
+		}
+		if pos > len(data) {
+			return entries, fmt.Errorf("索引截断")
+		}
+
+		var name string
+		if version == 4 {
+			// 路径前缀压缩：先去掉上一路径末尾N个字节，再拼接后续内容
+			strip, n := readOffsetVarint(data[pos:])
+			if n == 0 || strip < 0 || strip > len(previous) {
+				return entries, fmt.Errorf("索引路径无效")
+			}
+			pos += n
+			nul := bytes.IndexByte(data[pos:], 0)
+			if nul < 0 {
+				return entries, fmt.Errorf("索引截断")
+			}
+			name = previous[:len(previous)-strip] + string(data[pos:pos+nul])
+			pos += nul + 1
+		} else {
+			nul := bytes.IndexByte(data[pos:], 0)
+			if nul < 0 {
+				return entries, fmt.Errorf("索引截断")
+			}
+			name = string(data[pos : pos+nul])
+			pos += nul + 1
+			// v2/v3条目按8字节对齐，以NUL填充
+			pos = start + (pos-start+7)/8*8
+		}
+		previous = name
+
+		switch mode & 0xf000 {
+		case 0xa000, 0xe000: // 符号链接、子模块
+			continue
+		}
+		entries = append(entries, gitIndexEntry{path: name, sha: sha})
+	}
+
+	return entries, nil
+}
+
+// readOffsetVarint git的偏移变长整数编码（与OFS_DELTA相同），
+// 数据截断、超过9字节或值溢出时返回读取长度0
+func readOffsetVarint(data []byte) (int, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	pos := 0
+	c := data[pos]
+	pos++
+	value := int(c & 0x7f)
+	for c&0x80 != 0 {
+		if pos >= len(data) || pos >= 9 {
+			return 0, 0
+		}
+		c = data[pos]
+		pos++
+		value = ((value + 1) << 7) | int(c&0x7f)
+	}
+	if value < 0 {
+		return 0, 0
+	}
+	return value, pos
+}
+
+// commitField 读取commit对象头部的字段
+func commitField(commit []byte, field string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(commit))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, field+" ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, field+" "))
+		}
+	}
+	return ""
+}
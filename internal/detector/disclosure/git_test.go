@@ -0,0 +1,122 @@
+package disclosure
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dronesec/droneriskscan/internal/transport"
+)
+
+func TestApplyDeltaOverflowingSize(t *testing.T) {
+	base := []byte("x")
+	// 基对象长度1，目标长度为 0xff×9 + 0x01，按int累加会溢出为负数
+	delta := append([]byte{0x01}, bytes.Repeat([]byte{0xff}, 9)...)
+	delta = append(delta, 0x01)
+
+	if _, err := applyDelta(base, delta); err == nil {
+		t.Fatal("溢出的delta目标长度应返回错误")
+	}
+}
+
+func TestApplyDelta(t *testing.T) {
+	base := []byte("hello world")
+	// 基对象长度11，目标长度8：复制base[0:5]，插入"!!!"
+	delta := []byte{0x0b, 0x08, 0x90, 0x05, 0x03, '!', '!', '!'}
+
+	out, err := applyDelta(base, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "hello!!!" {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestReadOffsetVarintOverflow(t *testing.T) {
+	data := append(bytes.Repeat([]byte{0xff}, 12), 0x01)
+	if _, n := readOffsetVarint(data); n != 0 {
+		t.Fatalf("超长变长整数应被拒绝，读取了%d字节", n)
+	}
+
+	if value, n := readOffsetVarint([]byte{0x81, 0x00}); n != 2 || value != 256 {
+		t.Fatalf("got value=%d n=%d", value, n)
+	}
+}
+
+func TestParseGitIndexOverflowingStrip(t *testing.T) {
+	data := []byte("DIRC\x00\x00\x00\x04\x00\x00\x00\x01")
+	data = append(data, make([]byte, 62)...)
+	// v4路径前缀压缩的strip值溢出
+	data = append(data, bytes.Repeat([]byte{0xff}, 12)...)
+	data = append(data, 0x01, 'a', 0x00)
+
+	if _, err := parseGitIndex(data); err == nil {
+		t.Fatal("溢出的strip应返回错误")
+	}
+}
+
+// refDeltaPack 构造只含REF_DELTA对象的pack，bases为对象SHA到其基对象SHA的映射
+func refDeltaPack(t *testing.T, bases map[string]string) ([]byte, map[string]int64) {
+	var delta bytes.Buffer
+	writer := zlib.NewWriter(&delta)
+	writer.Write([]byte{0x01, 0x01, 0x01, 'x'})
+	writer.Close()
+
+	pack := []byte("PACK\x00\x00\x00\x02\x00\x00\x00\x00")
+	offsets := make(map[string]int64)
+	for sha, base := range bases {
+		baseSHA, err := hex.DecodeString(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets[sha] = int64(len(pack))
+		pack = append(pack, 0x74) // REF_DELTA，长度4
+		pack = append(pack, baseSHA...)
+		pack = append(pack, delta.Bytes()...)
+	}
+	return pack, offsets
+}
+
+func newTestGitRepo(t *testing.T, pack []byte, offsets map[string]int64) *gitRepo {
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	baseURL, _ := url.Parse(server.URL + "/.git/")
+
+	return &gitRepo{
+		dumper:  NewRepositoryDumper(transport.NewHTTPClient(transport.DefaultClientOptions()), nil, ""),
+		baseURL: baseURL,
+		packs:   []*gitPack{{name: "test", data: pack, offsets: offsets}},
+		cache:   make(map[string]*gitObject),
+		failed:  make(map[string]bool),
+	}
+}
+
+func TestGitObjectSelfReferencingRefDelta(t *testing.T) {
+	sha := "1111111111111111111111111111111111111111"
+	pack, offsets := refDeltaPack(t, map[string]string{sha: sha})
+
+	repo := newTestGitRepo(t, pack, offsets)
+	if obj := repo.object(context.Background(), sha); obj != nil {
+		t.Fatalf("以自身为基对象的REF_DELTA应解析失败，得到 %q", obj.data)
+	}
+}
+
+func TestGitObjectCyclicRefDelta(t *testing.T) {
+	a := "1111111111111111111111111111111111111111"
+	b := "2222222222222222222222222222222222222222"
+	pack, offsets := refDeltaPack(t, map[string]string{a: b, b: a})
+
+	repo := newTestGitRepo(t, pack, offsets)
+	if obj := repo.object(context.Background(), a); obj != nil {
+		t.Fatal("循环引用的REF_DELTA应解析失败")
+	}
+	if obj := repo.object(context.Background(), b); obj != nil {
+		t.Fatal("循环引用的REF_DELTA应解析失败")
+	}
+}
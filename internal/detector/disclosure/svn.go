@@ -0,0 +1,250 @@
+package disclosure
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// SVN 1.7+ 的工作副本元数据保存在.svn/wc.db（SQLite）中：NODES表记录文件路径和内容校验和，
+// 文件原文按校验和存放在.svn/pristine/xx/<sha1>.svn-base。这里只实现读取表数据所需的最小SQLite解析
+
+// DumpSVN 还原baseURL（以/.svn/结尾）指向的SVN工作副本
+func (d *RepositoryDumper) DumpSVN(ctx context.Context, baseURL *url.URL) (*RepositoryDump, error) {
+	dump := d.newDump("svn", baseURL)
+
+	db, _, err := d.fetch(ctx, baseURL.ResolveReference(&url.URL{Path: "wc.db"}).String(), maxRepoPackSize)
+	if err != nil {
+		return nil, fmt.Errorf("读取wc.db失败: %w", err)
+	}
+
+	rows, err := sqliteRows(db)
+	if err != nil {
+		return nil, fmt.Errorf("解析wc.db失败: %w", err)
+	}
+
+	// NODES: wc_id, local_relpath, op_depth, parent_relpath, repos_id, repos_path, revision, presence,
+	// moved_here, moved_to, kind, properties, depth, checksum, ...
+	files := make(map[string]string)
+	var order []string
+	for _, row := range rows {
+		if len(row) == 3 {
+			// REPOSITORY: id, root, uuid
+			if root, ok := row[1].(string); ok && strings.Contains(root, "://") {
+				dump.Info["root"] = root
+			}
+			continue
+		}
+		if len(row) < 14 {
+			continue
+		}
+		relpath, _ := row[1].(string)
+		kind, _ := row[10].(string)
+		presence, _ := row[7].(string)
+		checksum, _ := row[13].(string)
+		if relpath == "" || kind != "file" || presence != "normal" || !strings.HasPrefix(checksum, "$sha1$") {
+			continue
+		}
+		sha := strings.TrimPrefix(checksum, "$sha1$")
+		if !shaPattern.MatchString(sha) {
+			continue
+		}
+		if _, ok := files[relpath]; !ok && len(files) < maxRepoFiles {
+			files[relpath] = sha
+			order = append(order, relpath)
+		}
+		if revision, ok := row[6].(int64); ok {
+			if current, _ := strconv.ParseInt(dump.Info["revision"], 10, 64); revision > current {
+				dump.Info["revision"] = strconv.FormatInt(revision, 10)
+			}
+		}
+	}
+	dump.Info["nodes"] = strconv.Itoa(len(order))
+
+	for _, relpath := range order {
+		if ctx.Err() != nil {
+			break
+		}
+		sha := files[relpath]
+		pristine := baseURL.ResolveReference(&url.URL{Path: "pristine/" + sha[:2] + "/" + sha + ".svn-base"})
+		content, truncated, err := d.fetch(ctx, pristine.String(), maxRepoFileSize)
+		if err != nil || truncated {
+			dump.Missing++
+			continue
+		}
+		d.addFile(dump, relpath, content)
+	}
+
+	return d.finish(dump), nil
+}
+
+// sqliteRows 遍历SQLite文件中所有表叶子页的记录，不区分所属的表；溢出页中的部分被忽略
+func sqliteRows(db []byte) ([][]interface{}, error) {
+	if len(db) < 100 || string(db[:16]) != "SQLite format 3\x00" {
+		return nil, fmt.Errorf("不是SQLite数据库")
+	}
+	pageSize := int(binary.BigEndian.Uint16(db[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 {
+		return nil, fmt.Errorf("页大小无效")
+	}
+	usable := pageSize - int(db[20])
+
+	var rows [][]interface{}
+	for pageStart := 0; pageStart+pageSize <= len(db); pageStart += pageSize {
+		header := pageStart
+		if pageStart == 0 {
+			header = 100
+		}
+		page := db[pageStart : pageStart+pageSize]
+		if db[header] != 0x0d { // 表B树叶子页
+			continue
+		}
+
+		cells := int(binary.BigEndian.Uint16(db[header+3 : header+5]))
+		for i := 0; i < cells; i++ {
+			ptrPos := header - pageStart + 8 + i*2
+			if ptrPos+2 > len(page) {
+				break
+			}
+			cell := int(binary.BigEndian.Uint16(page[ptrPos:]))
+			if cell >= len(page) {
+				continue
+			}
+
+			payloadSize, n := sqliteVarint(page[cell:])
+			if n == 0 {
+				continue
+			}
+			_, m := sqliteVarint(page[cell+n:])
+			if m == 0 {
+				continue
+			}
+			start := cell + n + m
+
+			local := sqliteLocalPayload(int(payloadSize), usable)
+			end := start + local
+			if end > len(page) {
+				end = len(page)
+			}
+			if start >= end {
+				continue
+			}
+			if row := sqliteRecord(page[start:end]); row != nil {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+// sqliteLocalPayload 表叶子单元中保存在本页的负载长度
+func sqliteLocalPayload(payload, usable int) int {
+	maxLocal := usable - 35
+	if payload <= maxLocal {
+		return payload
+	}
+	minLocal := (usable-12)*32/255 - 23
+	k := minLocal + (payload-minLocal)%(usable-4)
+	if k <= maxLocal {
+		return k
+	}
+	return minLocal
+}
+
+// sqliteRecord 解码记录，返回nil、int64、float64、string或[]byte组成的列；被截断的列为nil
+func sqliteRecord(payload []byte) []interface{} {
+	headerSize, n := sqliteVarint(payload)
+	if n == 0 || headerSize > uint64(len(payload)) || headerSize < uint64(n) {
+		return nil
+	}
+
+	var types []uint64
+	for pos := n; pos < int(headerSize); {
+		serial, m := sqliteVarint(payload[pos:int(headerSize)])
+		if m == 0 {
+			return nil
+		}
+		types = append(types, serial)
+		pos += m
+	}
+
+	row := make([]interface{}, len(types))
+	pos := int(headerSize)
+	for i, serial := range types {
+		var size int
+		switch {
+		case serial <= 4:
+			size = int(serial)
+		case serial == 5:
+			size = 6
+		case serial == 6, serial == 7:
+			size = 8
+		case serial == 8, serial == 9:
+			size = 0
+		case serial >= 12:
+			// 长度不可能超过记录本身，先比较再转换，避免恶意序列类型溢出
+			if (serial-12)/2 > uint64(len(payload)) {
+				return row
+			}
+			size = int((serial - 12) / 2)
+		default:
+			return row
+		}
+		if size > len(payload)-pos {
+			return row
+		}
+		value := payload[pos : pos+size]
+		pos += size
+
+		switch {
+		case serial == 0:
+			row[i] = nil
+		case serial <= 6:
+			var v int64
+			if value[0]&0x80 != 0 {
+				v = -1
+			}
+			for _, b := range value {
+				v = v<<8 | int64(b)
+			}
+			row[i] = v
+		case serial == 7:
+			row[i] = math.Float64frombits(binary.BigEndian.Uint64(value))
+		case serial == 8:
+			row[i] = int64(0)
+		case serial == 9:
+			row[i] = int64(1)
+		case serial%2 == 0:
+			row[i] = append([]byte(nil), value...)
+		default:
+			row[i] = string(value)
+		}
+	}
+	return row
+}
+
+// sqliteVarint SQLite变长整数（大端，最多9字节），返回值和占用字节数，数据不足时字节数为0
+func sqliteVarint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(data) {
+			return 0, 0
+		}
+		if i == 8 {
+			return value<<8 | uint64(data[i]), 9
+		}
+		value = value<<7 | uint64(data[i]&0x7f)
+		if data[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return value, 9
+}
@@ -0,0 +1,34 @@
+package disclosure
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSqliteRecordOverflowingSerial(t *testing.T) {
+	// 头部长度10：9字节的序列类型变长整数接近2^64，解码为长度时会溢出
+	payload := append([]byte{0x0a}, bytes.Repeat([]byte{0xff}, 9)...)
+	payload = append(payload, 'a', 'b')
+
+	row := sqliteRecord(payload)
+	if len(row) != 1 || row[0] != nil {
+		t.Fatalf("got %v", row)
+	}
+}
+
+func TestSqliteRecordOverflowingHeaderSize(t *testing.T) {
+	payload := append(bytes.Repeat([]byte{0xff}, 9), 'a', 'b', 'c')
+	if row := sqliteRecord(payload); row != nil {
+		t.Fatalf("got %v", row)
+	}
+}
+
+func TestSqliteRecord(t *testing.T) {
+	// 头部长度3，列类型：1字节整数、长度2的文本 (2*2+13=17)
+	payload := []byte{0x03, 0x01, 0x11, 0x2a, 'h', 'i'}
+
+	row := sqliteRecord(payload)
+	if len(row) != 2 || row[0] != int64(42) || row[1] != "hi" {
+		t.Fatalf("got %#v", row)
+	}
+}
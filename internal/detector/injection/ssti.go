@@ -0,0 +1,465 @@
+package injection
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// TemplateInjectionDetector 服务端模板注入检测器 - 算术探测确认求值，再按决策树识别模板引擎
+type TemplateInjectionDetector struct {
+	*detector.BasePlugin
+	requestModifier  *detector.RequestModifier
+	responseAnalyzer *detector.ResponseAnalyzer
+	paramExtractor   *detector.ParameterExtractor
+}
+
+// TemplateEngine 模板引擎信息
+type TemplateEngine struct {
+	Name     string
+	Language string
+	RCE      bool // 已知可由模板表达式执行任意代码
+}
+
+// SSTIExpression 生成探测表达式及其预期渲染结果，marker为随机小写标记
+type SSTIExpression func(marker string) (payload, expected string)
+
+// SSTIFingerprint 引擎指纹：全部表达式都按预期渲染即判定为该引擎
+type SSTIFingerprint struct {
+	Engine TemplateEngine
+	Checks []SSTIExpression
+}
+
+// SSTIProbe 一种模板语法的求值探测及其下的引擎决策分支
+type SSTIProbe struct {
+	Syntax       string
+	Evaluate     func(a, b int, marker string) (payload, expected string)
+	Fingerprints []SSTIFingerprint // 按顺序检查，先匹配者优先
+	Default      TemplateEngine    // 指纹均不匹配时的结论
+}
+
+// NewTemplateInjectionDetector 创建模板注入检测器
+func NewTemplateInjectionDetector(httpClient transport.HTTPClient) *TemplateInjectionDetector {
+	base := detector.NewBasePlugin(
+		"template-injection",
+		detector.PluginTypeActive,
+		models.CategoryInjection,
+		models.SeverityHigh,
+	)
+
+	base.SetDescription("检测服务端模板注入漏洞，识别Jinja2、Twig、Freemarker、Velocity、ERB、Go模板等引擎")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &TemplateInjectionDetector{
+		BasePlugin:       base,
+		requestModifier:  detector.NewRequestModifier(httpClient),
+		responseAnalyzer: detector.NewResponseAnalyzer(),
+		paramExtractor:   detector.NewParameterExtractor(),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (t *TemplateInjectionDetector) SetSessionCookies(cookies []*http.Cookie) {
+	t.requestModifier.SetSessionCookies(cookies)
+}
+
+// Execute 执行模板注入检测
+func (t *TemplateInjectionDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	injectPoints := t.paramExtractor.ExtractParameters(target)
+	if len(injectPoints) == 0 {
+		result.Metadata["message"] = "未发现可注入参数"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] 模板注入检测器找到 %d 个注入点\n", len(injectPoints))
+
+	for _, point := range injectPoints {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		fmt.Printf("[INFO] 正在测试参数: %s (位置: %s)\n", point.Name, point.Position)
+
+		baselineBody, err := t.fetchBody(ctx, target, point, point.Value)
+		if err != nil {
+			continue
+		}
+
+		// 1. 算术求值探测 + 引擎识别
+		vuln := t.testEvaluation(ctx, target, point, string(baselineBody))
+
+		// 2. 模板语法错误特征
+		if vuln == nil {
+			vuln = t.testErrorBased(ctx, target, point, baselineBody)
+		}
+
+		if vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现模板注入漏洞: %s (%s)\n", point.Name, vuln.Metadata["engine"])
+		}
+	}
+
+	result.Metadata["tested_parameters"] = len(injectPoints)
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// testEvaluation 依次发送各语法的算术表达式，渲染出乘积且用新操作数复现后进入引擎识别
+func (t *TemplateInjectionDetector) testEvaluation(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, baseline string) *models.Vulnerability {
+	for _, probe := range t.getProbes() {
+		payload, expected, ok := t.tryEvaluate(ctx, target, point, baseline, probe)
+		if !ok {
+			continue
+		}
+
+		// 换一组操作数复现，排除巧合
+		if _, _, ok := t.tryEvaluate(ctx, target, point, baseline, probe); !ok {
+			continue
+		}
+
+		fmt.Printf("[DEBUG] %s 语法表达式被求值: %s => %s\n", probe.Syntax, payload, expected)
+
+		engine := t.fingerprint(ctx, target, point, baseline, probe)
+
+		vuln := t.buildVulnerability(
+			fmt.Sprintf("Server-Side Template Injection (%s)", engine.Name),
+			fmt.Sprintf("参数 %s 被作为模板内容渲染，%s 语法的表达式被服务端求值，识别为 %s 模板引擎", point.Name, probe.Syntax, engine.Name),
+			target, point, payload,
+			fmt.Sprintf("表达式 %s 渲染为 %s", strings.TrimPrefix(payload, point.Value), expected),
+			0.95, engine,
+		)
+		vuln.Metadata["syntax"] = probe.Syntax
+		vuln.Metadata["expression"] = strings.TrimPrefix(payload, point.Value)
+		vuln.Metadata["rendered"] = expected
+		return vuln
+	}
+
+	return nil
+}
+
+// tryEvaluate 发送一次随机操作数的求值探测
+func (t *TemplateInjectionDetector) tryEvaluate(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, baseline string, probe SSTIProbe) (string, string, bool) {
+	a := 1000 + rand.Intn(8999)
+	b := 1000 + rand.Intn(8999)
+	expr, expected := probe.Evaluate(a, b, randomMarker())
+	if strings.Contains(baseline, expected) {
+		return "", "", false
+	}
+
+	payload := point.Value + expr
+	fmt.Printf("[DEBUG] 测试模板注入payload: %s\n", payload)
+
+	body, err := t.fetchBody(ctx, target, point, payload)
+	if err != nil {
+		return "", "", false
+	}
+	return payload, expected, strings.Contains(string(body), expected)
+}
+
+// fingerprint 按决策树识别引擎：依次检查该语法下各引擎特有的表达式
+func (t *TemplateInjectionDetector) fingerprint(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, baseline string, probe SSTIProbe) TemplateEngine {
+	for _, fp := range probe.Fingerprints {
+		matched := true
+		for _, check := range fp.Checks {
+			expr, expected := check(randomMarker())
+			if strings.Contains(baseline, expected) {
+				matched = false
+				break
+			}
+
+			body, err := t.fetchBody(ctx, target, point, point.Value+expr)
+			if err != nil || !strings.Contains(string(body), expected) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return fp.Engine
+		}
+	}
+
+	return probe.Default
+}
+
+// testErrorBased 发送破坏各模板语法的多语言载荷，按错误特征识别引擎
+func (t *TemplateInjectionDetector) testErrorBased(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, baselineBody []byte) *models.Vulnerability {
+	polyglot := "${{<%[%'\"}}%\\."
+
+	body, err := t.fetchBody(ctx, target, point, point.Value+polyglot)
+	if err != nil {
+		return nil
+	}
+
+	for _, sig := range t.getErrorSignatures() {
+		// 基准中已存在的错误特征不作为证据
+		if found, _ := t.responseAnalyzer.ContainsErrorPatterns(baselineBody, sig.Patterns); found {
+			continue
+		}
+		found, matched := t.responseAnalyzer.ContainsErrorPatterns(body, sig.Patterns)
+		if !found {
+			continue
+		}
+
+		vuln := t.buildVulnerability(
+			fmt.Sprintf("Server-Side Template Injection (%s, Error-based)", sig.Engine.Name),
+			fmt.Sprintf("参数 %s 被作为模板内容解析，模板语法字符导致 %s 引擎报错", point.Name, sig.Engine.Name),
+			target, point, point.Value+polyglot,
+			fmt.Sprintf("响应中出现模板引擎错误特征: %v", matched),
+			0.7, sig.Engine,
+		)
+		vuln.Metadata["syntax"] = "polyglot"
+		vuln.Metadata["expression"] = polyglot
+		return vuln
+	}
+
+	return nil
+}
+
+// fetchBody 修改注入点并读取响应体
+func (t *TemplateInjectionDetector) fetchBody(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, value string) ([]byte, error) {
+	resp, err := t.requestModifier.ModifyParameter(ctx, target, point, value)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	return helper.ReadBody(resp)
+}
+
+// 模板引擎
+var (
+	engineJinja2     = TemplateEngine{Name: "Jinja2", Language: "Python", RCE: true}
+	engineTornado    = TemplateEngine{Name: "Tornado", Language: "Python", RCE: true}
+	engineMako       = TemplateEngine{Name: "Mako", Language: "Python", RCE: true}
+	engineDjango     = TemplateEngine{Name: "Django", Language: "Python", RCE: false}
+	engineTwig       = TemplateEngine{Name: "Twig", Language: "PHP", RCE: true}
+	engineSmarty     = TemplateEngine{Name: "Smarty", Language: "PHP", RCE: true}
+	engineFreemarker = TemplateEngine{Name: "Freemarker", Language: "Java", RCE: true}
+	engineVelocity   = TemplateEngine{Name: "Velocity", Language: "Java", RCE: true}
+	enginePebble     = TemplateEngine{Name: "Pebble", Language: "Java", RCE: true}
+	engineThymeleaf  = TemplateEngine{Name: "Thymeleaf", Language: "Java", RCE: true}
+	engineEL         = TemplateEngine{Name: "Expression Language (EL/SpEL)", Language: "Java", RCE: true}
+	engineERB        = TemplateEngine{Name: "ERB", Language: "Ruby", RCE: true}
+	engineSlim       = TemplateEngine{Name: "Slim/Haml", Language: "Ruby", RCE: true}
+	engineEJS        = TemplateEngine{Name: "EJS", Language: "JavaScript", RCE: true}
+	engineNunjucks   = TemplateEngine{Name: "Nunjucks", Language: "JavaScript", RCE: true}
+	enginePug        = TemplateEngine{Name: "Pug", Language: "JavaScript", RCE: true}
+	engineHandlebars = TemplateEngine{Name: "Handlebars", Language: "JavaScript", RCE: false}
+	engineRazor      = TemplateEngine{Name: "Razor", Language: "C#", RCE: true}
+	engineGo         = TemplateEngine{Name: "Go text/template", Language: "Go", RCE: false}
+)
+
+// mathExpr 构造 "前缀 a*b 后缀" 形式的算术探测
+func mathExpr(prefix, suffix string) func(a, b int, marker string) (string, string) {
+	return func(a, b int, marker string) (string, string) {
+		return fmt.Sprintf("%s%d*%d%s", prefix, a, b, suffix), fmt.Sprint(a * b)
+	}
+}
+
+// upperExpr 构造字符串转大写的指纹表达式，format中的 %s 为标记
+func upperExpr(format string) SSTIExpression {
+	return func(marker string) (string, string) {
+		return fmt.Sprintf(format, marker), strings.ToUpper(marker)
+	}
+}
+
+// getProbes 各模板语法的探测及决策树；{ } 放在 {{ }} 之前，避免Smarty把 {{a*b}} 渲染为 {乘积} 后误入双花括号分支
+func (t *TemplateInjectionDetector) getProbes() []SSTIProbe {
+	return []SSTIProbe{
+		{
+			Syntax:   "{ }",
+			Evaluate: mathExpr("{", "}"),
+			Fingerprints: []SSTIFingerprint{
+				{Engine: engineSmarty, Checks: []SSTIExpression{upperExpr("{'%s'|upper}")}},
+			},
+			Default: engineSmarty,
+		},
+		{
+			Syntax:   "{{ }}",
+			Evaluate: mathExpr("{{", "}}"),
+			Fingerprints: []SSTIFingerprint{
+				// Python字符串乘法 + Jinja过滤器
+				{Engine: engineJinja2, Checks: []SSTIExpression{
+					func(marker string) (string, string) {
+						return fmt.Sprintf("{{3*'%s'}}", marker), strings.Repeat(marker, 3)
+					},
+					upperExpr("{{'%s'|upper}}"),
+				}},
+				{Engine: engineTornado, Checks: []SSTIExpression{
+					func(marker string) (string, string) {
+						return fmt.Sprintf("{{3*'%s'}}", marker), strings.Repeat(marker, 3)
+					},
+				}},
+				// Java对象方法
+				{Engine: enginePebble, Checks: []SSTIExpression{
+					func(marker string) (string, string) {
+						return fmt.Sprintf("{{'%s'.getClass().getName()}}", marker), "java.lang.String"
+					},
+				}},
+				// JavaScript字符串方法
+				{Engine: engineNunjucks, Checks: []SSTIExpression{upperExpr("{{'%s'.toUpperCase()}}")}},
+				{Engine: engineTwig, Checks: []SSTIExpression{upperExpr("{{'%s'|upper}}")}},
+			},
+			Default: TemplateEngine{Name: "Unknown ({{ }})", RCE: false},
+		},
+		{
+			Syntax:   "${ }",
+			Evaluate: mathExpr("${", "}"),
+			Fingerprints: []SSTIFingerprint{
+				{Engine: engineFreemarker, Checks: []SSTIExpression{upperExpr("${'%s'?upper_case}")}},
+				{Engine: engineMako, Checks: []SSTIExpression{upperExpr("${'%s'.upper()}")}},
+				// Thymeleaf表达式工具对象
+				{Engine: engineThymeleaf, Checks: []SSTIExpression{upperExpr("${#strings.toUpperCase('%s')}")}},
+				{Engine: engineEL, Checks: []SSTIExpression{upperExpr("${'%s'.toUpperCase()}")}},
+			},
+			Default: TemplateEngine{Name: "Unknown (${ })", RCE: false},
+		},
+		{
+			Syntax:   "<%= %>",
+			Evaluate: mathExpr("<%= ", " %>"),
+			Fingerprints: []SSTIFingerprint{
+				{Engine: engineERB, Checks: []SSTIExpression{upperExpr("<%%= '%s'.upcase %%>")}},
+				{Engine: engineEJS, Checks: []SSTIExpression{upperExpr("<%%= '%s'.toUpperCase() %%>")}},
+			},
+			Default: TemplateEngine{Name: "ERB/EJS", RCE: true},
+		},
+		{
+			Syntax:   "#{ }",
+			Evaluate: mathExpr("#{", "}"),
+			Fingerprints: []SSTIFingerprint{
+				{Engine: engineSlim, Checks: []SSTIExpression{upperExpr("#{'%s'.upcase}")}},
+				{Engine: enginePug, Checks: []SSTIExpression{upperExpr("#{'%s'.toUpperCase()}")}},
+			},
+			Default: TemplateEngine{Name: "Unknown (#{ })", RCE: false},
+		},
+		{
+			Syntax: "#set",
+			Evaluate: func(a, b int, marker string) (string, string) {
+				return fmt.Sprintf("#set($%s=%d*%d)${%s}", marker, a, b, marker), fmt.Sprint(a * b)
+			},
+			Default: engineVelocity,
+		},
+		{
+			Syntax:   "@( )",
+			Evaluate: mathExpr("@(", ")"),
+			Default:  engineRazor,
+		},
+		{
+			// Go模板不支持算术运算，用print拼接两个字符串确认求值
+			Syntax: "{{print}}",
+			Evaluate: func(a, b int, marker string) (string, string) {
+				left, right := fmt.Sprintf("%s%d", marker, a), fmt.Sprintf("%s%d", marker, b)
+				return fmt.Sprintf(`{{print "%s" "%s"}}`, left, right), left + right
+			},
+			Default: engineGo,
+		},
+	}
+}
+
+// SSTIErrorSignature 模板引擎错误特征
+type SSTIErrorSignature struct {
+	Engine   TemplateEngine
+	Patterns []string
+}
+
+// getErrorSignatures 各模板引擎的错误特征
+func (t *TemplateInjectionDetector) getErrorSignatures() []SSTIErrorSignature {
+	return []SSTIErrorSignature{
+		{Engine: engineJinja2, Patterns: []string{"jinja2.exceptions", "jinja2/", "templatesyntaxerror: unexpected"}},
+		{Engine: engineTornado, Patterns: []string{"tornado.template", "tornado/template.py"}},
+		{Engine: engineMako, Patterns: []string{"mako.exceptions", "mako/lexer.py"}},
+		{Engine: engineDjango, Patterns: []string{"django.template.exceptions", "django/template/"}},
+		{Engine: engineTwig, Patterns: []string{"twig_error_syntax", "twig\\error\\syntaxerror", "twig\\error"}},
+		{Engine: engineSmarty, Patterns: []string{"smartycompilerexception", "smarty_compiler", "smarty error:"}},
+		{Engine: engineFreemarker, Patterns: []string{"freemarker.core.", "freemarker template error", "freemarker.template."}},
+		{Engine: engineVelocity, Patterns: []string{"org.apache.velocity", "velocity.exception"}},
+		{Engine: enginePebble, Patterns: []string{"com.mitchellbosecke.pebble", "io.pebbletemplates"}},
+		{Engine: engineThymeleaf, Patterns: []string{"org.thymeleaf.exceptions", "templateinputexception"}},
+		{Engine: engineEL, Patterns: []string{"javax.el.", "jakarta.el.", "org.springframework.expression"}},
+		{Engine: engineERB, Patterns: []string{"(erb):", "erb/compiler"}},
+		{Engine: engineEJS, Patterns: []string{"ejs:", "node_modules/ejs"}},
+		{Engine: engineNunjucks, Patterns: []string{"template render error", "node_modules/nunjucks"}},
+		{Engine: enginePug, Patterns: []string{"node_modules/pug", "pug:"}},
+		{Engine: engineHandlebars, Patterns: []string{"node_modules/handlebars", "parse error on line"}},
+		{Engine: engineRazor, Patterns: []string{"razorengine", "system.web.razor"}},
+		{Engine: engineGo, Patterns: []string{"html/template:", "text/template:", `unexpected "<" in command`}},
+	}
+}
+
+// randomMarker 生成用于指纹表达式的随机小写标记
+func randomMarker() string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	buf := make([]byte, 6)
+	for i := range buf {
+		buf[i] = letters[rand.Intn(len(letters))]
+	}
+	return "drs" + string(buf)
+}
+
+// buildVulnerability 构建模板注入漏洞对象，可执行代码的引擎提升为严重
+func (t *TemplateInjectionDetector) buildVulnerability(
+	title, description string,
+	target *detector.ScanTarget,
+	point detector.InjectPoint,
+	payload, evidence string,
+	confidence float64,
+	engine TemplateEngine,
+) *models.Vulnerability {
+	severity := models.SeverityHigh
+	cvss := 7.5
+	if engine.RCE {
+		severity = models.SeverityCritical
+		cvss = 9.8
+	}
+
+	builder := models.NewVulnerabilityBuilder().
+		WithType(models.VulnSSTI).
+		WithCategory(models.CategoryInjection).
+		WithSeverity(severity).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithParameter(point.Name, point.Position).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(t.Name()).
+		WithCWE("CWE-1336").
+		WithCVSS(cvss).
+		WithMetadata("engine", engine.Name).
+		WithMetadata("rce_capable", fmt.Sprint(engine.RCE)).
+		WithSolution("不要将用户输入拼接进模板源码，只作为渲染变量传入；必要时使用沙箱模式并限制可访问的对象和方法").
+		WithReferences([]string{
+			"https://portswigger.net/research/server-side-template-injection",
+			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/07-Input_Validation_Testing/18-Testing_for_Server-side_Template_Injection",
+		})
+	if engine.Language != "" {
+		builder = builder.WithMetadata("language", engine.Language)
+	}
+
+	return builder.Build()
+}
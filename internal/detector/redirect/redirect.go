@@ -0,0 +1,347 @@
+package redirect
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// redirectCanary 载荷中的外部跳转目标，使用保留域名避免真实跳转到第三方
+const redirectCanary = "drs-redirect.example.com"
+
+// OpenRedirectDetector 开放重定向检测器 - 禁止自动跟随重定向，检查Location/Refresh头及页面内跳转
+type OpenRedirectDetector struct {
+	*detector.BasePlugin
+	requestModifier *detector.RequestModifier
+	paramExtractor  *detector.ParameterExtractor
+}
+
+// RedirectPayload 重定向载荷及其绕过手法
+type RedirectPayload struct {
+	Value     string
+	Technique string
+}
+
+// redirectMatch 响应中发现的跳转
+type redirectMatch struct {
+	Kind     string // location、refresh、meta、javascript
+	Location string
+}
+
+var (
+	metaRefreshPattern = regexp.MustCompile(`(?is)<meta[^>]+http-equiv\s*=\s*["']?refresh["']?[^>]*content\s*=\s*["']?\s*\d*\s*;?\s*url\s*=\s*['"]?([^"'>\s]+)`)
+	jsLocationPattern  = regexp.MustCompile(`(?i)(?:window\.|document\.|top\.|self\.)?location(?:\.href)?\s*=\s*["']([^"']+)["']|location\.(?:replace|assign)\(\s*["']([^"']+)["']`)
+)
+
+// NewOpenRedirectDetector 创建开放重定向检测器
+func NewOpenRedirectDetector(httpClient transport.HTTPClient) *OpenRedirectDetector {
+	base := detector.NewBasePlugin(
+		"open-redirect",
+		detector.PluginTypeActive,
+		models.CategoryLogic,
+		models.SeverityMedium,
+	)
+
+	base.SetDescription("检测开放重定向漏洞，覆盖Location/Refresh头、meta refresh和JavaScript跳转及常见白名单绕过")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &OpenRedirectDetector{
+		BasePlugin:      base,
+		requestModifier: detector.NewRequestModifier(httpClient),
+		paramExtractor:  detector.NewParameterExtractor(),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (o *OpenRedirectDetector) SetSessionCookies(cookies []*http.Cookie) {
+	o.requestModifier.SetSessionCookies(cookies)
+}
+
+// Execute 执行开放重定向检测
+func (o *OpenRedirectDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	var injectPoints []detector.InjectPoint
+	for _, point := range o.paramExtractor.ExtractParameters(target) {
+		if o.isRedirectParameter(point) {
+			injectPoints = append(injectPoints, point)
+		}
+	}
+	if len(injectPoints) == 0 {
+		result.Metadata["message"] = "未发现跳转类参数"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] 开放重定向检测器找到 %d 个跳转类参数\n", len(injectPoints))
+
+	// 所有探测请求都不跟随重定向，否则只能看到跳转后的页面
+	ctx = transport.WithoutRedirects(ctx)
+
+	for _, point := range injectPoints {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		fmt.Printf("[INFO] 正在测试参数: %s (位置: %s)\n", point.Name, point.Position)
+
+		if vuln := o.testParameter(ctx, target, point); vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现开放重定向漏洞: %s (%s)\n", point.Name, vuln.Metadata["redirect_type"])
+		}
+	}
+
+	result.Metadata["tested_parameters"] = len(injectPoints)
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// testParameter 依次发送各载荷，响应跳转到外部域名即判定存在漏洞
+func (o *OpenRedirectDetector) testParameter(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint) *models.Vulnerability {
+	for _, payload := range o.getPayloads(target, point) {
+		fmt.Printf("[DEBUG] 测试重定向payload: %s\n", payload.Value)
+
+		resp, err := o.requestModifier.ModifyParameter(ctx, target, point, payload.Value)
+		if err != nil {
+			continue
+		}
+
+		helper := transport.NewResponseHelper()
+		body, _ := helper.ReadBody(resp)
+		resp.Body.Close()
+
+		match := o.findRedirect(resp, body)
+		if match == nil {
+			continue
+		}
+
+		description := fmt.Sprintf("参数 %s 的值被直接用作跳转地址，可将用户重定向到任意外部站点", point.Name)
+		evidence := fmt.Sprintf("%s 跳转到 %s", match.Kind, match.Location)
+		if match.Kind == "location" || match.Kind == "refresh" {
+			evidence = fmt.Sprintf("HTTP %d, %s: %s", resp.StatusCode, headerName(match.Kind), match.Location)
+		}
+
+		vuln := o.buildVulnerability(
+			fmt.Sprintf("Open Redirect (%s)", payload.Technique),
+			description,
+			target, point, payload.Value, evidence,
+			o.confidence(match.Kind),
+		)
+		vuln.Metadata["redirect_type"] = match.Kind
+		vuln.Metadata["location"] = match.Location
+		vuln.Metadata["technique"] = payload.Technique
+		return vuln
+	}
+
+	return nil
+}
+
+// findRedirect 在响应头和响应体中查找指向探测域名的跳转
+func (o *OpenRedirectDetector) findRedirect(resp *http.Response, body []byte) *redirectMatch {
+	var base *url.URL
+	if resp.Request != nil {
+		base = resp.Request.URL
+	}
+
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if location := resp.Header.Get("Location"); location != "" && isCanaryRedirect(base, location) {
+			return &redirectMatch{Kind: "location", Location: location}
+		}
+	}
+
+	if refresh := resp.Header.Get("Refresh"); refresh != "" {
+		if idx := strings.Index(strings.ToLower(refresh), "url="); idx >= 0 {
+			location := strings.Trim(refresh[idx+4:], `'" `)
+			if isCanaryRedirect(base, location) {
+				return &redirectMatch{Kind: "refresh", Location: location}
+			}
+		}
+	}
+
+	content := string(body)
+	for _, m := range metaRefreshPattern.FindAllStringSubmatch(content, -1) {
+		location := html.UnescapeString(m[1])
+		if isCanaryRedirect(base, location) {
+			return &redirectMatch{Kind: "meta", Location: location}
+		}
+	}
+
+	for _, m := range jsLocationPattern.FindAllStringSubmatch(content, -1) {
+		location := m[1]
+		if location == "" {
+			location = m[2]
+		}
+		location = unescapeJSString(html.UnescapeString(location))
+		if isCanaryRedirect(base, location) {
+			return &redirectMatch{Kind: "javascript", Location: location}
+		}
+	}
+
+	return nil
+}
+
+// isCanaryRedirect 按浏览器的解析方式判断跳转地址是否指向探测域名
+func isCanaryRedirect(base *url.URL, location string) bool {
+	// 浏览器会去掉首尾空白和控制字符，并把反斜杠当作斜杠处理
+	location = strings.TrimFunc(location, func(r rune) bool { return r <= ' ' })
+	location = strings.ReplaceAll(location, `\`, "/")
+
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	if base != nil {
+		parsed = base.ResolveReference(parsed)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	return host == redirectCanary || strings.HasSuffix(host, "."+redirectCanary)
+}
+
+// unescapeJSString 还原JavaScript字符串中常见的斜杠转义
+func unescapeJSString(s string) string {
+	replacer := strings.NewReplacer(`\/`, "/", `\x2f`, "/", `\x2F`, "/", `\u002f`, "/", `\u002F`, "/")
+	return replacer.Replace(s)
+}
+
+// headerName 跳转类型对应的响应头名称
+func headerName(kind string) string {
+	if kind == "refresh" {
+		return "Refresh"
+	}
+	return "Location"
+}
+
+// confidence 服务端跳转可直接确认；页面内跳转可能位于未执行的脚本分支中
+func (o *OpenRedirectDetector) confidence(kind string) float64 {
+	switch kind {
+	case "location", "refresh":
+		return 0.95
+	case "meta":
+		return 0.9
+	default:
+		return 0.8
+	}
+}
+
+// isRedirectParameter 判断参数是否可能被用作跳转地址
+func (o *OpenRedirectDetector) isRedirectParameter(point detector.InjectPoint) bool {
+	if point.Position == models.PositionCOOKIE || point.Position == models.PositionHEADER {
+		return false
+	}
+	if point.Type == detector.ParamTypeURL {
+		return true
+	}
+
+	value := strings.TrimSpace(point.Value)
+	if strings.HasPrefix(value, "/") {
+		return true
+	}
+	if parsed, err := url.Parse(value); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		return true
+	}
+
+	name := strings.ToLower(point.Name)
+	// JSON/XML路径取最后一段
+	if idx := strings.LastIndexAny(name, "./"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	for _, hint := range []string{
+		"redirect", "redir", "return", "next", "url", "uri", "goto", "dest", "target",
+		"continue", "forward", "callback", "link", "location", "jump", "to", "back",
+		"success", "fail", "origin", "referer", "ref", "out", "checkout", "service",
+	} {
+		// 过短的提示词只做精确匹配，避免 token、photo 之类的误判
+		if name == hint || (len(hint) > 3 && strings.Contains(name, hint)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getPayloads 生成跳转载荷：绝对地址、协议相对、反斜杠、userinfo及白名单前缀绕过
+func (o *OpenRedirectDetector) getPayloads(target *detector.ScanTarget, point detector.InjectPoint) []RedirectPayload {
+	host := target.URL.Host
+	canary := redirectCanary
+
+	payloads := []RedirectPayload{
+		{Value: "https://" + canary + "/", Technique: "Absolute URL"},
+		{Value: "//" + canary + "/", Technique: "Protocol-relative"},
+		{Value: "///" + canary + "/", Technique: "Protocol-relative"},
+		{Value: `/\` + canary + "/", Technique: "Backslash"},
+		{Value: `\\` + canary + "/", Technique: "Backslash"},
+		{Value: `https:\\` + canary + "/", Technique: "Backslash"},
+		{Value: "https://" + host + "@" + canary + "/", Technique: "Userinfo"},
+		{Value: "//" + host + "@" + canary + "/", Technique: "Userinfo"},
+		// 只校验前缀或包含目标域名的白名单
+		{Value: "https://" + host + "." + canary + "/", Technique: "Whitelisted prefix"},
+		{Value: "https://" + canary + "/" + host, Technique: "Whitelisted substring"},
+		{Value: "https://" + canary + "/?" + host, Technique: "Whitelisted substring"},
+		{Value: "https://" + canary + "#" + host, Technique: "Whitelisted suffix"},
+	}
+
+	// 原值为本站绝对地址时，在其后拼接userinfo分隔符
+	if parsed, err := url.Parse(point.Value); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		prefix := parsed.Scheme + "://" + parsed.Host
+		payloads = append(payloads,
+			RedirectPayload{Value: prefix + "@" + canary + "/", Technique: "Whitelisted prefix"},
+			RedirectPayload{Value: prefix + "." + canary + "/", Technique: "Whitelisted prefix"},
+		)
+	}
+
+	return payloads
+}
+
+// buildVulnerability 构建开放重定向漏洞对象
+func (o *OpenRedirectDetector) buildVulnerability(
+	title, description string,
+	target *detector.ScanTarget,
+	point detector.InjectPoint,
+	payload, evidence string,
+	confidence float64,
+) *models.Vulnerability {
+	return models.NewVulnerabilityBuilder().
+		WithType(models.VulnOpenRedirect).
+		WithCategory(models.CategoryLogic).
+		WithSeverity(models.SeverityMedium).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithParameter(point.Name, point.Position).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(o.Name()).
+		WithCWE("CWE-601").
+		WithCVSS(6.1).
+		WithSolution("跳转目标只接受站内相对路径或白名单中的完整主机名，解析URL后比较主机而不是做字符串前缀匹配，并拒绝 // 和反斜杠开头的地址").
+		WithReferences([]string{
+			"https://cheatsheetseries.owasp.org/cheatsheets/Unvalidated_Redirects_and_Forwards_Cheat_Sheet.html",
+			"https://cwe.mitre.org/data/definitions/601.html",
+		}).
+		Build()
+}
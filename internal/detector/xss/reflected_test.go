@@ -0,0 +1,42 @@
+package xss
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBuildBreakoutPayloadsUnquotedAttribute(t *testing.T) {
+	canary := "drs1a2b3c"
+	page := "<html><body><form><input value=%s></form></body></html>"
+
+	reflections := LocateReflections(fmt.Sprintf(page, canary), canary)
+	if len(reflections) != 1 {
+		t.Fatalf("got %d reflections", len(reflections))
+	}
+	reflection := reflections[0]
+	if reflection.Context != ContextAttribute || reflection.Quote != 0 || reflection.AttrName != "value" {
+		t.Fatalf("got %+v", reflection)
+	}
+
+	payloads := BuildBreakoutPayloads(reflection, canary)
+	if len(payloads) == 0 {
+		t.Fatal("没有生成payload")
+	}
+	for _, payload := range payloads {
+		if strings.ContainsRune(payload.Value, 0) || strings.ContainsRune(payload.Proof, 0) {
+			t.Fatalf("payload包含NUL字节: %q", payload.Value)
+		}
+		// 服务端原样反射时响应中必须出现证明片段
+		body := fmt.Sprintf(page, payload.Value)
+		if !strings.Contains(body, payload.Proof) {
+			t.Fatalf("响应中没有证明片段 %q", payload.Proof)
+		}
+	}
+
+	// 空白逃逸后事件处理器成为input的独立属性
+	body := fmt.Sprintf(page, payloads[0].Value)
+	if !strings.Contains(body, "<input value=x onmouseover=alert(1) ") {
+		t.Fatalf("没有逃逸无引号属性值: %s", body)
+	}
+}
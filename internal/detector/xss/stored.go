@@ -0,0 +1,295 @@
+package xss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/transport"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// StoredXSSDetector 存储型XSS检测器
+//
+// 检测分两个阶段：Execute阶段通过每个注入点提交带唯一标记的payload，
+// PostScan阶段回访本次扫描的全部页面，查找标记出现的位置（sink）并关联回提交点（source）。
+type StoredXSSDetector struct {
+	*detector.BasePlugin
+	requestModifier *detector.RequestModifier
+	paramExtractor  *detector.ParameterExtractor
+	sessionCookies  []*http.Cookie
+	cookieMutex     sync.RWMutex
+
+	markers      map[string]*PlantedMarker
+	markersMutex sync.RWMutex
+}
+
+// PlantedMarker 已提交的标记
+type PlantedMarker struct {
+	Marker    string
+	Payload   string
+	SourceURL string
+	Method    string
+	Point     detector.InjectPoint
+	PlantedAt time.Time
+}
+
+// NewStoredXSSDetector 创建存储型XSS检测器
+func NewStoredXSSDetector(httpClient transport.HTTPClient) *StoredXSSDetector {
+	base := detector.NewBasePlugin(
+		"stored-xss-detector",
+		detector.PluginTypeActive,
+		models.CategoryXSS,
+		models.SeverityHigh,
+	)
+
+	base.SetDescription("检测存储型XSS漏洞，提交唯一标记后回访所有页面定位输出点")
+	base.SetAuthor("DroneRiskScan Team")
+	base.SetVersion("1.0.0")
+	base.SetHTTPClient(httpClient)
+
+	return &StoredXSSDetector{
+		BasePlugin:      base,
+		requestModifier: detector.NewRequestModifier(httpClient),
+		paramExtractor:  detector.NewParameterExtractor(),
+		markers:         make(map[string]*PlantedMarker),
+	}
+}
+
+// SetSessionCookies 设置会话Cookie
+func (s *StoredXSSDetector) SetSessionCookies(cookies []*http.Cookie) {
+	s.cookieMutex.Lock()
+	s.sessionCookies = cookies
+	s.cookieMutex.Unlock()
+	s.requestModifier.SetSessionCookies(cookies)
+}
+
+// Execute 第一阶段：通过每个注入点提交标记
+func (s *StoredXSSDetector) Execute(ctx context.Context, target *detector.ScanTarget) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	injectPoints := s.paramExtractor.ExtractParameters(target)
+	planted := 0
+	for _, point := range injectPoints {
+		// 存储型XSS通常经由表单、查询参数或API请求体写入
+		switch point.Position {
+		case models.PositionGET, models.PositionPOST, models.PositionJSON, models.PositionXML:
+		default:
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		marker := newCanary()
+		payload := storedPayload(marker)
+
+		resp, err := s.requestModifier.ModifyParameter(ctx, target, point, payload)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+
+		s.markersMutex.Lock()
+		s.markers[marker] = &PlantedMarker{
+			Marker:    marker,
+			Payload:   payload,
+			SourceURL: target.URL.String(),
+			Method:    target.Method,
+			Point:     point,
+			PlantedAt: time.Now(),
+		}
+		s.markersMutex.Unlock()
+		planted++
+
+		fmt.Printf("[DEBUG] 存储型XSS标记已提交: %s -> %s (%s)\n", marker, point.Name, target.URL.String())
+	}
+
+	result.Metadata["planted_markers"] = planted
+	return result, nil
+}
+
+// PostScan 第二阶段：回访所有页面，查找已提交标记的输出位置
+func (s *StoredXSSDetector) PostScan(ctx context.Context, pages []string) (*detector.DetectionResult, error) {
+	result := &detector.DetectionResult{
+		IsVulnerable:    false,
+		Vulnerabilities: []*models.Vulnerability{},
+		Evidence:        []detector.Evidence{},
+		Metadata:        make(map[string]interface{}),
+	}
+
+	s.markersMutex.Lock()
+	markers := s.markers
+	s.markers = make(map[string]*PlantedMarker)
+	s.markersMutex.Unlock()
+
+	if len(markers) == 0 {
+		return result, nil
+	}
+
+	// 提交点本身也可能是输出点
+	pageSet := make(map[string]bool)
+	var revisit []string
+	for _, page := range pages {
+		if !pageSet[page] {
+			pageSet[page] = true
+			revisit = append(revisit, page)
+		}
+	}
+	for _, marker := range markers {
+		if !pageSet[marker.SourceURL] {
+			pageSet[marker.SourceURL] = true
+			revisit = append(revisit, marker.SourceURL)
+		}
+	}
+
+	fmt.Printf("[INFO] 存储型XSS回访 %d 个页面，查找 %d 个标记\n", len(revisit), len(markers))
+
+	reported := make(map[string]bool)
+	for _, page := range revisit {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		body, err := s.fetch(ctx, page)
+		if err != nil {
+			continue
+		}
+		lowerBody := strings.ToLower(body)
+
+		for marker, planted := range markers {
+			if !strings.Contains(lowerBody, marker) {
+				continue
+			}
+
+			key := marker + "|" + page
+			if reported[key] {
+				continue
+			}
+			reported[key] = true
+
+			executable := isExecutablePayload(body, marker)
+			fmt.Printf("[DEBUG] 标记 %s 出现在 %s (可执行: %t)\n", marker, page, executable)
+			if !executable {
+				continue
+			}
+
+			vuln := s.buildVulnerability(planted, page)
+			result.IsVulnerable = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+			result.Evidence = append(result.Evidence, detector.Evidence{
+				Type:        detector.EvidenceTypePattern,
+				Description: fmt.Sprintf("在 %s 提交的标记出现在 %s 且未被编码", planted.SourceURL, page),
+				Data:        planted.Payload,
+				Confidence:  vuln.Confidence,
+			})
+			fmt.Printf("[SUCCESS] 发现存储型XSS漏洞: %s -> %s\n", planted.Point.Name, page)
+		}
+	}
+
+	result.Metadata["revisited_pages"] = len(revisit)
+	result.Metadata["planted_markers"] = len(markers)
+	result.Metadata["vulnerabilities_found"] = len(result.Vulnerabilities)
+
+	return result, nil
+}
+
+// fetch 携带会话回访页面
+func (s *StoredXSSDetector) fetch(ctx context.Context, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %w", err)
+	}
+	s.cookieMutex.RLock()
+	cookies := s.sessionCookies
+	s.cookieMutex.RUnlock()
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := s.GetHTTPClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	helper := transport.NewResponseHelper()
+	body, err := helper.ReadBody(resp)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// buildVulnerability 构建存储型XSS漏洞，sink为输出页面，source为提交点
+func (s *StoredXSSDetector) buildVulnerability(planted *PlantedMarker, sinkURL string) *models.Vulnerability {
+	return models.NewVulnerabilityBuilder().
+		WithType(models.VulnXSSStored).
+		WithCategory(models.CategoryXSS).
+		WithSeverity(models.SeverityHigh).
+		WithTitle("Stored Cross-Site Scripting").
+		WithDescription(fmt.Sprintf("通过 %s 的参数 %s 提交的内容被持久化，并在 %s 中未经编码输出", planted.SourceURL, planted.Point.Name, sinkURL)).
+		WithURL(sinkURL).
+		WithMethod(planted.Method).
+		WithParameter(planted.Point.Name, planted.Point.Position).
+		WithPayload(planted.Payload).
+		WithEvidence(fmt.Sprintf("标记 %s 在回访页面中以可执行形式出现", planted.Marker)).
+		WithConfidence(0.95).
+		WithPlugin(s.Name()).
+		WithCWE("CWE-79").
+		WithCVSS(7.2).
+		WithSolution("在输出时根据上下文对存储内容进行编码，入库前进行输入校验，并部署内容安全策略(CSP)").
+		WithReferences([]string{
+			"https://owasp.org/www-community/attacks/xss/#stored-xss-attacks",
+			"https://cheatsheetseries.owasp.org/cheatsheets/Cross_Site_Scripting_Prevention_Cheat_Sheet.html",
+		}).
+		WithMetadata("marker", planted.Marker).
+		WithMetadata("source_url", planted.SourceURL).
+		WithMetadata("source_method", planted.Method).
+		WithMetadata("source_parameter", planted.Point.Name).
+		WithMetadata("source_position", string(planted.Point.Position)).
+		WithMetadata("sink_url", sinkURL).
+		WithMetadata("planted_at", planted.PlantedAt.Format(time.RFC3339)).
+		Build()
+}
+
+// storedPayload 构造带标记的存储型payload，前缀用于逃逸属性上下文
+func storedPayload(marker string) string {
+	return fmt.Sprintf(`"'><svg class=%s onload=alert(1)>`, marker)
+}
+
+// isExecutablePayload 检查标记所在的svg标签是否以可执行形式输出
+func isExecutablePayload(body, marker string) bool {
+	lowerBody := strings.ToLower(body)
+	tag := fmt.Sprintf("<svg class=%s onload=alert(1)>", marker)
+
+	searchFrom := 0
+	for {
+		idx := strings.Index(lowerBody[searchFrom:], tag)
+		if idx < 0 {
+			return false
+		}
+		offset := searchFrom + idx
+
+		// 标签必须位于普通文本节点中，不能在注释、脚本或RCDATA元素内
+		reflection := analyzeContext(body, lowerBody, offset)
+		if reflection.Context == ContextHTMLText && reflection.TagName == "" {
+			return true
+		}
+		searchFrom = offset + len(tag)
+	}
+}
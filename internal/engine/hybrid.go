@@ -11,7 +11,6 @@ import (
 
 	"github.com/dronesec/droneriskscan/internal/browser"
 	"github.com/dronesec/droneriskscan/internal/detector"
-	"github.com/dronesec/droneriskscan/internal/detector/injection"
 	"github.com/dronesec/droneriskscan/pkg/models"
 )
 
@@ -41,6 +40,7 @@ type HybridScannerConfig struct {
 	AutoFallback      bool                       `json:"auto_fallback"`      // 自动降级
 	SmartRouting      bool                       `json:"smart_routing"`      // 智能路由
 	AIAnalysis        bool                       `json:"ai_analysis"`        // AI分析
+	DOMXSS            bool                       `json:"dom_xss"`            // DOM型XSS插桩检测
 	
 	// 性能配置
 	BrowserPoolSize   int                        `json:"browser_pool_size"`
@@ -191,6 +191,7 @@ func DefaultHybridScannerConfig() *HybridScannerConfig {
 		AutoFallback:      true,
 		SmartRouting:      true,
 		AIAnalysis:        true,
+		DOMXSS:            true,
 		BrowserPoolSize:   2,
 		MaxBrowserTime:    5 * time.Minute,
 		ConcurrentBrowser: 1,
@@ -273,6 +274,12 @@ func (hs *HybridScanner) ScanURL(ctx context.Context, targetURL string) (*ScanRe
 		detectionResults = append(detectionResults, traditionalResults...)
 	}
 	
+	// 6. DOM型XSS检测（浏览器sink插桩）
+	if hs.config.DOMXSS && hs.playwrightManager != nil && hs.config.DetectionMode != DetectionPassive {
+		domResults := hs.performDOMXSSDetection(ctx, targetURL, functionPoints, authCookies)
+		detectionResults = append(detectionResults, domResults...)
+	}
+	
 	// 合并检测结果
 	for _, vuln := range detectionResults {
 		result.AddVulnerability(vuln)
@@ -570,8 +577,8 @@ func (hs *HybridScanner) performTraditionalVulnerabilityDetection(ctx context.Co
 	plugins := hs.traditionalScanner.GetPlugins()
 	for _, plugin := range plugins {
 		// Set session cookies for the plugin
-		if sqlPlugin, ok := plugin.(*injection.SQLiDetector); ok {
-			sqlPlugin.SetSessionCookies(cookies)
+		if sessionPlugin, ok := plugin.(detector.SessionAware); ok {
+			sessionPlugin.SetSessionCookies(cookies)
 		}
 		
 		result, err := plugin.Execute(ctx, scanTarget)
@@ -589,6 +596,104 @@ func (hs *HybridScanner) performTraditionalVulnerabilityDetection(ctx context.Co
 	return vulnerabilities
 }
 
+// performDOMXSSDetection 使用Playwright插桩检测DOM型XSS
+func (hs *HybridScanner) performDOMXSSDetection(ctx context.Context, targetURL string, functionPoints []*browser.FunctionPoint, cookies []*http.Cookie) []*models.Vulnerability {
+	var vulnerabilities []*models.Vulnerability
+	
+	base, err := url.Parse(targetURL)
+	if err != nil {
+		return vulnerabilities
+	}
+	
+	// 目标页面加上所有GET功能点
+	pageURLs := []string{targetURL}
+	for _, point := range functionPoints {
+		if point.Method != "GET" || point.URL == "" {
+			continue
+		}
+		if resolved, err := base.Parse(point.URL); err == nil {
+			pageURLs = append(pageURLs, resolved.String())
+		}
+	}
+	pageURLs = removeDuplicateURLs(pageURLs)
+	
+	if len(cookies) > 0 {
+		hs.playwrightManager.SetCookies(cookies)
+	}
+	
+	findings, err := hs.playwrightManager.ScanDOMXSS(ctx, pageURLs)
+	if err != nil {
+		fmt.Printf("[ERROR] DOM XSS detection failed: %v\n", err)
+	}
+	
+	for _, finding := range findings {
+		vulnerabilities = append(vulnerabilities, buildDOMXSSVulnerability(finding))
+	}
+	
+	return vulnerabilities
+}
+
+// buildDOMXSSVulnerability 将DOM XSS发现转换为漏洞对象
+func buildDOMXSSVulnerability(finding *browser.DOMXSSFinding) *models.Vulnerability {
+	confidence := 0.60
+	severity := models.SeverityMedium
+	if finding.Unencoded {
+		confidence = 0.90
+		severity = models.SeverityHigh
+	}
+	// location类sink只有在完全控制URL时才可利用(javascript:伪协议/开放重定向)
+	if strings.HasPrefix(finding.Sink, "location") {
+		severity = models.SeverityMedium
+		if strings.HasPrefix(finding.SinkValue, finding.Canary) {
+			confidence = 0.85
+		} else {
+			confidence = 0.50
+		}
+	}
+	
+	parameter := finding.Parameter
+	if parameter == "" {
+		parameter = finding.Source
+	}
+	
+	return models.NewVulnerabilityBuilder().
+		WithType(models.VulnXSSDom).
+		WithCategory(models.CategoryXSS).
+		WithSeverity(severity).
+		WithTitle("DOM-based Cross-Site Scripting").
+		WithDescription(fmt.Sprintf("来自 %s 的输入被页面脚本传入危险sink %s", finding.Source, finding.Sink)).
+		WithURL(finding.URL).
+		WithMethod("GET").
+		WithParameter(parameter, domSourcePosition(finding.Source)).
+		WithPayload(finding.Payload).
+		WithEvidence(fmt.Sprintf("sink: %s\nvalue: %s\nstack:\n%s", finding.Sink, finding.SinkValue, finding.StackTrace)).
+		WithConfidence(confidence).
+		WithPlugin("dom-xss-detector").
+		WithCWE("CWE-79").
+		WithCVSS(6.1).
+		WithSolution("避免将URL片段、查询参数或postMessage数据传入innerHTML、document.write、eval等sink，使用textContent并校验消息来源").
+		WithReferences([]string{
+			"https://owasp.org/www-community/attacks/DOM_Based_XSS",
+			"https://cheatsheetseries.owasp.org/cheatsheets/DOM_based_XSS_Prevention_Cheat_Sheet.html",
+		}).
+		WithMetadata("source", finding.Source).
+		WithMetadata("sink", finding.Sink).
+		WithMetadata("stack_trace", finding.StackTrace).
+		Build()
+}
+
+// domSourcePosition 将DOM XSS输入源映射为参数位置
+func domSourcePosition(source string) models.Position {
+	switch source {
+	case browser.DOMSourceFragment:
+		return models.PositionFragment
+	case browser.DOMSourcePostMessage:
+		return models.PositionMessage
+	default:
+		return models.PositionGET
+	}
+}
+
 // Close 关闭混合扫描器
 func (hs *HybridScanner) Close() error {
 	if hs.stagehandManager != nil {
@@ -3,7 +3,10 @@ package engine
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -11,7 +14,18 @@ import (
 	"github.com/dronesec/droneriskscan/internal/auth"
 	"github.com/dronesec/droneriskscan/internal/crawler"
 	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/internal/detector/authz"
+	"github.com/dronesec/droneriskscan/internal/detector/csrf"
+	"github.com/dronesec/droneriskscan/internal/detector/disclosure"
+	"github.com/dronesec/droneriskscan/internal/detector/file"
+	"github.com/dronesec/droneriskscan/internal/detector/graphql"
 	"github.com/dronesec/droneriskscan/internal/detector/injection"
+	"github.com/dronesec/droneriskscan/internal/detector/jwt"
+	"github.com/dronesec/droneriskscan/internal/detector/misconfig"
+	"github.com/dronesec/droneriskscan/internal/detector/redirect"
+	"github.com/dronesec/droneriskscan/internal/detector/ssrf"
+	"github.com/dronesec/droneriskscan/internal/detector/xss"
+	"github.com/dronesec/droneriskscan/internal/oob"
 	"github.com/dronesec/droneriskscan/internal/reporter"
 	"github.com/dronesec/droneriskscan/internal/scheduler"
 	"github.com/dronesec/droneriskscan/internal/transport"
@@ -26,7 +40,10 @@ type Scanner struct {
 	plugins        map[string]detector.Plugin
 	config         *ScannerConfig
 	sessionManager *auth.SessionManager
+	identities     []*auth.SessionManager // 越权检测使用的其他身份
 	crawler        *crawler.Crawler
+	graphQL        *crawler.GraphQLDiscoverer
+	oobServer      *oob.Server
 	mutex          sync.RWMutex
 }
 
@@ -40,16 +57,25 @@ type ScannerConfig struct {
 	DisabledPlugins  []string
 	RiskLevels       []models.Severity
 	ReportFormats    []string
+	OutputDir        string // 扫描产物输出目录（如还原的源码仓库），为空时不落盘
+	JWTWordlist      string // JWT弱密钥爆破使用的额外字典文件，为空时只使用内置字典
 	Verbose          bool
 	Debug            bool
 	
 	// 认证配置
 	AuthCredentials  *auth.Credentials
+	Identities       []*auth.Credentials // 越权检测使用的其他身份，主身份为AuthCredentials
 	
 	// 爬虫配置
 	EnableCrawler    bool
 	MaxCrawlDepth    int
 	MaxCrawlPages    int
+	EnableGraphQL    bool // 探测GraphQL端点并将结构中的查询和变更作为扫描目标
+	
+	// 带外交互配置
+	EnableOOB        bool
+	OOBConfig        *oob.Config
+	OOBWaitTime      time.Duration // 扫描结束前等待延迟回连的最长时间
 }
 
 // NewScanner 创建新的扫描器实例
@@ -93,6 +119,9 @@ func NewScanner(config *ScannerConfig) (*Scanner, error) {
 	if config.AuthCredentials != nil {
 		scanner.sessionManager = auth.NewSessionManager(httpClient, config.AuthCredentials)
 	}
+	for _, credentials := range config.Identities {
+		scanner.identities = append(scanner.identities, auth.NewSessionManager(httpClient, credentials))
+	}
 
 	// 初始化爬虫
 	if config.EnableCrawler {
@@ -106,6 +135,20 @@ func NewScanner(config *ScannerConfig) (*Scanner, error) {
 		}
 		scanner.crawler = crawler.NewCrawler(httpClient, scanner.sessionManager, crawlerConfig)
 	}
+	if config.EnableGraphQL {
+		scanner.graphQL = crawler.NewGraphQLDiscoverer(httpClient, scanner.sessionManager)
+	}
+
+	// 启动带外交互服务器
+	if config.EnableOOB {
+		scanner.oobServer = oob.NewServer(config.OOBConfig)
+		if err := scanner.oobServer.Start(); err != nil {
+			return nil, fmt.Errorf("启动带外交互服务器失败: %w", err)
+		}
+		if config.Verbose {
+			fmt.Printf("[INFO] 带外交互服务器监听: %s\n", scanner.oobServer.HTTPAddr())
+		}
+	}
 
 	// 注册默认插件
 	if err := scanner.registerDefaultPlugins(); err != nil {
@@ -134,6 +177,8 @@ func DefaultScannerConfig() *ScannerConfig {
 		EnableCrawler: true,
 		MaxCrawlDepth: 2,
 		MaxCrawlPages: 50,
+		EnableGraphQL: true,
+		OOBWaitTime:   10 * time.Second,
 	}
 }
 
@@ -145,9 +190,130 @@ func (s *Scanner) registerDefaultPlugins() error {
 		return fmt.Errorf("注册SQL注入检测器失败: %w", err)
 	}
 
-	// 这里可以注册更多检测器...
-	// xssDetector := xss.NewXSSDetector(s.httpClient)
-	// s.RegisterPlugin(xssDetector)
+	// 注册反射型XSS检测器
+	xssDetector := xss.NewXSSDetector(s.httpClient)
+	if err := s.RegisterPlugin(xssDetector); err != nil {
+		return fmt.Errorf("注册XSS检测器失败: %w", err)
+	}
+
+	// 注册存储型XSS检测器
+	storedXSSDetector := xss.NewStoredXSSDetector(s.httpClient)
+	if err := s.RegisterPlugin(storedXSSDetector); err != nil {
+		return fmt.Errorf("注册存储型XSS检测器失败: %w", err)
+	}
+
+	// 注册命令注入检测器
+	cmdiDetector := injection.NewCommandInjectionDetector(s.httpClient)
+	if err := s.RegisterPlugin(cmdiDetector); err != nil {
+		return fmt.Errorf("注册命令注入检测器失败: %w", err)
+	}
+
+	// 注册路径遍历检测器
+	traversalDetector := file.NewPathTraversalDetector(s.httpClient)
+	if err := s.RegisterPlugin(traversalDetector); err != nil {
+		return fmt.Errorf("注册路径遍历检测器失败: %w", err)
+	}
+
+	// 注册文件上传检测器
+	uploadDetector := file.NewFileUploadDetector(s.httpClient)
+	if err := s.RegisterPlugin(uploadDetector); err != nil {
+		return fmt.Errorf("注册文件上传检测器失败: %w", err)
+	}
+
+	// 注册LDAP注入检测器
+	ldapiDetector := injection.NewLDAPInjectionDetector(s.httpClient)
+	if err := s.RegisterPlugin(ldapiDetector); err != nil {
+		return fmt.Errorf("注册LDAP注入检测器失败: %w", err)
+	}
+
+	// 注册NoSQL注入检测器
+	nosqliDetector := injection.NewNoSQLInjectionDetector(s.httpClient)
+	if err := s.RegisterPlugin(nosqliDetector); err != nil {
+		return fmt.Errorf("注册NoSQL注入检测器失败: %w", err)
+	}
+
+	// 注册模板注入检测器
+	sstiDetector := injection.NewTemplateInjectionDetector(s.httpClient)
+	if err := s.RegisterPlugin(sstiDetector); err != nil {
+		return fmt.Errorf("注册模板注入检测器失败: %w", err)
+	}
+
+	// 注册XXE检测器
+	xxeDetector := injection.NewXXEDetector(s.httpClient)
+	if err := s.RegisterPlugin(xxeDetector); err != nil {
+		return fmt.Errorf("注册XXE检测器失败: %w", err)
+	}
+
+	// 注册CRLF注入检测器
+	crlfDetector := injection.NewCRLFInjectionDetector(s.httpClient)
+	if err := s.RegisterPlugin(crlfDetector); err != nil {
+		return fmt.Errorf("注册CRLF注入检测器失败: %w", err)
+	}
+
+	// 注册SSRF检测器
+	ssrfDetector := ssrf.NewSSRFDetector(s.httpClient)
+	if err := s.RegisterPlugin(ssrfDetector); err != nil {
+		return fmt.Errorf("注册SSRF检测器失败: %w", err)
+	}
+
+	// 注册开放重定向检测器
+	redirectDetector := redirect.NewOpenRedirectDetector(s.httpClient)
+	if err := s.RegisterPlugin(redirectDetector); err != nil {
+		return fmt.Errorf("注册开放重定向检测器失败: %w", err)
+	}
+
+	// 注册安全响应头被动分析器
+	headersAnalyzer := misconfig.NewSecurityHeadersAnalyzer(s.httpClient)
+	if err := s.RegisterPlugin(headersAnalyzer); err != nil {
+		return fmt.Errorf("注册安全响应头分析器失败: %w", err)
+	}
+
+	// 注册CORS检测器
+	corsDetector := misconfig.NewCORSDetector(s.httpClient)
+	if err := s.RegisterPlugin(corsDetector); err != nil {
+		return fmt.Errorf("注册CORS检测器失败: %w", err)
+	}
+
+	// 注册备份文件发现插件
+	backupDetector := disclosure.NewBackupFileDetector(s.httpClient)
+	if err := s.RegisterPlugin(backupDetector); err != nil {
+		return fmt.Errorf("注册备份文件发现插件失败: %w", err)
+	}
+
+	// 注册越权访问检测器
+	idorDetector := authz.NewIDORDetector(s.httpClient)
+	if err := s.RegisterPlugin(idorDetector); err != nil {
+		return fmt.Errorf("注册越权访问检测器失败: %w", err)
+	}
+
+	// 注册认证绕过检测器
+	authBypassDetector := authz.NewAuthBypassDetector(s.httpClient)
+	if err := s.RegisterPlugin(authBypassDetector); err != nil {
+		return fmt.Errorf("注册认证绕过检测器失败: %w", err)
+	}
+
+	// 注册CSRF检测器
+	csrfDetector := csrf.NewCSRFDetector(s.httpClient)
+	if err := s.RegisterPlugin(csrfDetector); err != nil {
+		return fmt.Errorf("注册CSRF检测器失败: %w", err)
+	}
+
+	// 注册JWT安全检测器
+	jwtDetector := jwt.NewJWTDetector(s.httpClient)
+	if s.config.JWTWordlist != "" {
+		if err := jwtDetector.LoadWordlist(s.config.JWTWordlist); err != nil {
+			return fmt.Errorf("加载JWT密钥字典失败: %w", err)
+		}
+	}
+	if err := s.RegisterPlugin(jwtDetector); err != nil {
+		return fmt.Errorf("注册JWT检测器失败: %w", err)
+	}
+
+	// 注册GraphQL安全检测器
+	graphQLDetector := graphql.NewGraphQLDetector(s.httpClient)
+	if err := s.RegisterPlugin(graphQLDetector); err != nil {
+		return fmt.Errorf("注册GraphQL检测器失败: %w", err)
+	}
 
 	return nil
 }
@@ -209,6 +375,27 @@ func (s *Scanner) RegisterPlugin(plugin detector.Plugin) error {
 		return nil
 	}
 
+	// 注入带外交互服务器
+	if oobAware, ok := plugin.(detector.OOBAware); ok && s.oobServer != nil {
+		oobAware.SetOOBProvider(s.oobServer)
+	}
+
+	// 注入扫描产物输出目录
+	if outputAware, ok := plugin.(detector.OutputAware); ok && s.config.OutputDir != "" {
+		outputAware.SetOutputDir(s.config.OutputDir)
+	}
+
+	// 被动插件订阅客户端收到的全部响应
+	if observer, ok := plugin.(detector.ResponseObserver); ok {
+		if observable, ok := s.httpClient.(transport.ResponseObservable); ok {
+			observable.AddResponseObserver(func(resp *http.Response) {
+				if plugin.IsEnabled() {
+					observer.ObserveResponse(resp)
+				}
+			})
+		}
+	}
+
 	s.plugins[name] = plugin
 
 	if s.config.Verbose {
@@ -230,6 +417,9 @@ func (s *Scanner) ScanURLs(ctx context.Context, targetURLs []string) (*models.Sc
 		return nil, fmt.Errorf("目标URL列表不能为空")
 	}
 
+	// 告知插件扫描范围，爬取阶段的响应也会被观察
+	s.setPluginScope(targetURLs)
+
 	// 创建扫描结果
 	scanID := generateScanID()
 	result := models.NewScanResult(scanID)
@@ -239,8 +429,24 @@ func (s *Scanner) ScanURLs(ctx context.Context, targetURLs []string) (*models.Sc
 		fmt.Printf("[INFO] 开始扫描 %d 个目标\n", len(targetURLs))
 	}
 	
-	// 直接使用原始目标
-	allTargets := targetURLs
+	// 启用爬虫时先爬取，发现的页面和表单请求一并扫描
+	var allTargets []*TargetSpec
+	if s.crawler != nil {
+		allTargets = s.crawlAndDiscoverTargets(ctx, targetURLs)
+	} else {
+		for _, targetURL := range removeDuplicateURLs(targetURLs) {
+			allTargets = append(allTargets, &TargetSpec{URL: targetURL, Method: "GET"})
+		}
+	}
+
+	// 发现GraphQL端点，结构中的每个查询和变更作为带变量的请求扫描
+	if s.graphQL != nil {
+		allTargets = s.discoverGraphQLTargets(ctx, targetURLs, allTargets)
+	}
+
+	if s.config.Verbose && len(allTargets) != len(targetURLs) {
+		fmt.Printf("[INFO] 爬取后共 %d 个扫描目标\n", len(allTargets))
+	}
 
 	// 启动任务调度器
 	if err := s.scheduler.Start(ctx); err != nil {
@@ -256,34 +462,56 @@ func (s *Scanner) ScanURLs(ctx context.Context, targetURLs []string) (*models.Sc
 	// 启动结果收集器
 	go s.collectResults(result, resultChan, errorChan)
 
-	for _, targetURL := range allTargets {
+	// 订阅带外交互，插件登记的延迟回连在扫描结束前到达时上报
+	stopOOB := s.subscribeOOBFindings(resultChan)
+
+	for _, target := range allTargets {
 		wg.Add(1)
 		
 		// 创建扫描任务
 		task := &scheduler.Task{
-			ID:       fmt.Sprintf("scan_%s_%d", extractHostFromURL(targetURL), time.Now().UnixNano()),
+			ID:       fmt.Sprintf("scan_%s_%d", extractHostFromURL(target.URL), time.Now().UnixNano()),
 			Type:     scheduler.TaskTypeScan,
 			Priority: scheduler.PriorityNormal,
 			Payload: map[string]interface{}{
-				"url":    targetURL,
+				"url":    target.URL,
+				"target": target,
 				"result": result,
 			},
 			CreatedAt: time.Now(),
 		}
 
-		// 提交任务
-		s.scheduler.Submit(task, func(ctx context.Context, task *scheduler.Task) error {
+		// 提交任务。扫描错误已经通过errorChan上报，不返回给调度器，
+		// 否则调度器重试会重复扫描目标并多次调用wg.Done
+		err := s.scheduler.Submit(task, func(ctx context.Context, task *scheduler.Task) error {
 			defer wg.Done()
 			
-			targetURL := task.Payload["url"].(string)
+			target := task.Payload["target"].(*TargetSpec)
 			scanResult := task.Payload["result"].(*models.ScanResult)
 			
-			return s.scanSingleTarget(ctx, targetURL, scanResult, resultChan, errorChan)
+			s.scanSingleTarget(ctx, target, scanResult, resultChan, errorChan)
+			return nil
 		})
+		if err != nil {
+			wg.Done()
+			errorChan <- fmt.Errorf("提交扫描任务失败 %s: %w", target.URL, err)
+		}
 	}
 
 	// 等待所有任务完成
 	wg.Wait()
+
+	// 执行收尾检测（存储型漏洞回访等）
+	scannedURLs := make([]string, 0, len(allTargets))
+	for _, target := range allTargets {
+		scannedURLs = append(scannedURLs, target.URL)
+	}
+	s.runPostScanPlugins(ctx, removeDuplicateURLs(scannedURLs), resultChan)
+
+	// 等待尚未到达的带外回连
+	s.waitForOOBInteractions(ctx)
+	stopOOB()
+
 	close(resultChan)
 	close(errorChan)
 
@@ -299,8 +527,28 @@ func (s *Scanner) ScanURLs(ctx context.Context, targetURLs []string) (*models.Sc
 	return result, nil
 }
 
+// setPluginScope 将用户指定目标的主机名告知需要区分扫描范围的插件
+func (s *Scanner) setPluginScope(targetURLs []string) {
+	var hosts []string
+	for _, targetURL := range targetURLs {
+		if u, err := url.Parse(targetURL); err == nil && u.Hostname() != "" {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, plugin := range s.plugins {
+		if scopePlugin, ok := plugin.(detector.ScopeAware); ok {
+			scopePlugin.SetScopeHosts(hosts)
+		}
+	}
+}
+
 // scanSingleTarget 扫描单个目标
-func (s *Scanner) scanSingleTarget(ctx context.Context, targetURL string, result *models.ScanResult, resultChan chan<- *models.Vulnerability, errorChan chan<- error) error {
+func (s *Scanner) scanSingleTarget(ctx context.Context, target *TargetSpec, result *models.ScanResult, resultChan chan<- *models.Vulnerability, errorChan chan<- error) error {
+	targetURL := target.URL
+
 	// 解析URL
 	parsedURL, err := url.Parse(targetURL)
 	if err != nil {
@@ -317,7 +565,7 @@ func (s *Scanner) scanSingleTarget(ctx context.Context, targetURL string, result
 
 	// 发送初始请求获取基准响应
 	startTime := time.Now()
-	resp, err := s.httpClient.Get(targetURL)
+	resp, err := s.sendBaselineRequest(ctx, target)
 	responseTime := time.Since(startTime)
 
 	targetResult.ResponseTime = responseTime
@@ -349,8 +597,9 @@ func (s *Scanner) scanSingleTarget(ctx context.Context, targetURL string, result
 	// 创建扫描目标
 	scanTarget := &detector.ScanTarget{
 		URL:        parsedURL,
-		Method:     "GET",
+		Method:     target.Method,
 		Headers:    make(map[string]string),
+		Body:       target.Body,
 		Parameters: make(map[string][]string),
 		Cookies:    make(map[string]string),
 		Metadata:   make(map[string]interface{}),
@@ -358,6 +607,22 @@ func (s *Scanner) scanSingleTarget(ctx context.Context, targetURL string, result
 		BaselineResponse: resp,
 		BaselineBody:     body,
 	}
+	if target.ContentType != "" {
+		scanTarget.Headers["Content-Type"] = target.ContentType
+	}
+	if target.Form != nil {
+		scanTarget.Metadata["form"] = target.Form
+		scanTarget.Metadata["page_url"] = target.PageURL
+	}
+	if target.GraphQL != nil {
+		scanTarget.Metadata["graphql_endpoint"] = target.GraphQL
+	}
+	if target.Operation != nil {
+		scanTarget.Metadata["graphql"] = target.Operation
+	}
+	if len(target.Plugins) > 0 {
+		scanTarget.Metadata["test_plugins"] = target.Plugins
+	}
 
 	// 提取参数
 	if parsedURL.RawQuery != "" {
@@ -382,11 +647,12 @@ func (s *Scanner) scanSingleTarget(ctx context.Context, targetURL string, result
 	s.mutex.RLock()
 	plugins := make([]detector.Plugin, 0, len(s.plugins))
 	for _, plugin := range s.plugins {
-		if plugin.IsEnabled() {
+		if plugin.IsEnabled() && shouldRunPlugin(plugin, target.Plugins) {
 			plugins = append(plugins, plugin)
 		}
 	}
 	s.mutex.RUnlock()
+	orderPlugins(plugins, target.Plugins)
 
 	for _, plugin := range plugins {
 		select {
@@ -401,11 +667,16 @@ func (s *Scanner) scanSingleTarget(ctx context.Context, targetURL string, result
 
 		// 如果存在会话管理器，为插件设置会话Cookie
 		if s.sessionManager != nil && s.sessionManager.IsLoggedIn() {
-			if sqliPlugin, ok := plugin.(*injection.SQLiDetector); ok {
-				sqliPlugin.SetSessionCookies(s.sessionManager.GetCookies())
+			if sessionPlugin, ok := plugin.(detector.SessionAware); ok {
+				sessionPlugin.SetSessionCookies(s.sessionManager.GetCookies())
 			}
 		}
 
+		// 为越权检测插件设置全部登录身份
+		if identityPlugin, ok := plugin.(detector.IdentityAware); ok {
+			identityPlugin.SetIdentities(s.loggedInIdentities())
+		}
+
 		// 执行检测
 		detectionResult, err := plugin.Execute(ctx, scanTarget)
 		if err != nil {
@@ -437,6 +708,62 @@ func (s *Scanner) scanSingleTarget(ctx context.Context, targetURL string, result
 	return nil
 }
 
+// runPostScanPlugins 在所有目标扫描完成后执行收尾检测插件
+func (s *Scanner) runPostScanPlugins(ctx context.Context, scannedURLs []string, resultChan chan<- *models.Vulnerability) {
+	s.mutex.RLock()
+	var postScanPlugins []detector.PostScanPlugin
+	for _, plugin := range s.plugins {
+		if postScanPlugin, ok := plugin.(detector.PostScanPlugin); ok && plugin.IsEnabled() {
+			postScanPlugins = append(postScanPlugins, postScanPlugin)
+		}
+	}
+	s.mutex.RUnlock()
+
+	if len(postScanPlugins) == 0 {
+		return
+	}
+
+	// 回访页面：已扫描的目标加上爬虫发现的所有页面
+	pages := append([]string{}, scannedURLs...)
+	if s.crawler != nil {
+		for _, crawlResult := range s.crawler.GetResults() {
+			pages = append(pages, crawlResult.URL)
+		}
+	}
+	pages = removeDuplicateURLs(pages)
+
+	for _, plugin := range postScanPlugins {
+		if s.sessionManager != nil && s.sessionManager.IsLoggedIn() {
+			if sessionPlugin, ok := plugin.(detector.SessionAware); ok {
+				sessionPlugin.SetSessionCookies(s.sessionManager.GetCookies())
+			}
+		}
+
+		if s.config.Debug {
+			fmt.Printf("[DEBUG] 执行收尾检测 %s，回访 %d 个页面\n", plugin.Name(), len(pages))
+		}
+
+		detectionResult, err := plugin.PostScan(ctx, pages)
+		if err != nil {
+			if s.config.Debug {
+				fmt.Printf("[DEBUG] 收尾检测 %s 执行失败: %v\n", plugin.Name(), err)
+			}
+			continue
+		}
+
+		if detectionResult != nil && detectionResult.IsVulnerable {
+			for _, vuln := range detectionResult.Vulnerabilities {
+				vuln.Timestamp = time.Now()
+				select {
+				case resultChan <- vuln:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
 // collectResults 收集扫描结果
 func (s *Scanner) collectResults(result *models.ScanResult, resultChan <-chan *models.Vulnerability, errorChan <-chan error) {
 	for {
@@ -499,9 +826,41 @@ func (s *Scanner) Login(ctx context.Context) error {
 		fmt.Printf("[INFO] 登录成功，会话ID: %s\n", s.sessionManager.GetSessionID())
 	}
 
+	// 其他身份登录失败不影响扫描，只是不参与越权检测
+	for _, identity := range s.identities {
+		if err := identity.Login(ctx); err != nil {
+			fmt.Printf("[WARN] 身份 %s 登录失败，不参与越权检测: %v\n", identity.GetIdentityName(), err)
+			continue
+		}
+		if s.config.Verbose {
+			fmt.Printf("[INFO] 身份 %s 登录成功\n", identity.GetIdentityName())
+		}
+	}
+
 	return nil
 }
 
+// loggedInIdentities 已登录的全部身份，主身份在前；主身份未登录时返回nil
+func (s *Scanner) loggedInIdentities() []detector.Identity {
+	if s.sessionManager == nil || !s.sessionManager.IsLoggedIn() {
+		return nil
+	}
+
+	var identities []detector.Identity
+	for _, sm := range append([]*auth.SessionManager{s.sessionManager}, s.identities...) {
+		if !sm.IsLoggedIn() {
+			continue
+		}
+		identities = append(identities, detector.Identity{
+			Name:    sm.GetIdentityName(),
+			Marker:  sm.GetUsername(),
+			Cookies: sm.GetCookies(),
+			Headers: sm.GetAuthHeaders(),
+		})
+	}
+	return identities
+}
+
 // IsAuthenticated 检查是否已认证
 func (s *Scanner) IsAuthenticated() bool {
 	if s.sessionManager == nil {
@@ -530,7 +889,15 @@ func (s *Scanner) Close() error {
 		ctx := context.Background()
 		s.sessionManager.Logout(ctx)
 	}
+	for _, identity := range s.identities {
+		if identity.IsLoggedIn() {
+			identity.Logout(context.Background())
+		}
+	}
 
+	if s.oobServer != nil {
+		s.oobServer.Stop()
+	}
 	if s.httpClient != nil {
 		s.httpClient.Close()
 	}
@@ -556,8 +923,21 @@ func extractHostFromURL(rawURL string) string {
 }
 
 // crawlAndDiscoverTargets 爬取并发现目标
-func (s *Scanner) crawlAndDiscoverTargets(ctx context.Context, initialURLs []string) []string {
-	var allTargets []string
+func (s *Scanner) crawlAndDiscoverTargets(ctx context.Context, initialURLs []string) []*TargetSpec {
+	var allTargets []*TargetSpec
+	seen := make(map[string]bool)
+	addTarget := func(target *TargetSpec) {
+		if target == nil || seen[target.key()] {
+			return
+		}
+		seen[target.key()] = true
+		allTargets = append(allTargets, target)
+	}
+	
+	// 用户指定的目标没有插件推荐，总是运行全部插件
+	for _, initialURL := range initialURLs {
+		addTarget(&TargetSpec{URL: initialURL, Method: "GET"})
+	}
 	
 	for _, initialURL := range initialURLs {
 		if s.config.Verbose {
@@ -570,8 +950,6 @@ func (s *Scanner) crawlAndDiscoverTargets(ctx context.Context, initialURLs []str
 			if s.config.Debug {
 				fmt.Printf("[ERROR] 爬取失败: %v\n", err)
 			}
-			// 如果爬取失败，至少添加原始 URL
-			allTargets = append(allTargets, initialURL)
 			continue
 		}
 		
@@ -579,30 +957,104 @@ func (s *Scanner) crawlAndDiscoverTargets(ctx context.Context, initialURLs []str
 			fmt.Printf("[INFO] 爬取完成，发现 %d 个页面\n", len(crawlResults))
 		}
 		
-		// 分析爬取结果并生成目标 URL
+		// 分析爬取结果并生成扫描目标
 		for _, crawlResult := range crawlResults {
-			// 基本 URL
-			allTargets = append(allTargets, crawlResult.URL)
+			// 页面本身
+			addTarget(&TargetSpec{
+				URL:     crawlResult.URL,
+				Method:  "GET",
+				Plugins: crawlResult.TestPlugins,
+			})
 			
-			// 为每个表单生成测试 URL
+			// 为每个表单生成测试请求
 			for _, form := range crawlResult.Forms {
-				if form.Method == "GET" && form.Action != "" {
+				if form.Method == "GET" {
 					// 为 GET 表单生成测试 URL
 					formURL := s.generateFormTestURL(crawlResult.URL, form)
 					if formURL != "" {
-						allTargets = append(allTargets, formURL)
+						addTarget(&TargetSpec{
+							URL:     formURL,
+							Method:  "GET",
+							Plugins: crawlResult.TestPlugins,
+							Form:    form,
+							PageURL: crawlResult.URL,
+						})
 					}
+				} else {
+					// 其他表单生成带请求体的完整请求
+					addTarget(s.generateFormTestRequest(crawlResult, form))
 				}
 			}
 			
 			// 打印功能分析结果
 			if s.config.Debug {
-				fmt.Printf("[DEBUG] 发现页面: %s\n", crawlResult.URL)
+				fmt.Printf("[DEBUG] 发现页面: %s (功能: %s, 推荐插件: %v)\n",
+					crawlResult.URL, crawlResult.FunctionType, crawlResult.TestPlugins)
 			}
 		}
 	}
 	
-	return removeDuplicateURLs(allTargets)
+	return allTargets
+}
+
+// discoverGraphQLTargets 在每个目标站点上发现GraphQL端点，为端点本身和结构中的每个操作生成扫描目标
+func (s *Scanner) discoverGraphQLTargets(ctx context.Context, targetURLs []string, allTargets []*TargetSpec) []*TargetSpec {
+	seen := make(map[string]bool)
+	var candidates []string
+	for _, target := range allTargets {
+		seen[target.key()] = true
+		candidates = append(candidates, target.URL)
+	}
+
+	origins := make(map[string]bool)
+	for _, targetURL := range targetURLs {
+		host := extractHostFromURL(targetURL)
+		if origins[host] {
+			continue
+		}
+		origins[host] = true
+
+		endpoint, err := s.graphQL.Discover(ctx, targetURL, candidates)
+		if err != nil {
+			if s.config.Debug {
+				fmt.Printf("[DEBUG] %s: %v\n", targetURL, err)
+			}
+			continue
+		}
+
+		// 端点本身总是作为目标，供GraphQL检测器检查内省、批量查询和深度限制
+		targets := []*TargetSpec{{
+			URL:         endpoint.URL,
+			Method:      "POST",
+			Body:        `{"query":"query{__typename}","variables":{}}`,
+			ContentType: "application/json",
+			GraphQL:     endpoint,
+		}}
+		for _, operation := range endpoint.Operations() {
+			targets = append(targets, &TargetSpec{
+				URL:         endpoint.URL,
+				Method:      "POST",
+				Body:        operation.RequestBody(),
+				ContentType: "application/json",
+				GraphQL:     endpoint,
+				Operation:   operation,
+			})
+		}
+
+		for _, target := range targets {
+			if !seen[target.key()] {
+				seen[target.key()] = true
+				allTargets = append(allTargets, target)
+			}
+		}
+
+		if s.config.Verbose {
+			fmt.Printf("[INFO] GraphQL端点 %s 结构来源: %s，生成 %d 个操作\n",
+				endpoint.URL, endpoint.SchemaSource, len(targets)-1)
+		}
+	}
+
+	return allTargets
 }
 
 // generateFormTestURL 为表单生成测试URL
@@ -638,6 +1090,86 @@ func (s *Scanner) generateFormTestURL(baseURL string, form *crawler.FormInfo) st
 	return actionURL.String()
 }
 
+// generateFormTestRequest 为POST等表单生成带请求体的测试目标，
+// 隐藏字段和提交按钮保留原值（如CSRF令牌），上传表单使用multipart编码
+func (s *Scanner) generateFormTestRequest(page *crawler.CrawlResult, form *crawler.FormInfo) *TargetSpec {
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return nil
+	}
+	
+	// 未设置action的表单提交到当前页面
+	actionURL := base
+	if form.Action != "" {
+		if actionURL, err = base.Parse(form.Action); err != nil {
+			return nil
+		}
+	}
+	actionURL.Fragment = ""
+	
+	method := form.Method
+	if method == "" {
+		method = "POST"
+	}
+	
+	target := &TargetSpec{
+		URL:     actionURL.String(),
+		Method:  method,
+		Plugins: page.TestPlugins,
+		Form:    form,
+		PageURL: page.URL,
+	}
+	
+	multipartForm := form.HasUpload || strings.Contains(strings.ToLower(form.EncType), "multipart/form-data")
+	
+	values := url.Values{}
+	var parts []*detector.MultipartPart
+	for _, input := range form.Inputs {
+		if input.Name == "" {
+			continue
+		}
+		
+		var value string
+		switch input.Type {
+		case "hidden", "submit":
+			value = input.Value
+		case "checkbox", "radio":
+			value = input.Value
+			if value == "" {
+				value = "on"
+			}
+		case "file":
+			parts = append(parts, &detector.MultipartPart{
+				Name:        input.Name,
+				FileName:    "test.txt",
+				ContentType: "text/plain",
+				Data:        []byte("test"),
+				IsFile:      true,
+			})
+			continue
+		default:
+			value = s.getTestValueForInput(input)
+		}
+		
+		values.Add(input.Name, value)
+		parts = append(parts, &detector.MultipartPart{Name: input.Name, Data: []byte(value)})
+	}
+	
+	if multipartForm {
+		body, contentType, err := detector.BuildMultipartBody(parts)
+		if err != nil {
+			return nil
+		}
+		target.Body = body
+		target.ContentType = contentType
+	} else {
+		target.Body = values.Encode()
+		target.ContentType = "application/x-www-form-urlencoded"
+	}
+	
+	return target
+}
+
 // getTestValueForInput 为输入字段获取测试值
 func (s *Scanner) getTestValueForInput(input *crawler.InputInfo) string {
 	lowerName := strings.ToLower(input.Name)
@@ -671,6 +1203,186 @@ func (s *Scanner) getTestValueForInput(input *crawler.InputInfo) string {
 	}
 }
 
+// subscribeOOBFindings 订阅带外交互，认领插件登记的预期漏洞并写入结果通道，
+// 返回的函数取消订阅并丢弃未到达的预期，调用后不再写入通道
+func (s *Scanner) subscribeOOBFindings(resultChan chan<- *models.Vulnerability) func() {
+	if s.oobServer == nil {
+		return func() {}
+	}
+
+	var mutex sync.Mutex
+	stopped := false
+
+	unsubscribe := s.oobServer.Subscribe(func(interaction *oob.Interaction) {
+		vuln, ok := s.oobServer.Claim(interaction.ID)
+		if !ok {
+			return
+		}
+
+		vuln.Evidence = strings.TrimSpace(fmt.Sprintf("%s\n收到来自 %s 的%s带外回连（关联ID %s）",
+			vuln.Evidence, interaction.RemoteAddr, strings.ToUpper(string(interaction.Protocol)), interaction.ID))
+		vuln.Metadata["oob_id"] = interaction.ID
+		vuln.Metadata["oob_protocol"] = string(interaction.Protocol)
+		vuln.Metadata["oob_remote_addr"] = interaction.RemoteAddr
+		vuln.Metadata["oob_received_at"] = interaction.Timestamp.Format(time.RFC3339)
+
+		if s.config.Verbose {
+			fmt.Printf("[FOUND] 收到延迟带外回连: %s (%s)\n", vuln.Title, interaction.ID)
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !stopped {
+			resultChan <- vuln
+		}
+	})
+
+	return func() {
+		unsubscribe()
+		mutex.Lock()
+		stopped = true
+		mutex.Unlock()
+		s.oobServer.DropExpectations()
+	}
+}
+
+// waitForOOBInteractions 仍有未到达的预期回连时，在配置的时间内等待
+func (s *Scanner) waitForOOBInteractions(ctx context.Context) {
+	if s.oobServer == nil || s.oobServer.PendingExpectations() == 0 || s.config.OOBWaitTime <= 0 {
+		return
+	}
+
+	if s.config.Verbose {
+		fmt.Printf("[INFO] 等待 %d 个带外回连（最长 %s）\n", s.oobServer.PendingExpectations(), s.config.OOBWaitTime)
+	}
+
+	deadline := time.NewTimer(s.config.OOBWaitTime)
+	defer deadline.Stop()
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for s.oobServer.PendingExpectations() > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// TargetSpec 扫描目标请求，爬虫发现的表单会生成带请求体的目标
+type TargetSpec struct {
+	URL         string
+	Method      string
+	Body        string
+	ContentType string
+	Plugins     []string                  // 爬虫推荐的测试插件，为空时运行全部插件
+	Form        *crawler.FormInfo         // 目标来源表单
+	PageURL     string                    // 表单所在页面
+	GraphQL     *crawler.GraphQLEndpoint  // 目标所属的GraphQL端点
+	Operation   *crawler.GraphQLOperation // 目标对应的GraphQL操作
+}
+
+// key 目标去重键
+func (t *TargetSpec) key() string {
+	return t.Method + " " + t.URL + " " + t.Body
+}
+
+// sendBaselineRequest 发送目标的原始请求获取基准响应
+func (s *Scanner) sendBaselineRequest(ctx context.Context, target *TargetSpec) (*http.Response, error) {
+	if target.Method == "GET" && target.Body == "" && (s.sessionManager == nil || !s.sessionManager.IsLoggedIn()) {
+		return s.httpClient.Get(target.URL)
+	}
+	
+	var body io.Reader
+	if target.Body != "" {
+		body = strings.NewReader(target.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, target.Method, target.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	if target.ContentType != "" {
+		req.Header.Set("Content-Type", target.ContentType)
+	}
+	if s.sessionManager != nil && s.sessionManager.IsLoggedIn() {
+		for _, cookie := range s.sessionManager.GetCookies() {
+			req.AddCookie(cookie)
+		}
+	}
+	return s.httpClient.Do(req)
+}
+
+// pluginRecommendationAliases 已注册插件名与爬虫推荐插件名的对应关系
+var pluginRecommendationAliases = map[string]string{
+	"enhanced-sqli-detector": "sqli-detector",
+	"stored-xss-detector":    "xss-detector",
+}
+
+// crawlerRecommendedPlugins 爬虫可能推荐的插件名，不在其中的插件不受推荐结果限制
+var crawlerRecommendedPlugins = map[string]bool{
+	"sqli-detector":        true,
+	"xss-detector":         true,
+	"nosql-injection":      true,
+	"brute-force":          true,
+	"file-upload":          true,
+	"path-traversal":       true,
+	"malware-upload":       true,
+	"privilege-escalation": true,
+	"csrf-detector":        true,
+	"ssrf-detector":        true,
+	"open-redirect":        true,
+	"command-injection":    true,
+}
+
+// shouldRunPlugin 根据爬虫推荐判断插件是否对目标执行：
+// 无推荐时全部执行；被动插件及爬虫不会推荐的插件总是执行
+func shouldRunPlugin(plugin detector.Plugin, recommended []string) bool {
+	if len(recommended) == 0 || plugin.Type() == detector.PluginTypePassive {
+		return true
+	}
+
+	name := plugin.Name()
+	if alias, ok := pluginRecommendationAliases[name]; ok {
+		name = alias
+	}
+	if !crawlerRecommendedPlugins[name] {
+		return true
+	}
+
+	for _, rec := range recommended {
+		if rec == name {
+			return true
+		}
+	}
+	return false
+}
+
+// orderPlugins 按爬虫推荐排序插件：推荐的插件先执行，其余按名称顺序执行
+func orderPlugins(plugins []detector.Plugin, recommended []string) {
+	isRecommended := make(map[string]bool, len(recommended))
+	for _, rec := range recommended {
+		isRecommended[rec] = true
+	}
+	rank := func(plugin detector.Plugin) bool {
+		name := plugin.Name()
+		if alias, ok := pluginRecommendationAliases[name]; ok {
+			name = alias
+		}
+		return isRecommended[name]
+	}
+
+	sort.SliceStable(plugins, func(i, j int) bool {
+		ri, rj := rank(plugins[i]), rank(plugins[j])
+		if ri != rj {
+			return ri
+		}
+		return plugins[i].Name() < plugins[j].Name()
+	})
+}
+
 // shouldCopyHeader 判断是否应该复制响应头到请求头
 func shouldCopyHeader(headerName string) bool {
 	// 只复制某些特定的头部
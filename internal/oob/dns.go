@@ -0,0 +1,111 @@
+package oob
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"time"
+)
+
+// DNS监听只实现带外确认所需的最小子集：解析查询名，按关联ID记录，A记录返回配置的地址
+
+const (
+	dnsHeaderLen = 12
+	dnsTypeA     = 1
+	dnsClassIN   = 1
+)
+
+// serveDNS 处理DNS查询直到连接关闭
+func (s *Server) serveDNS(conn net.PacketConn) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		query := make([]byte, n)
+		copy(query, buf[:n])
+
+		name, qtype, questionEnd, ok := parseDNSQuestion(query)
+		if !ok {
+			continue
+		}
+
+		if id := s.Match(strings.Split(name, ".")); id != "" {
+			s.Record(&Interaction{
+				ID:         id,
+				Protocol:   ProtocolDNS,
+				RemoteAddr: addr.String(),
+				Request:    name,
+				Timestamp:  time.Now(),
+			})
+		}
+
+		conn.WriteTo(s.buildDNSResponse(query[:questionEnd], qtype), addr)
+	}
+}
+
+// parseDNSQuestion 解析第一个查询问题，返回查询名、类型及问题段结束位置
+func parseDNSQuestion(msg []byte) (string, uint16, int, bool) {
+	if len(msg) < dnsHeaderLen || binary.BigEndian.Uint16(msg[4:6]) == 0 {
+		return "", 0, 0, false
+	}
+
+	var labels []string
+	pos := dnsHeaderLen
+	for {
+		if pos >= len(msg) {
+			return "", 0, 0, false
+		}
+		length := int(msg[pos])
+		pos++
+		if length == 0 {
+			break
+		}
+		// 查询中不应出现压缩指针
+		if length&0xC0 != 0 || pos+length > len(msg) {
+			return "", 0, 0, false
+		}
+		labels = append(labels, string(msg[pos:pos+length]))
+		pos += length
+	}
+
+	if pos+4 > len(msg) {
+		return "", 0, 0, false
+	}
+	qtype := binary.BigEndian.Uint16(msg[pos : pos+2])
+	pos += 4
+
+	return strings.ToLower(strings.Join(labels, ".")), qtype, pos, true
+}
+
+// buildDNSResponse 构造应答：回显问题段，A查询附带一条指向配置地址的记录
+func (s *Server) buildDNSResponse(question []byte, qtype uint16) []byte {
+	resp := make([]byte, len(question))
+	copy(resp, question)
+
+	// QR=1, AA=1, 保留RD
+	resp[2] = 0x84 | (question[2] & 0x01)
+	resp[3] = 0x00
+	binary.BigEndian.PutUint16(resp[4:6], 1)
+	binary.BigEndian.PutUint16(resp[6:8], 0)
+	binary.BigEndian.PutUint16(resp[8:10], 0)
+	binary.BigEndian.PutUint16(resp[10:12], 0)
+
+	ip := net.ParseIP(s.config.DNSResponseIP).To4()
+	if qtype != dnsTypeA || ip == nil {
+		return resp
+	}
+
+	binary.BigEndian.PutUint16(resp[6:8], 1)
+	answer := make([]byte, 16)
+	binary.BigEndian.PutUint16(answer[0:2], 0xC000|dnsHeaderLen) // 指向问题段中的查询名
+	binary.BigEndian.PutUint16(answer[2:4], dnsTypeA)
+	binary.BigEndian.PutUint16(answer[4:6], dnsClassIN)
+	binary.BigEndian.PutUint32(answer[6:10], 0)
+	binary.BigEndian.PutUint16(answer[10:12], 4)
+	copy(answer[12:16], ip)
+
+	return append(resp, answer...)
+}
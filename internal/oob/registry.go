@@ -0,0 +1,245 @@
+package oob
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// Registry 关联ID登记表：记录每个ID收到的交互、插件登记的预期漏洞，并向订阅者分发交互事件
+type Registry struct {
+	mutex       sync.RWMutex
+	entries     map[string]*entry
+	subscribers map[int]func(*Interaction)
+	nextSubID   int
+}
+
+// entry 单个关联ID的状态
+type entry struct {
+	interactions []*Interaction
+	notify       chan struct{} // 首次收到交互时关闭
+
+	expectation *models.Vulnerability // 回连到达时上报的漏洞
+	claimed     bool
+}
+
+// NewRegistry 创建关联ID登记表
+func NewRegistry() *Registry {
+	return &Registry{
+		entries:     make(map[string]*entry),
+		subscribers: make(map[int]func(*Interaction)),
+	}
+}
+
+// Register 生成并登记新的关联ID（小写十六进制，可作为DNS标签）
+func (r *Registry) Register() string {
+	id := newCorrelationID()
+
+	r.mutex.Lock()
+	r.entries[id] = &entry{notify: make(chan struct{})}
+	r.mutex.Unlock()
+
+	return id
+}
+
+// Expect 为关联ID登记预期漏洞，回连到达时由订阅者认领上报；
+// 登记前已经收到的交互会立即重新分发
+func (r *Registry) Expect(id string, vuln *models.Vulnerability) {
+	r.mutex.Lock()
+	e, ok := r.entries[id]
+	if !ok {
+		r.mutex.Unlock()
+		return
+	}
+	e.expectation = vuln
+	// 同一漏洞已被先登记的关联ID认领
+	for _, other := range r.entries {
+		if vuln != nil && other != e && other.expectation == vuln && other.claimed {
+			e.claimed = true
+			break
+		}
+	}
+	var first *Interaction
+	if len(e.interactions) > 0 {
+		first = e.interactions[0]
+	}
+	r.mutex.Unlock()
+
+	if first != nil {
+		r.dispatch(first)
+	}
+}
+
+// Claim 认领关联ID的预期漏洞，每个ID只能认领一次；
+// 同一漏洞登记在多个关联ID上时，第一个到达的回连认领后其余ID不再上报
+func (r *Registry) Claim(id string) (*models.Vulnerability, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	e, ok := r.entries[id]
+	if !ok || e.expectation == nil || e.claimed || len(e.interactions) == 0 {
+		return nil, false
+	}
+	for _, other := range r.entries {
+		if other.expectation == e.expectation {
+			other.claimed = true
+		}
+	}
+	return e.expectation, true
+}
+
+// PendingExpectations 已登记预期但尚未收到回连的数量
+func (r *Registry) PendingExpectations() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	count := 0
+	for _, e := range r.entries {
+		if e.expectation != nil && !e.claimed && len(e.interactions) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// DropExpectations 丢弃全部未认领的预期漏洞（扫描结束时调用，避免串到下一次扫描）
+func (r *Registry) DropExpectations() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, e := range r.entries {
+		if !e.claimed {
+			e.expectation = nil
+		}
+	}
+}
+
+// Subscribe 订阅全部已登记ID的交互事件，返回取消订阅函数
+func (r *Registry) Subscribe(handler func(*Interaction)) func() {
+	r.mutex.Lock()
+	id := r.nextSubID
+	r.nextSubID++
+	r.subscribers[id] = handler
+	r.mutex.Unlock()
+
+	return func() {
+		r.mutex.Lock()
+		delete(r.subscribers, id)
+		r.mutex.Unlock()
+	}
+}
+
+// Record 记录交互并通知等待者和订阅者，未登记的关联ID忽略
+func (r *Registry) Record(interaction *Interaction) bool {
+	r.mutex.Lock()
+	e, ok := r.entries[interaction.ID]
+	if !ok {
+		r.mutex.Unlock()
+		return false
+	}
+	if len(e.interactions) == 0 {
+		close(e.notify)
+	}
+	e.interactions = append(e.interactions, interaction)
+	r.mutex.Unlock()
+
+	r.dispatch(interaction)
+	return true
+}
+
+// dispatch 向订阅者分发交互事件
+func (r *Registry) dispatch(interaction *Interaction) {
+	r.mutex.RLock()
+	handlers := make([]func(*Interaction), 0, len(r.subscribers))
+	for _, handler := range r.subscribers {
+		handlers = append(handlers, handler)
+	}
+	r.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		handler(interaction)
+	}
+}
+
+// Wait 等待关联ID的回连，timeout不大于0时只检查当前是否已收到
+func (r *Registry) Wait(ctx context.Context, id string, timeout time.Duration) bool {
+	r.mutex.RLock()
+	e, ok := r.entries[id]
+	r.mutex.RUnlock()
+	if !ok {
+		return false
+	}
+
+	if timeout <= 0 {
+		select {
+		case <-e.notify:
+			return true
+		default:
+			return false
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-e.notify:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Interactions 获取关联ID已收到的全部交互
+func (r *Registry) Interactions(id string) []*Interaction {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	e, ok := r.entries[id]
+	if !ok {
+		return nil
+	}
+	interactions := make([]*Interaction, len(e.interactions))
+	copy(interactions, e.interactions)
+	return interactions
+}
+
+// Match 在候选片段中查找已登记的关联ID
+func (r *Registry) Match(candidates []string) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(candidate)
+		if _, ok := r.entries[candidate]; ok {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// correlationIDPattern 关联ID格式
+var correlationIDPattern = regexp.MustCompile(`(?i)drs[0-9a-f]{16}`)
+
+// FindIn 在任意文本（邮件内容、请求体等）中查找已登记的关联ID
+func (r *Registry) FindIn(text string) string {
+	return r.Match(correlationIDPattern.FindAllString(text, -1))
+}
+
+// newCorrelationID 生成关联ID
+func newCorrelationID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("drs%016x", time.Now().UnixNano())
+	}
+	return "drs" + hex.EncodeToString(buf)
+}
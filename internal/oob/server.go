@@ -0,0 +1,257 @@
+package oob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dronesec/droneriskscan/internal/detector"
+	"github.com/dronesec/droneriskscan/pkg/models"
+)
+
+// 带外交互服务器：在扫描进程内监听HTTP（及可选的DNS、SMTP）回连，
+// 每个载荷使用独立的关联ID，目标服务器访问 http://<公开地址>/<ID>、解析 <ID>.<域名>
+// 或向 <ID>@<域名> 发信即记录一次交互
+
+// Protocol 交互协议
+type Protocol string
+
+const (
+	ProtocolHTTP Protocol = "http"
+	ProtocolDNS  Protocol = "dns"
+	ProtocolSMTP Protocol = "smtp"
+)
+
+// Config 带外交互服务器配置
+type Config struct {
+	HTTPAddr      string // HTTP监听地址，如 127.0.0.1:0
+	PublicHost    string // 目标回连使用的主机（host:port），为空时使用HTTP监听地址
+	DNSAddr       string // DNS监听地址（UDP），为空时不启用DNS
+	SMTPAddr      string // SMTP监听地址，为空时不启用SMTP
+	Domain        string // DNS/SMTP回连根域名，需将其NS/MX记录指向本机
+	DNSResponseIP string // DNS A记录应答地址
+}
+
+// DefaultConfig 默认配置，仅监听本机
+func DefaultConfig() *Config {
+	return &Config{
+		HTTPAddr:      "127.0.0.1:0",
+		DNSResponseIP: "127.0.0.1",
+	}
+}
+
+// Interaction 收到的一次带外交互
+type Interaction struct {
+	ID         string
+	Protocol   Protocol
+	RemoteAddr string
+	Request    string // HTTP请求原文、DNS查询名或SMTP会话
+	Timestamp  time.Time
+}
+
+// Server 带外交互服务器
+type Server struct {
+	*Registry
+	config *Config
+
+	httpListener net.Listener
+	httpServer   *http.Server
+	dnsConn      net.PacketConn
+	smtpListener net.Listener
+
+	contentMutex sync.RWMutex
+	contents     map[string]hostedContent // 关联ID -> 回连时返回的内容
+}
+
+// hostedContent 为关联ID托管的应答内容
+type hostedContent struct {
+	contentType string
+	body        []byte
+}
+
+// NewServer 创建带外交互服务器
+func NewServer(config *Config) *Server {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.DNSResponseIP == "" {
+		config.DNSResponseIP = "127.0.0.1"
+	}
+
+	return &Server{
+		Registry: NewRegistry(),
+		config:   config,
+		contents: make(map[string]hostedContent),
+	}
+}
+
+// Start 启动监听
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.config.HTTPAddr)
+	if err != nil {
+		return fmt.Errorf("启动带外HTTP监听失败: %w", err)
+	}
+	s.httpListener = listener
+	s.httpServer = &http.Server{
+		Handler:      http.HandlerFunc(s.handleHTTP),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go s.httpServer.Serve(listener)
+
+	if s.config.DNSAddr != "" {
+		conn, err := net.ListenPacket("udp", s.config.DNSAddr)
+		if err != nil {
+			s.Stop()
+			return fmt.Errorf("启动带外DNS监听失败: %w", err)
+		}
+		s.dnsConn = conn
+		go s.serveDNS(conn)
+	}
+
+	if s.config.SMTPAddr != "" {
+		listener, err := net.Listen("tcp", s.config.SMTPAddr)
+		if err != nil {
+			s.Stop()
+			return fmt.Errorf("启动带外SMTP监听失败: %w", err)
+		}
+		s.smtpListener = listener
+		go s.serveSMTP(listener)
+	}
+
+	return nil
+}
+
+// Stop 停止监听
+func (s *Server) Stop() error {
+	if s.smtpListener != nil {
+		s.smtpListener.Close()
+	}
+	if s.dnsConn != nil {
+		s.dnsConn.Close()
+	}
+	if s.httpServer != nil {
+		return s.httpServer.Close()
+	}
+	return nil
+}
+
+// HTTPAddr 实际的HTTP监听地址
+func (s *Server) HTTPAddr() string {
+	if s.httpListener == nil {
+		return s.config.HTTPAddr
+	}
+	return s.httpListener.Addr().String()
+}
+
+// DNSAddr 实际的DNS监听地址，未启用时为空
+func (s *Server) DNSAddr() string {
+	if s.dnsConn == nil {
+		return ""
+	}
+	return s.dnsConn.LocalAddr().String()
+}
+
+// SMTPAddr 实际的SMTP监听地址，未启用时为空
+func (s *Server) SMTPAddr() string {
+	if s.smtpListener == nil {
+		return ""
+	}
+	return s.smtpListener.Addr().String()
+}
+
+// NewInteraction 生成新的关联ID及各协议的回连地址
+func (s *Server) NewInteraction() *detector.OOBInteraction {
+	id := s.Register()
+
+	host := s.config.PublicHost
+	if host == "" {
+		host = s.HTTPAddr()
+	}
+
+	interaction := &detector.OOBInteraction{
+		ID:  id,
+		URL: fmt.Sprintf("http://%s/%s", host, id),
+	}
+
+	domain := strings.Trim(s.config.Domain, ".")
+	if s.dnsConn != nil && domain != "" {
+		interaction.Domain = id + "." + domain
+	}
+	if s.smtpListener != nil {
+		if domain != "" {
+			interaction.Email = id + "@" + domain
+		} else {
+			// 无域名时使用地址字面量，仅适用于目标直连本机25端口的场景
+			smtpHost, _, _ := net.SplitHostPort(s.SMTPAddr())
+			interaction.Email = fmt.Sprintf("%s@[%s]", id, smtpHost)
+		}
+	}
+
+	return interaction
+}
+
+// WaitForInteraction 等待关联ID的回连，timeout不大于0时只检查当前是否已收到
+func (s *Server) WaitForInteraction(ctx context.Context, id string, timeout time.Duration) bool {
+	return s.Wait(ctx, id, timeout)
+}
+
+// ExpectInteraction 登记关联ID回连到达时上报的漏洞
+func (s *Server) ExpectInteraction(id string, vuln *models.Vulnerability) {
+	s.Expect(id, vuln)
+}
+
+// ServeContent 目标回连该关联ID时返回指定内容
+func (s *Server) ServeContent(id, contentType string, body []byte) {
+	s.contentMutex.Lock()
+	defer s.contentMutex.Unlock()
+	s.contents[id] = hostedContent{contentType: contentType, body: body}
+}
+
+// handleHTTP 处理HTTP回连，关联ID可以出现在路径、查询参数、Host子域名或请求体中
+func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	var candidates []string
+	candidates = append(candidates, strings.FieldsFunc(r.URL.Path, func(c rune) bool { return c == '/' })...)
+	for _, values := range r.URL.Query() {
+		candidates = append(candidates, values...)
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	candidates = append(candidates, strings.Split(host, ".")...)
+
+	raw, _ := httputil.DumpRequest(r, true)
+	id := s.Match(candidates)
+	if id == "" {
+		id = s.FindIn(string(raw))
+	}
+
+	if id != "" {
+		s.Record(&Interaction{
+			ID:         id,
+			Protocol:   ProtocolHTTP,
+			RemoteAddr: r.RemoteAddr,
+			Request:    string(raw),
+			Timestamp:  time.Now(),
+		})
+	}
+
+	s.contentMutex.RLock()
+	content, hosted := s.contents[id]
+	s.contentMutex.RUnlock()
+	if id != "" && hosted {
+		w.Header().Set("Content-Type", content.contentType)
+		w.Write(content.body)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, "ok")
+}
@@ -0,0 +1,121 @@
+package oob
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// SMTP监听只实现收信所需的最小命令集，用于确认目标是否向注入的邮箱地址发信
+// （如邮件头注入、找回密码等功能中的盲注）
+
+// smtpMaxMessage 单封邮件最多记录的字节数
+const smtpMaxMessage = 64 * 1024
+
+// serveSMTP 接受SMTP连接直到监听关闭
+func (s *Server) serveSMTP(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleSMTP(conn)
+	}
+}
+
+// handleSMTP 处理单个SMTP会话，在信封地址和邮件内容中查找关联ID
+func (s *Server) handleSMTP(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	var transcript strings.Builder
+	var envelope []string
+	reset := func() {
+		transcript.Reset()
+		envelope = nil
+	}
+
+	reply("220 %s ESMTP ready", s.smtpHostname())
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		transcript.WriteString(line)
+
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "HELO"), strings.HasPrefix(command, "EHLO"):
+			reply("250 %s", s.smtpHostname())
+		case strings.HasPrefix(command, "MAIL FROM:"), strings.HasPrefix(command, "RCPT TO:"):
+			envelope = append(envelope, strings.TrimSpace(line[strings.Index(line, ":")+1:]))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if strings.TrimRight(dataLine, "\r\n") == "." {
+					break
+				}
+				if transcript.Len() < smtpMaxMessage {
+					transcript.WriteString(dataLine)
+				}
+			}
+			s.recordSMTP(conn.RemoteAddr().String(), envelope, transcript.String())
+			reset()
+			reply("250 OK: queued")
+		case command == "RSET":
+			reset()
+			reply("250 OK")
+		case command == "NOOP":
+			reply("250 OK")
+		case command == "QUIT":
+			// 只有信封没有DATA的会话（如地址校验）也算一次交互
+			if len(envelope) > 0 {
+				s.recordSMTP(conn.RemoteAddr().String(), envelope, transcript.String())
+			}
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// recordSMTP 按信封地址或邮件内容中的关联ID记录交互
+func (s *Server) recordSMTP(remoteAddr string, envelope []string, transcript string) {
+	id := s.FindIn(strings.Join(envelope, " "))
+	if id == "" {
+		id = s.FindIn(transcript)
+	}
+	if id == "" {
+		return
+	}
+
+	s.Record(&Interaction{
+		ID:         id,
+		Protocol:   ProtocolSMTP,
+		RemoteAddr: remoteAddr,
+		Request:    transcript,
+		Timestamp:  time.Now(),
+	})
+}
+
+// smtpHostname SMTP问候使用的主机名
+func (s *Server) smtpHostname() string {
+	if s.config.Domain != "" {
+		return strings.Trim(s.config.Domain, ".")
+	}
+	return "localhost"
+}
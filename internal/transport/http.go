@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dronesec/droneriskscan/pkg/models"
@@ -32,6 +33,18 @@ type Client struct {
 	userAgent string
 	headers   map[string]string
 	options   *ClientOptions
+
+	observerMutex sync.RWMutex
+	observers     []ResponseObserver
+}
+
+// ResponseObserver 响应观察者，客户端收到的每个响应（含跟随重定向前的中间响应）都会通知，
+// 调用时响应体可能尚未读取，观察者只应使用状态行和头部
+type ResponseObserver func(resp *http.Response)
+
+// ResponseObservable 支持注册响应观察者的客户端
+type ResponseObservable interface {
+	AddResponseObserver(observer ResponseObserver)
 }
 
 // ClientOptions 客户端选项
@@ -95,11 +108,17 @@ func NewHTTPClient(options *ClientOptions) *Client {
 		}
 	}
 
+	var client *Client
+
 	// 创建HTTP客户端
 	httpClient := &http.Client{
 		Transport: transport,
 		Timeout:   options.Timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if RedirectsDisabled(req.Context()) {
+				return http.ErrUseLastResponse
+			}
+			client.notifyObservers(req.Response)
 			if len(via) >= options.MaxRedirects {
 				return fmt.Errorf("stopped after %d redirects", options.MaxRedirects)
 			}
@@ -107,7 +126,7 @@ func NewHTTPClient(options *ClientOptions) *Client {
 		},
 	}
 
-	client := &Client{
+	client = &Client{
 		client:    httpClient,
 		userAgent: options.UserAgent,
 		headers:   make(map[string]string),
@@ -124,10 +143,24 @@ func NewHTTPClient(options *ClientOptions) *Client {
 	return client
 }
 
+// redirectsDisabledKey 禁止跟随重定向的上下文键
+type redirectsDisabledKey struct{}
+
+// WithoutRedirects 返回禁止自动跟随重定向的上下文，使用该上下文的请求直接返回3xx响应本身
+func WithoutRedirects(ctx context.Context) context.Context {
+	return context.WithValue(ctx, redirectsDisabledKey{}, true)
+}
+
+// RedirectsDisabled 判断上下文是否禁止跟随重定向
+func RedirectsDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(redirectsDisabledKey{}).(bool)
+	return disabled
+}
+
 // Do 执行HTTP请求
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	// 添加默认头部
-	if c.userAgent != "" {
+	// 添加默认头部，保留调用方显式设置的User-Agent
+	if c.userAgent != "" && req.Header.Get("User-Agent") == "" {
 		req.Header.Set("User-Agent", c.userAgent)
 	}
 	
@@ -151,7 +184,40 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 		req.Header.Set("Connection", "keep-alive")
 	}
 
-	return c.client.Do(req)
+	var resp *http.Response
+	var err error
+	if capture := rawCaptureFrom(req.Context()); capture != nil {
+		resp, err = c.doRaw(req, capture)
+	} else {
+		resp, err = c.client.Do(req)
+	}
+	if err == nil {
+		c.notifyObservers(resp)
+	}
+
+	return resp, err
+}
+
+// AddResponseObserver 注册响应观察者
+func (c *Client) AddResponseObserver(observer ResponseObserver) {
+	c.observerMutex.Lock()
+	defer c.observerMutex.Unlock()
+	c.observers = append(c.observers, observer)
+}
+
+// notifyObservers 通知所有响应观察者
+func (c *Client) notifyObservers(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	c.observerMutex.RLock()
+	observers := c.observers
+	c.observerMutex.RUnlock()
+
+	for _, observer := range observers {
+		observer(resp)
+	}
 }
 
 // Get 发送GET请求
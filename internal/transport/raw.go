@@ -0,0 +1,137 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// net/http 解析响应时会规范化头部（合并、改写大小写、容忍裸LF），看不到服务端实际写出的字节。
+// 带有RawCapture的请求改走独立的短连接，在连接层记录响应原文，用于CRLF注入等需要原始头部的检测
+
+// rawCaptureLimit 单个响应最多记录的字节数
+const rawCaptureLimit = 64 * 1024
+
+// RawCapture 记录一次请求的原始响应字节
+type RawCapture struct {
+	mutex sync.Mutex
+	data  []byte
+}
+
+// rawCaptureKey 原始响应记录的上下文键
+type rawCaptureKey struct{}
+
+// WithRawCapture 返回记录原始响应的上下文，使用该上下文的请求不复用连接、不跟随重定向、只使用HTTP/1.1
+func WithRawCapture(ctx context.Context) (context.Context, *RawCapture) {
+	capture := &RawCapture{}
+	return context.WithValue(ctx, rawCaptureKey{}, capture), capture
+}
+
+// rawCaptureFrom 取出上下文中的原始响应记录
+func rawCaptureFrom(ctx context.Context) *RawCapture {
+	capture, _ := ctx.Value(rawCaptureKey{}).(*RawCapture)
+	return capture
+}
+
+// Bytes 已记录的响应原文
+func (rc *RawCapture) Bytes() []byte {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return append([]byte(nil), rc.data...)
+}
+
+// Header 响应原文中状态行和头部部分（不含空行），未收到完整头部时返回已收到的部分
+func (rc *RawCapture) Header() []byte {
+	data := rc.Bytes()
+	if idx := bytes.Index(data, []byte("\r\n\r\n")); idx >= 0 {
+		return data[:idx]
+	}
+	if idx := bytes.Index(data, []byte("\n\n")); idx >= 0 {
+		return data[:idx]
+	}
+	return data
+}
+
+// write 追加读取到的字节
+func (rc *RawCapture) write(p []byte) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if remaining := rawCaptureLimit - len(rc.data); remaining > 0 {
+		if len(p) > remaining {
+			p = p[:remaining]
+		}
+		rc.data = append(rc.data, p...)
+	}
+}
+
+// captureConn 将读取到的字节同步写入RawCapture的连接
+type captureConn struct {
+	net.Conn
+	capture *RawCapture
+}
+
+// Read 读取并记录
+func (cc *captureConn) Read(p []byte) (int, error) {
+	n, err := cc.Conn.Read(p)
+	if n > 0 {
+		cc.capture.write(p[:n])
+	}
+	return n, err
+}
+
+// doRaw 使用记录原始响应的一次性连接发送请求；HTTPS经HTTP代理时记录到的是隧道内的密文，Header不可用
+func (c *Client) doRaw(req *http.Request, capture *RawCapture) (*http.Response, error) {
+	base, ok := c.client.Transport.(*http.Transport)
+	if !ok {
+		return c.client.Do(req)
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	rawTransport := base.Clone()
+	rawTransport.DisableKeepAlives = true
+	rawTransport.ForceAttemptHTTP2 = false
+	rawTransport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	rawTransport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &captureConn{Conn: conn, capture: capture}, nil
+	}
+	rawTransport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		config := &tls.Config{}
+		if base.TLSClientConfig != nil {
+			config = base.TLSClientConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		config.NextProtos = []string{"http/1.1"}
+
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return &captureConn{Conn: tlsConn, capture: capture}, nil
+	}
+
+	rawClient := &http.Client{
+		Transport: rawTransport,
+		Timeout:   c.client.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return rawClient.Do(req)
+}
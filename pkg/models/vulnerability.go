@@ -67,21 +67,35 @@ const (
 	VulnIDOR             VulnType = "idor"
 	VulnFileUpload       VulnType = "file_upload"
 	VulnPathTraversal    VulnType = "path_traversal"
+	VulnSSRF             VulnType = "ssrf"
+	VulnSSTI             VulnType = "ssti"
+	VulnXXE              VulnType = "xxe"
+	VulnOpenRedirect     VulnType = "open_redirect"
+	VulnCRLFInjection    VulnType = "crlf_injection"
+	VulnSecurityHeaders  VulnType = "security_headers"
+	VulnInsecureCookie   VulnType = "insecure_cookie"
 	VulnInfoDisclosure   VulnType = "info_disclosure"
 	VulnCORS             VulnType = "cors"
 	VulnBackupFiles      VulnType = "backup_files"
+	VulnCSRF             VulnType = "csrf"
+	VulnJWT              VulnType = "jwt"
+	VulnGraphQL          VulnType = "graphql"
 )
 
 // Position 参数位置
 type Position string
 
 const (
-	PositionGET    Position = "GET"
-	PositionPOST   Position = "POST"
-	PositionHEADER Position = "HEADER"
-	PositionCOOKIE Position = "COOKIE"
-	PositionJSON   Position = "JSON"
-	PositionXML    Position = "XML"
+	PositionGET       Position = "GET"
+	PositionPOST      Position = "POST"
+	PositionHEADER    Position = "HEADER"
+	PositionCOOKIE    Position = "COOKIE"
+	PositionJSON      Position = "JSON"
+	PositionXML       Position = "XML"
+	PositionMultipart Position = "MULTIPART"
+	PositionPATH      Position = "PATH"
+	PositionFragment  Position = "FRAGMENT"
+	PositionMessage   Position = "POSTMESSAGE"
 )
 
 // Vulnerability 漏洞信息
@@ -270,16 +284,18 @@ func (b *VulnerabilityBuilder) Build() *Vulnerability {
 // deriveCategory 根据漏洞类型推导类别
 func (b *VulnerabilityBuilder) deriveCategory(vulnType VulnType) Category {
 	switch vulnType {
-	case VulnSQLi, VulnNoSQLi, VulnCommandInjection, VulnLDAPInjection:
+	case VulnSQLi, VulnNoSQLi, VulnCommandInjection, VulnLDAPInjection, VulnPathTraversal, VulnFileUpload, VulnSSRF, VulnSSTI, VulnXXE, VulnCRLFInjection:
 		return CategoryInjection
 	case VulnXSSReflected, VulnXSSStored, VulnXSSDom:
 		return CategoryXSS
-	case VulnAuthBypass, VulnIDOR:
+	case VulnAuthBypass, VulnIDOR, VulnJWT:
 		return CategoryAuth
 	case VulnInfoDisclosure, VulnBackupFiles:
 		return CategoryDisclosure
-	case VulnCORS:
+	case VulnCORS, VulnSecurityHeaders, VulnInsecureCookie, VulnGraphQL:
 		return CategoryConfig
+	case VulnCSRF:
+		return CategoryCSRF
 	default:
 		return CategoryLogic
 	}